@@ -0,0 +1,190 @@
+/*
+Copyright © 2024 ks6088ts
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ks6088ts-labs/misctl/internal"
+
+	"github.com/playwright-community/playwright-go"
+	"github.com/spf13/cobra"
+)
+
+// benchResult holds the timings of a single URL captured with a given engine
+type benchResult struct {
+	Engine       string  `json:"engine"`
+	URL          string  `json:"url"`
+	LoadMs       float64 `json:"load_ms"`
+	ScreenshotMs float64 `json:"screenshot_ms"`
+	Error        string  `json:"error,omitempty"`
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// benchCaptureOptions are the capture options of bench, which writes flat artifacts with the defaults of scrape
+func benchCaptureOptions(outputDir string) captureOptions {
+	return captureOptions{OutputDir: outputDir, Layout: layoutFlat}
+}
+
+// benchEngine captures every URL with the given engine and returns one result per URL
+func benchEngine(out io.Writer, pw *playwright.Playwright, engine string, urls []string, outputDir string, headless bool) []benchResult {
+	results := make([]benchResult, 0, len(urls))
+	fail := func(err error) []benchResult {
+		for _, url := range urls {
+			results = append(results, benchResult{Engine: engine, URL: url, Error: err.Error()})
+		}
+		return results
+	}
+
+	bt, err := browserType(pw, engine)
+	if err != nil {
+		return fail(err)
+	}
+	browser, err := bt.Launch(playwright.BrowserTypeLaunchOptions{
+		Headless: playwright.Bool(headless),
+	})
+	if err != nil {
+		return fail(withInstallHint(fmt.Errorf("could not launch %s: %w", engine, err)))
+	}
+	defer browser.Close()
+	page, err := browser.NewPage()
+	if err != nil {
+		return fail(fmt.Errorf("could not create page: %w", err))
+	}
+
+	for _, url := range urls {
+		fmt.Fprintf(out, "Scraping %s with %s\n", url, engine)
+		result, err := capture(out, page, scrapeJob{URL: url}, benchCaptureOptions(outputDir))
+		br := benchResult{
+			Engine:       engine,
+			URL:          url,
+			LoadMs:       durationMs(result.LoadTime),
+			ScreenshotMs: durationMs(result.ScreenshotTime),
+		}
+		if err != nil {
+			br.Error = err.Error()
+		}
+		results = append(results, br)
+	}
+	return results
+}
+
+// printBenchTable prints the per-URL timings followed by the per-engine averages
+func printBenchTable(out io.Writer, engines []string, results []benchResult) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ENGINE\tURL\tLOAD (ms)\tSCREENSHOT (ms)\tERROR")
+	for _, r := range results {
+		fmt.Fprintf(w, "%s\t%s\t%.1f\t%.1f\t%s\n", r.Engine, r.URL, r.LoadMs, r.ScreenshotMs, r.Error)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "ENGINE\tOK\tAVG LOAD (ms)\tAVG SCREENSHOT (ms)")
+	for _, engine := range engines {
+		ok, load, screenshot := 0, 0.0, 0.0
+		for _, r := range results {
+			if r.Engine != engine || r.Error != "" {
+				continue
+			}
+			ok++
+			load += r.LoadMs
+			screenshot += r.ScreenshotMs
+		}
+		if ok > 0 {
+			load /= float64(ok)
+			screenshot /= float64(ok)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%.1f\t%.1f\n", engine, ok, load, screenshot)
+	}
+	w.Flush()
+}
+
+// benchCmd represents the bench command
+var benchCmd = &cobra.Command{
+	Use:   "bench",
+	Short: "Benchmark scrape performance per browser engine",
+	Long: `Load the given urls with each browser engine (chromium, firefox and webkit)
+and report the page load and screenshot timings per engine.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		out := cmd.OutOrStdout()
+		// Parse flags
+		urls, err := cmd.Flags().GetStringArray("url")
+		assertErrorToNilf("failed to parse `url`: %w", err)
+		engines, err := cmd.Flags().GetStringArray("engine")
+		assertErrorToNilf("failed to parse `engine`: %w", err)
+		dir, err := cmd.Flags().GetString("dir")
+		assertErrorToNilf("failed to parse `dir`: %w", err)
+		headless, err := cmd.Flags().GetBool("headless")
+		assertErrorToNilf("failed to parse `headless`: %w", err)
+		format, err := cmd.Flags().GetString("format")
+		assertErrorToNilf("failed to parse `format`: %w", err)
+		pretty, err := cmd.Flags().GetBool("pretty")
+		assertErrorToNilf("failed to parse `pretty`: %w", err)
+		if internal.JSONOutput() {
+			format = "json"
+		}
+		if format != "table" && format != "json" {
+			internal.Fatalf(internal.CodeUsage, "invalid `format` %q, must be table or json", format)
+		}
+
+		pw, err := playwright.Run()
+		assertErrorToNilf("could not launch playwright: %w", withInstallHint(err))
+
+		results := []benchResult{}
+		for _, engine := range engines {
+			// Each engine writes its screenshots into its own directory
+			outputDir := filepath.Join(dir, engine)
+			err = os.MkdirAll(outputDir, os.ModePerm)
+			assertErrorToNilf("could not create output directory: %w", err)
+			results = append(results, benchEngine(internal.Decorative(out), pw, engine, urls, outputDir, headless)...)
+		}
+
+		err = pw.Stop()
+		assertErrorToNilf("could not stop playwright: %w", err)
+
+		if format == "json" {
+			data, err := internal.MarshalJSON(results, pretty)
+			assertErrorToNilf("could not marshal results: %w", err)
+			fmt.Fprintln(out, string(data))
+			return
+		}
+		printBenchTable(out, engines, results)
+	},
+}
+
+func init() {
+	scrapeCmd.AddCommand(benchCmd)
+
+	benchCmd.Flags().StringArrayP("url", "u", []string{}, "URL to scrape")
+	benchCmd.Flags().StringArrayP("engine", "e", []string{"chromium", "firefox", "webkit"}, "Browser engine to benchmark")
+	benchCmd.Flags().StringP("dir", "d", "artifacts/bench", "Output directory")
+	benchCmd.Flags().BoolP("headless", "m", true, "Headless mode")
+	benchCmd.Flags().StringP("format", "f", "table", "Output format: table or json")
+
+	assertErrorToNilf("could not mark `url` as required: %w", benchCmd.MarkFlagRequired("url"))
+}
@@ -0,0 +1,18 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestBenchCaptureOptions(t *testing.T) {
+	dir := t.TempDir()
+	opts := benchCaptureOptions(dir)
+	got, err := artifactPath(opts.OutputDir, opts.Layout, "https://example.com")
+	if err != nil {
+		t.Fatalf("artifactPath() with the bench options returned error: %v", err)
+	}
+	if filepath.Dir(got) != dir {
+		t.Errorf("artifactPath() = %q; want a file in %q", got, dir)
+	}
+}
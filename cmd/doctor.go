@@ -0,0 +1,177 @@
+/*
+Copyright © 2024 ks6088ts
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/ks6088ts-labs/misctl/cmd/iot"
+	"github.com/ks6088ts-labs/misctl/internal"
+	"github.com/playwright-community/playwright-go"
+
+	"github.com/spf13/cobra"
+)
+
+// Statuses of a doctor check
+const (
+	doctorPass = "pass"
+	doctorFail = "fail"
+	// doctorWarn is a failed check that is not critical
+	doctorWarn = "warn"
+	doctorSkip = "skip"
+)
+
+// doctorCheck is the result of one check of the doctor command, and its JSON output
+type doctorCheck struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Detail   string `json:"detail"`
+	Critical bool   `json:"critical"`
+}
+
+// newDoctorCheck builds the result of a check from its error, a failed check is only a warning if it is not critical
+func newDoctorCheck(name string, critical bool, detail string, err error) doctorCheck {
+	check := doctorCheck{Name: name, Status: doctorPass, Detail: detail, Critical: critical}
+	if err != nil {
+		check.Status = doctorWarn
+		if critical {
+			check.Status = doctorFail
+		}
+		check.Detail = err.Error()
+	}
+	return check
+}
+
+// doctorFailed reports whether any critical check failed
+func doctorFailed(checks []doctorCheck) bool {
+	for _, check := range checks {
+		if check.Status == doctorFail {
+			return true
+		}
+	}
+	return false
+}
+
+// writeDoctorChecklist prints one line per check, the details of multi-line errors are indented under it
+func writeDoctorChecklist(out io.Writer, checks []doctorCheck) {
+	for _, check := range checks {
+		detail := strings.ReplaceAll(check.Detail, "\n", "\n       ")
+		fmt.Fprintf(out, "[%s] %s: %s\n", strings.ToUpper(check.Status), check.Name, detail)
+	}
+}
+
+// checkPlaywright launches Chromium the way scrape does, which fails if the driver or the browser is not installed
+func checkPlaywright() (version string, err error) {
+	pw, err := playwright.Run()
+	if err != nil {
+		return "", withInstallHint(fmt.Errorf("could not launch playwright: %w", err))
+	}
+	defer func() {
+		if stopErr := pw.Stop(); stopErr != nil {
+			err = errors.Join(err, fmt.Errorf("could not stop playwright: %w", stopErr))
+		}
+	}()
+	browser, err := pw.Chromium.Launch(playwright.BrowserTypeLaunchOptions{Headless: playwright.Bool(true)})
+	if err != nil {
+		return "", withInstallHint(fmt.Errorf("could not launch Chromium: %w", err))
+	}
+	version = browser.Version()
+	if err := browser.Close(); err != nil {
+		return version, fmt.Errorf("could not close browser: %w", err)
+	}
+	return version, nil
+}
+
+// checkBroker connects to the broker of the .env file, the check is skipped without one
+func checkBroker(ctx context.Context, env string, timeout time.Duration) doctorCheck {
+	if env == "" {
+		return doctorCheck{Name: "mqtt", Status: doctorSkip, Detail: "no --env given", Critical: true}
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	elapsed, err := iot.CheckBroker(ctx, env)
+	return newDoctorCheck("mqtt", true, fmt.Sprintf("connected to the broker of %s in %s", env, elapsed.Round(time.Millisecond)), err)
+}
+
+// checkPortBindable listens on the port of every interface, as the http command does, and closes the listener
+func checkPortBindable(port int) error {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return err
+	}
+	return listener.Close()
+}
+
+// doctorCmd represents the doctor command
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check that the environment is ready to run misctl",
+	Long: `This command checks that the Playwright driver and Chromium are installed,
+that the MQTT broker of the .env file given by --env is reachable, and that the ports of the http command are bindable.
+It prints a checklist and exits with a non-zero code if a critical check fails.
+A port already in use is only a warning, and the MQTT check is skipped without --env.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		env, err := cmd.Flags().GetString("env")
+		assertErrorToNilf("failed to parse `env`: %w", err)
+		ports, err := cmd.Flags().GetIntSlice("port")
+		assertErrorToNilf("failed to parse `port`: %w", err)
+		timeout, err := cmd.Flags().GetDuration("timeout")
+		assertErrorToNilf("failed to parse `timeout`: %w", err)
+		pretty, err := cmd.Flags().GetBool("pretty")
+		assertErrorToNilf("failed to parse `pretty`: %w", err)
+
+		checks := []doctorCheck{}
+		version, err := checkPlaywright()
+		checks = append(checks, newDoctorCheck("playwright", true, fmt.Sprintf("Chromium %s launched", version), err))
+
+		checks = append(checks, checkBroker(cmd.Context(), env, timeout))
+
+		for _, port := range ports {
+			err := checkPortBindable(port)
+			checks = append(checks, newDoctorCheck(fmt.Sprintf("http port %d", port), false, "bindable", err))
+		}
+
+		if internal.JSONOutput() {
+			err = internal.PrintJSON(cmd.OutOrStdout(), checks, pretty)
+			assertErrorToNilf("could not print checks: %w", err)
+		} else {
+			writeDoctorChecklist(cmd.OutOrStdout(), checks)
+		}
+		if doctorFailed(checks) {
+			internal.Fatalf(internal.CodeRuntime, "a critical check failed")
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+
+	doctorCmd.Flags().StringP("env", "e", "", "Path to the .env file of the MQTT broker to check, the check is skipped if empty")
+	doctorCmd.Flags().IntSliceP("port", "p", []int{8080}, "Port number of the http command to check, repeat to check several ports")
+	doctorCmd.Flags().Duration("timeout", 10*time.Second, "Timeout of the MQTT broker check")
+}
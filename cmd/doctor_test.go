@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewDoctorCheck(t *testing.T) {
+	// Table Driven Test
+	tests := []struct {
+		name       string
+		critical   bool
+		err        error
+		wantStatus string
+		wantDetail string
+	}{
+		{name: "pass case", critical: true, err: nil, wantStatus: doctorPass, wantDetail: "ok"},
+		{name: "critical failure case", critical: true, err: errors.New("connection refused"), wantStatus: doctorFail, wantDetail: "connection refused"},
+		{name: "warning case", critical: false, err: errors.New("address already in use"), wantStatus: doctorWarn, wantDetail: "address already in use"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newDoctorCheck("check", tt.critical, "ok", tt.err)
+			if got.Status != tt.wantStatus || got.Detail != tt.wantDetail {
+				t.Errorf("%s: newDoctorCheck() = %+v; want status %q and detail %q", tt.name, got, tt.wantStatus, tt.wantDetail)
+			}
+		})
+	}
+}
+
+func TestDoctorFailed(t *testing.T) {
+	// Table Driven Test
+	tests := []struct {
+		name   string
+		checks []doctorCheck
+		want   bool
+	}{
+		{name: "no checks case", checks: nil, want: false},
+		{name: "all passed case", checks: []doctorCheck{{Status: doctorPass}, {Status: doctorSkip}}, want: false},
+		{name: "warning case", checks: []doctorCheck{{Status: doctorPass}, {Status: doctorWarn}}, want: false},
+		{name: "failure case", checks: []doctorCheck{{Status: doctorFail}, {Status: doctorPass}}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := doctorFailed(tt.checks); got != tt.want {
+				t.Errorf("%s: doctorFailed() = %t; want %t", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteDoctorChecklist(t *testing.T) {
+	// Table Driven Test
+	tests := []struct {
+		name   string
+		checks []doctorCheck
+		want   string
+	}{
+		{name: "single line case", checks: []doctorCheck{{Name: "mqtt", Status: doctorSkip, Detail: "no --env given"}}, want: "[SKIP] mqtt: no --env given\n"},
+		{name: "multi-line case", checks: []doctorCheck{{Name: "playwright", Status: doctorFail, Detail: "not installed\nrun install"}}, want: "[FAIL] playwright: not installed\n       run install\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			writeDoctorChecklist(&out, tt.checks)
+			if got := out.String(); got != tt.want {
+				t.Errorf("%s: writeDoctorChecklist() = %q; want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckPortBindable(t *testing.T) {
+	listener, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	used := listener.Addr().(*net.TCPAddr).Port
+
+	// Table Driven Test
+	tests := []struct {
+		name    string
+		port    int
+		wantErr bool
+	}{
+		{name: "free port case", port: 0, wantErr: false},
+		{name: "port in use case", port: used, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkPortBindable(tt.port); (err != nil) != tt.wantErr {
+				t.Errorf("%s: checkPortBindable(%d) = %v; want error %t", tt.name, tt.port, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckBroker(t *testing.T) {
+	dir := t.TempDir()
+
+	// Table Driven Test
+	tests := []struct {
+		name       string
+		env        string
+		wantStatus string
+		wantDetail string
+	}{
+		{name: "no env case", env: "", wantStatus: doctorSkip, wantDetail: "no --env given"},
+		{name: "missing env case", env: "missing.env", wantStatus: doctorFail, wantDetail: "could not load .env file"},
+		{name: "invalid port case", env: "MQTT_HOST_NAME=localhost\nMQTT_TCP_PORT=abc\n", wantStatus: doctorFail, wantDetail: "invalid MQTT_TCP_PORT"},
+		{name: "invalid bool case", env: "MQTT_HOST_NAME=localhost\nMQTT_USE_TLS=maybe\n", wantStatus: doctorFail, wantDetail: "invalid MQTT_USE_TLS"},
+		{name: "missing CA file case", env: "MQTT_HOST_NAME=localhost\nMQTT_CA_FILE=" + filepath.Join(dir, "missing.pem") + "\n", wantStatus: doctorFail, wantDetail: "missing.pem"},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := tt.env
+			if strings.Contains(env, "=") {
+				env = filepath.Join(dir, fmt.Sprintf("%d.env", i))
+				if err := os.WriteFile(env, []byte(tt.env), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			} else if env != "" {
+				env = filepath.Join(dir, env)
+			}
+			got := checkBroker(context.Background(), env, time.Second)
+			if got.Status != tt.wantStatus || !strings.Contains(got.Detail, tt.wantDetail) {
+				t.Errorf("%s: checkBroker() = %+v; want status %q and a detail containing %q", tt.name, got, tt.wantStatus, tt.wantDetail)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package http
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// adminPrefix is the path prefix of the admin endpoints, which are not recorded in the access log
+const adminPrefix = "/admin/"
+
+// accessRecord is a request kept in the access log
+type accessRecord struct {
+	Time       time.Time `json:"time"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	RemoteAddr string    `json:"remote_addr"`
+	Status     int       `json:"status"`
+	Bytes      int       `json:"bytes"`
+	DurationMs float64   `json:"duration_ms"`
+}
+
+// accessLog is a ring buffer of the most recent requests
+type accessLog struct {
+	mu      sync.Mutex
+	records []accessRecord
+	next    int
+	full    bool
+}
+
+func newAccessLog(size int) *accessLog {
+	return &accessLog{records: make([]accessRecord, size)}
+}
+
+// add records a request, overwriting the oldest one once the buffer is full
+func (l *accessLog) add(r accessRecord) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.records[l.next] = r
+	l.next = (l.next + 1) % len(l.records)
+	if l.next == 0 {
+		l.full = true
+	}
+}
+
+// snapshot returns the recorded requests, oldest first
+func (l *accessLog) snapshot() []accessRecord {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.full {
+		return append([]accessRecord{}, l.records[:l.next]...)
+	}
+	return append(append([]accessRecord{}, l.records[l.next:]...), l.records[:l.next]...)
+}
+
+// ServeHTTP serves the recorded requests as a JSON array
+func (l *accessLog) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(l.snapshot()); err != nil {
+		log.Printf("Write failed: %v\n", err)
+	}
+}
+
+// statusRecorder captures the status code and the size of a response
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+	return n, err
+}
+
+// recordAccess adds every request handled by next to the access log, except the admin ones
+func recordAccess(next http.Handler, l *accessLog) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, adminPrefix) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		l.add(accessRecord{
+			Time:       start,
+			Method:     r.Method,
+			Path:       r.URL.Path,
+			RemoteAddr: r.RemoteAddr,
+			Status:     rec.status,
+			Bytes:      rec.bytes,
+			DurationMs: float64(time.Since(start)) / float64(time.Millisecond),
+		})
+	})
+}
@@ -0,0 +1,57 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAccessLog(t *testing.T) {
+	// Table Driven Test
+	tests := []struct {
+		name      string
+		size      int
+		paths     []string
+		wantPaths []string
+	}{
+		{name: "empty case", size: 3, paths: nil, wantPaths: []string{}},
+		{name: "partial case", size: 3, paths: []string{"/a", "/b"}, wantPaths: []string{"/a", "/b"}},
+		{name: "wrap around case", size: 3, paths: []string{"/a", "/b", "/c", "/d", "/e"}, wantPaths: []string{"/c", "/d", "/e"}},
+		{name: "admin skipped case", size: 3, paths: []string{"/a", "/admin/requests", "/b"}, wantPaths: []string{"/a", "/b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newAccessLog(tt.size)
+			handler := recordAccess(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusTeapot)
+			}), l)
+			for _, path := range tt.paths {
+				handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+			}
+
+			rec := httptest.NewRecorder()
+			l.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/requests", nil))
+			var records []accessRecord
+			if err := json.Unmarshal(rec.Body.Bytes(), &records); err != nil {
+				t.Fatalf("%s: invalid JSON %q: %v", tt.name, rec.Body.String(), err)
+			}
+			got := []string{}
+			for _, r := range records {
+				got = append(got, r.Path)
+				if r.Status != http.StatusTeapot {
+					t.Errorf("%s: status of %s = %d; want %d", tt.name, r.Path, r.Status, http.StatusTeapot)
+				}
+			}
+			if len(got) != len(tt.wantPaths) {
+				t.Fatalf("%s: recorded paths = %v; want %v", tt.name, got, tt.wantPaths)
+			}
+			for i := range got {
+				if got[i] != tt.wantPaths[i] {
+					t.Errorf("%s: recorded paths = %v; want %v", tt.name, got, tt.wantPaths)
+					break
+				}
+			}
+		})
+	}
+}
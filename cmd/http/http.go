@@ -22,8 +22,10 @@ THE SOFTWARE.
 package http
 
 import (
+	"crypto/tls"
 	"log"
 
+	"github.com/ks6088ts-labs/misctl/internal"
 	"github.com/spf13/cobra"
 )
 
@@ -31,21 +33,152 @@ import (
 var httpCmd = &cobra.Command{
 	Use:   "http",
 	Short: "Start a HTTP server",
-	Long:  `Start a HTTP server that listens on the specified port.`,
+	Long: `Start a HTTP server that listens on the specified ports.
+
+The OpenTelemetry traces, metrics and logs of the server are written to the output by the stdout exporters.
+With --json, the output is only these exporter records, one JSON object per line, without the progress text.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		port, err := cmd.Flags().GetInt("port")
+		ports, err := cmd.Flags().GetIntSlice("port")
+		if err != nil {
+			log.Fatalf("unable to parse `port`: %v", err)
+		}
+		unixSocket, err := cmd.Flags().GetString("unix-socket")
+		if err != nil {
+			log.Fatalf("unable to parse `unix-socket`: %v", err)
+		}
+		// The socket replaces the default port, an explicit --port listens on both
+		if unixSocket != "" && !cmd.Flags().Changed("port") {
+			ports = nil
+		}
+		portFile, err := cmd.Flags().GetString("port-file")
+		if err != nil {
+			log.Fatalf("unable to parse `port-file`: %v", err)
+		}
+		if portFile != "" && len(ports) == 0 {
+			internal.Fatalf(internal.CodeUsage, "--port-file requires a TCP --port")
+		}
+
+		certFile, err := cmd.Flags().GetString("tls-cert")
+		if err != nil {
+			log.Fatalf("unable to parse `tls-cert`: %v", err)
+		}
+		keyFile, err := cmd.Flags().GetString("tls-key")
+		if err != nil {
+			log.Fatalf("unable to parse `tls-key`: %v", err)
+		}
+		cipherSuites, err := cmd.Flags().GetStringSlice("tls-cipher-suites")
+		if err != nil {
+			log.Fatalf("unable to parse `tls-cipher-suites`: %v", err)
+		}
+		allowInsecureCiphers, err := cmd.Flags().GetBool("allow-insecure-ciphers")
+		if err != nil {
+			log.Fatalf("unable to parse `allow-insecure-ciphers`: %v", err)
+		}
+		minVersion, err := cmd.Flags().GetString("tls-min-version")
+		if err != nil {
+			log.Fatalf("unable to parse `tls-min-version`: %v", err)
+		}
+		allowLegacyTLS, err := cmd.Flags().GetBool("allow-legacy-tls")
+		if err != nil {
+			log.Fatalf("unable to parse `allow-legacy-tls`: %v", err)
+		}
+		tlsMinVersion, err := internal.ParseTLSVersion(minVersion)
 		if err != nil {
-			log.Printf("unable to parse `port`: %v", port)
+			internal.Fatalf(internal.CodeUsage, "%s", err)
+		}
+		if tlsMinVersion < tls.VersionTLS12 {
+			if !allowLegacyTLS {
+				internal.Fatalf(internal.CodeUsage, "refusing to start: --tls-min-version %s is deprecated, pass --allow-legacy-tls to use it anyway", minVersion)
+			}
+			log.Printf("warning: accepting deprecated TLS versions from %s", minVersion)
+		}
+		maxConcurrent, err := cmd.Flags().GetInt("max-concurrent")
+		if err != nil {
+			log.Fatalf("unable to parse `max-concurrent`: %v", err)
+		}
+		overflow, err := cmd.Flags().GetString("overflow")
+		if err != nil {
+			log.Fatalf("unable to parse `overflow`: %v", err)
+		}
+		if maxConcurrent < 0 {
+			internal.Fatalf(internal.CodeUsage, "invalid `max-concurrent` %d, must not be negative", maxConcurrent)
+		}
+		if err := validateOverflow(overflow); err != nil {
+			internal.Fatalf(internal.CodeUsage, "%s", err)
+		}
+		admin, err := cmd.Flags().GetBool("admin")
+		if err != nil {
+			log.Fatalf("unable to parse `admin`: %v", err)
+		}
+		accessLogBuffer, err := cmd.Flags().GetInt("access-log-buffer")
+		if err != nil {
+			log.Fatalf("unable to parse `access-log-buffer`: %v", err)
+		}
+		if accessLogBuffer < 1 {
+			internal.Fatalf(internal.CodeUsage, "invalid `access-log-buffer` %d, must be at least 1", accessLogBuffer)
+		}
+		closeAfter, err := cmd.Flags().GetDuration("close-after")
+		if err != nil {
+			log.Fatalf("unable to parse `close-after`: %v", err)
+		}
+		if closeAfter < 0 {
+			internal.Fatalf(internal.CodeUsage, "invalid `close-after` %s, must not be negative", closeAfter)
+		}
+		verifyOnly, err := cmd.Flags().GetBool("verify-only")
+		if err != nil {
+			log.Fatalf("unable to parse `verify-only`: %v", err)
+		}
+
+		cfg := serverConfig{
+			Ports:                ports,
+			UnixSocket:           unixSocket,
+			PortFile:             portFile,
+			TLSCertFile:          certFile,
+			TLSKeyFile:           keyFile,
+			TLSCipherSuites:      cipherSuites,
+			AllowInsecureCiphers: allowInsecureCiphers,
+			TLSMinVersion:        tlsMinVersion,
+			AllowLegacyTLS:       allowLegacyTLS,
+			MaxConcurrent:        maxConcurrent,
+			Overflow:             overflow,
+			Admin:                admin,
+			AccessLogBuffer:      accessLogBuffer,
+			CloseAfter:           closeAfter,
+			Out:                  cmd.OutOrStdout(),
+		}
+
+		if verifyOnly {
+			if !cfg.useTLS() {
+				internal.Fatalf(internal.CodeUsage, "--verify-only requires --tls-cert and --tls-key")
+			}
+			if err := verifyTLS(cfg); err != nil {
+				internal.Fatalf(internal.CodeConfig, "TLS verification failed: %v", err)
+			}
+			return
 		}
 
-		if err := run(port); err != nil {
-			log.Fatalln(err)
+		if err := run(cmd.Context(), cfg); err != nil {
+			internal.Fatalf(internal.CodeRuntime, "%s", err)
 		}
 	},
 }
 
 func init() {
-	httpCmd.Flags().IntP("port", "p", 8080, "Port number")
+	httpCmd.Flags().IntSliceP("port", "p", []int{8080}, "Port number, repeat to listen on several ports")
+	httpCmd.Flags().String("unix-socket", "", "Path of a Unix domain socket to listen on instead of TCP, removed on shutdown; pass --port too to listen on both")
+	httpCmd.Flags().String("port-file", "", "Write the bound TCP ports to this file once listening, one per line, and remove it on shutdown; use with --port 0 to discover the ephemeral port")
+	httpCmd.Flags().String("tls-cert", "", "Path to the TLS certificate file (PEM) to serve HTTPS")
+	httpCmd.Flags().String("tls-key", "", "Path to the TLS private key file (PEM) to serve HTTPS")
+	httpCmd.Flags().StringSlice("tls-cipher-suites", []string{}, "Comma-separated TLS 1.0-1.2 cipher suite names to enable, defaults to the Go defaults")
+	httpCmd.Flags().Bool("allow-insecure-ciphers", false, "Allow cipher suites known to be insecure in --tls-cipher-suites")
+	httpCmd.Flags().String("tls-min-version", "1.2", "Minimum TLS version: 1.0, 1.1, 1.2 or 1.3")
+	httpCmd.Flags().Bool("allow-legacy-tls", false, "Allow a --tls-min-version below 1.2")
+	httpCmd.Flags().Int("max-concurrent", 0, "Maximum number of requests handled at the same time, 0 for no limit")
+	httpCmd.Flags().String("overflow", "queue", "Policy for requests above --max-concurrent: queue or reject (503)")
+	httpCmd.Flags().Bool("admin", false, "Serve the admin endpoints: /admin/requests lists the recent requests as JSON")
+	httpCmd.Flags().Int("access-log-buffer", 100, "Number of recent requests kept for /admin/requests")
+	httpCmd.Flags().Duration("close-after", 0, "Shut the server down gracefully after this duration, 0 to serve until interrupted")
+	httpCmd.Flags().Bool("verify-only", false, "Serve a single TLS handshake to verify the certificate and key, then exit")
 }
 
 func GetCommand() *cobra.Command {
@@ -0,0 +1,51 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/otel/metric"
+)
+
+const (
+	overflowQueue  = "queue"
+	overflowReject = "reject"
+)
+
+// validateOverflow checks the `--overflow` policy
+func validateOverflow(overflow string) error {
+	switch overflow {
+	case overflowQueue, overflowReject:
+		return nil
+	}
+	return fmt.Errorf("unknown overflow policy %q, must be one of %s or %s", overflow, overflowQueue, overflowReject)
+}
+
+// limitConcurrency lets at most max requests run next at the same time.
+// Excess requests wait for a slot with the queue policy, or get a 503 right away with the reject policy.
+// Queued requests whose client goes away also get a 503.
+func limitConcurrency(next http.Handler, max int, overflow string, inflight metric.Int64UpDownCounter) http.Handler {
+	slots := make(chan struct{}, max)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if overflow == overflowReject {
+			select {
+			case slots <- struct{}{}:
+			default:
+				http.Error(w, "too many concurrent requests", http.StatusServiceUnavailable)
+				return
+			}
+		} else {
+			select {
+			case slots <- struct{}{}:
+			case <-r.Context().Done():
+				http.Error(w, "too many concurrent requests", http.StatusServiceUnavailable)
+				return
+			}
+		}
+		defer func() { <-slots }()
+
+		inflight.Add(r.Context(), 1)
+		defer inflight.Add(r.Context(), -1)
+		next.ServeHTTP(w, r)
+	})
+}
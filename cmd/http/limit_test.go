@@ -0,0 +1,88 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel/metric/noop"
+)
+
+func TestLimitConcurrency(t *testing.T) {
+	inflight, err := noop.NewMeterProvider().Meter("test").Int64UpDownCounter("inflight")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Table Driven Test
+	tests := []struct {
+		name       string
+		overflow   string
+		wantStatus int
+	}{
+		{name: "reject case", overflow: overflowReject, wantStatus: http.StatusServiceUnavailable},
+		{name: "queue case", overflow: overflowQueue, wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			started := make(chan struct{})
+			release := make(chan struct{})
+			var once sync.Once
+			handler := limitConcurrency(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/block" {
+					once.Do(func() { close(started) })
+					<-release
+				}
+			}), 1, tt.overflow, inflight)
+
+			// Occupy the only slot
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/block", nil))
+			}()
+			<-started
+
+			rec := httptest.NewRecorder()
+			if tt.overflow == overflowQueue {
+				// The queued request can only complete once the slot is released
+				queued := make(chan struct{})
+				go func() {
+					defer close(queued)
+					handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+				}()
+				close(release)
+				<-queued
+			} else {
+				handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+				close(release)
+			}
+			<-done
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("%s: status = %d; want %d", tt.name, rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestValidateOverflow(t *testing.T) {
+	// Table Driven Test
+	tests := []struct {
+		name     string
+		overflow string
+		wantErr  bool
+	}{
+		{name: "queue case", overflow: "queue"},
+		{name: "reject case", overflow: "reject"},
+		{name: "unknown policy case", overflow: "drop", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateOverflow(tt.overflow); (err != nil) != tt.wantErr {
+				t.Errorf("%s: validateOverflow(%s) = %v; wantErr %t", tt.name, tt.overflow, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -3,8 +3,10 @@ package http
 import (
 	"context"
 	"errors"
+	"io"
 	"time"
 
+	"github.com/ks6088ts-labs/misctl/internal"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/exporters/stdout/stdoutlog"
 	"go.opentelemetry.io/otel/exporters/stdout/stdoutmetric"
@@ -18,7 +20,7 @@ import (
 
 // setupOTelSDK bootstraps the OpenTelemetry pipeline.
 // If it does not return an error, make sure to call shutdown for proper cleanup.
-func setupOTelSDK(ctx context.Context) (shutdown func(context.Context) error, err error) {
+func setupOTelSDK(ctx context.Context, out io.Writer) (shutdown func(context.Context) error, err error) {
 	var shutdownFuncs []func(context.Context) error
 
 	// shutdown calls cleanup functions registered via shutdownFuncs.
@@ -43,7 +45,7 @@ func setupOTelSDK(ctx context.Context) (shutdown func(context.Context) error, er
 	otel.SetTextMapPropagator(prop)
 
 	// Set up trace provider.
-	tracerProvider, err := newTraceProvider()
+	tracerProvider, err := newTraceProvider(out)
 	if err != nil {
 		handleErr(err)
 		return
@@ -52,7 +54,7 @@ func setupOTelSDK(ctx context.Context) (shutdown func(context.Context) error, er
 	otel.SetTracerProvider(tracerProvider)
 
 	// Set up meter provider.
-	meterProvider, err := newMeterProvider()
+	meterProvider, err := newMeterProvider(out)
 	if err != nil {
 		handleErr(err)
 		return
@@ -61,7 +63,7 @@ func setupOTelSDK(ctx context.Context) (shutdown func(context.Context) error, er
 	otel.SetMeterProvider(meterProvider)
 
 	// Set up logger provider.
-	loggerProvider, err := newLoggerProvider()
+	loggerProvider, err := newLoggerProvider(out)
 	if err != nil {
 		handleErr(err)
 		return
@@ -79,9 +81,13 @@ func newPropagator() propagation.TextMapPropagator {
 	)
 }
 
-func newTraceProvider() (*trace.TracerProvider, error) {
-	traceExporter, err := stdouttrace.New(
-		stdouttrace.WithPrettyPrint())
+func newTraceProvider(out io.Writer) (*trace.TracerProvider, error) {
+	options := []stdouttrace.Option{stdouttrace.WithWriter(out)}
+	// --json keeps one span per line
+	if !internal.JSONOutput() {
+		options = append(options, stdouttrace.WithPrettyPrint())
+	}
+	traceExporter, err := stdouttrace.New(options...)
 	if err != nil {
 		return nil, err
 	}
@@ -94,8 +100,8 @@ func newTraceProvider() (*trace.TracerProvider, error) {
 	return traceProvider, nil
 }
 
-func newMeterProvider() (*metric.MeterProvider, error) {
-	metricExporter, err := stdoutmetric.New()
+func newMeterProvider(out io.Writer) (*metric.MeterProvider, error) {
+	metricExporter, err := stdoutmetric.New(stdoutmetric.WithWriter(out))
 	if err != nil {
 		return nil, err
 	}
@@ -108,8 +114,8 @@ func newMeterProvider() (*metric.MeterProvider, error) {
 	return meterProvider, nil
 }
 
-func newLoggerProvider() (*log.LoggerProvider, error) {
-	logExporter, err := stdoutlog.New()
+func newLoggerProvider(out io.Writer) (*log.LoggerProvider, error) {
+	logExporter, err := stdoutlog.New(stdoutlog.WithWriter(out))
 	if err != nil {
 		return nil, err
 	}
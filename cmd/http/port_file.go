@@ -0,0 +1,46 @@
+package http
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// boundPorts returns the TCP ports the listeners are bound to, which tells the ports chosen for port 0
+func boundPorts(listeners []net.Listener) []int {
+	ports := []int{}
+	for _, ln := range listeners {
+		if addr, ok := ln.Addr().(*net.TCPAddr); ok {
+			ports = append(ports, addr.Port)
+		}
+	}
+	return ports
+}
+
+// writePortFile writes the ports to path, one per line in the order of --port.
+// The file is renamed into place so that a harness polling for it never reads it half written.
+func writePortFile(path string, ports []int) error {
+	lines := make([]string, 0, len(ports))
+	for _, port := range ports {
+		lines = append(lines, strconv.Itoa(port))
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("could not create port file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(strings.Join(lines, "\n") + "\n"); err != nil {
+		tmp.Close()
+		return fmt.Errorf("could not write port file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("could not write port file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("could not write port file: %w", err)
+	}
+	return nil
+}
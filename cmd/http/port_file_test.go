@@ -0,0 +1,73 @@
+package http
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestBoundPorts(t *testing.T) {
+	tcp, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tcp.Close()
+	unix, err := net.Listen("unix", filepath.Join(t.TempDir(), "http.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unix.Close()
+	port := tcp.Addr().(*net.TCPAddr).Port
+
+	// Table Driven Test
+	tests := []struct {
+		name      string
+		listeners []net.Listener
+		want      []int
+	}{
+		{name: "no listener case", listeners: nil, want: []int{}},
+		{name: "tcp case", listeners: []net.Listener{tcp}, want: []int{port}},
+		{name: "unix socket case", listeners: []net.Listener{tcp, unix}, want: []int{port}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := boundPorts(tt.listeners); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s: boundPorts() = %v; want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWritePortFile(t *testing.T) {
+	// Table Driven Test
+	tests := []struct {
+		name  string
+		ports []int
+		want  string
+	}{
+		{name: "single port case", ports: []int{41234}, want: "41234\n"},
+		{name: "several ports case", ports: []int{8080, 41234}, want: "8080\n41234\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "http.port")
+			if err := writePortFile(path, tt.ports); err != nil {
+				t.Fatalf("%s: writePortFile() = %v", tt.name, err)
+			}
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("%s: writePortFile() wrote %q; want %q", tt.name, got, tt.want)
+			}
+			// The temporary file is renamed, nothing else is left behind
+			if entries, err := os.ReadDir(dir); err != nil || len(entries) != 1 {
+				t.Errorf("%s: writePortFile() left %v in the directory; want only the port file", tt.name, entries)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package http
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
@@ -14,6 +15,8 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/ks6088ts-labs/misctl/internal"
+
 	"go.opentelemetry.io/contrib/bridges/otelslog"
 
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
@@ -29,6 +32,8 @@ var (
 	meter   = otel.Meter(name)
 	logger  = otelslog.NewLogger(name)
 	rollCnt metric.Int64Counter
+	// inflightCnt tracks the requests being handled when `--max-concurrent` is set
+	inflightCnt metric.Int64UpDownCounter
 )
 
 func init() {
@@ -39,6 +44,12 @@ func init() {
 	if err != nil {
 		panic(err)
 	}
+	inflightCnt, err = meter.Int64UpDownCounter("http.server.concurrent_requests",
+		metric.WithDescription("The number of requests being handled under the concurrency limit"),
+		metric.WithUnit("{request}"))
+	if err != nil {
+		panic(err)
+	}
 }
 
 func rolldice(w http.ResponseWriter, r *http.Request) {
@@ -65,13 +76,59 @@ func rolldice(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func run(port int) (err error) {
+// serverConfig holds the options of the HTTP server
+type serverConfig struct {
+	Ports []int
+	// UnixSocket is the path of a Unix domain socket to listen on as well, empty for none
+	UnixSocket string
+	// PortFile is the path the bound TCP ports are written to once listening, removed on shutdown, empty for none
+	PortFile    string
+	TLSCertFile string
+	TLSKeyFile  string
+	// MaxConcurrent caps the requests handled at the same time, 0 for no limit
+	MaxConcurrent int
+	// Overflow is the policy for requests above MaxConcurrent, queue or reject
+	Overflow string
+	// TLSCipherSuites are the cipher suite names to enable, empty keeps the defaults
+	TLSCipherSuites      []string
+	AllowInsecureCiphers bool
+	// TLSMinVersion is the minimum TLS version, below 1.2 requires AllowLegacyTLS
+	TLSMinVersion  uint16
+	AllowLegacyTLS bool
+	// Admin serves the admin endpoints, such as the recent requests at /admin/requests
+	Admin bool
+	// AccessLogBuffer is the number of recent requests kept for /admin/requests
+	AccessLogBuffer int
+	// CloseAfter shuts the server down gracefully once elapsed, 0 to serve until interrupted
+	CloseAfter time.Duration
+	// Out receives the OpenTelemetry exporter output
+	Out io.Writer
+}
+
+// useTLS reports whether the server should serve HTTPS
+func (c serverConfig) useTLS() bool {
+	return c.TLSCertFile != "" && c.TLSKeyFile != ""
+}
+
+// tlsConfig builds the server TLS configuration with the shared builder
+func (c serverConfig) tlsConfig() (*tls.Config, error) {
+	return internal.BuildTLSConfig(internal.TLSOptions{
+		CertFile:             c.TLSCertFile,
+		KeyFile:              c.TLSKeyFile,
+		CipherSuites:         c.TLSCipherSuites,
+		AllowInsecureCiphers: c.AllowInsecureCiphers,
+		MinVersion:           c.TLSMinVersion,
+		AllowLegacyVersions:  c.AllowLegacyTLS,
+	})
+}
+
+func run(ctx context.Context, cfg serverConfig) (err error) {
 	// Handle SIGINT (CTRL+C) gracefully.
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
 	defer stop()
 
 	// Set up OpenTelemetry.
-	otelShutdown, err := setupOTelSDK(ctx)
+	otelShutdown, err := setupOTelSDK(ctx, cfg.Out)
 	if err != nil {
 		return
 	}
@@ -80,18 +137,75 @@ func run(port int) (err error) {
 		err = errors.Join(err, otelShutdown(context.Background()))
 	}()
 
-	// Start HTTP server.
+	// Listen on every port before serving so that a busy port fails fast.
+	listeners := make([]net.Listener, 0, len(cfg.Ports))
+	for _, port := range cfg.Ports {
+		ln, lnErr := net.Listen("tcp", ":"+strconv.Itoa(port))
+		if lnErr != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			err = lnErr
+			return
+		}
+		listeners = append(listeners, ln)
+	}
+	if cfg.UnixSocket != "" {
+		ln, lnErr := listenUnix(cfg.UnixSocket)
+		if lnErr != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			err = lnErr
+			return
+		}
+		listeners = append(listeners, ln)
+	}
+	// Closing removes the socket file even when serving fails, Shutdown closes them too.
+	defer func() {
+		for _, l := range listeners {
+			l.Close()
+		}
+	}()
+	if cfg.PortFile != "" {
+		if err = writePortFile(cfg.PortFile, boundPorts(listeners)); err != nil {
+			return
+		}
+		defer os.Remove(cfg.PortFile)
+	}
+
+	// Start HTTP server, a single server shares the handler across all the listeners.
 	srv := &http.Server{
-		Addr:         ":" + strconv.Itoa(port),
 		BaseContext:  func(_ net.Listener) context.Context { return ctx },
 		ReadTimeout:  time.Second,
 		WriteTimeout: 10 * time.Second,
-		Handler:      newHTTPHandler(),
+		Handler:      newHTTPHandler(cfg),
+	}
+	if cfg.useTLS() {
+		if srv.TLSConfig, err = cfg.tlsConfig(); err != nil {
+			return
+		}
+	}
+	srvErr := make(chan error, len(listeners))
+	for _, ln := range listeners {
+		fmt.Fprintf(internal.Decorative(cfg.Out), "Listening on %s\n", ln.Addr())
+		go func(ln net.Listener) {
+			if cfg.useTLS() {
+				// The certificate is already loaded in srv.TLSConfig
+				srvErr <- srv.ServeTLS(ln, "", "")
+				return
+			}
+			srvErr <- srv.Serve(ln)
+		}(ln)
+	}
+
+	var closeAfter <-chan time.Time
+	if cfg.CloseAfter > 0 {
+		timer := time.NewTimer(cfg.CloseAfter)
+		defer timer.Stop()
+		closeAfter = timer.C
+		fmt.Fprintf(internal.Decorative(cfg.Out), "Shutting down at %s (after %s)\n", time.Now().Add(cfg.CloseAfter).Format(time.RFC3339), cfg.CloseAfter)
 	}
-	srvErr := make(chan error, 1)
-	go func() {
-		srvErr <- srv.ListenAndServe()
-	}()
 
 	// Wait for interruption.
 	select {
@@ -102,14 +216,17 @@ func run(port int) (err error) {
 		// Wait for first CTRL+C.
 		// Stop receiving signal notifications as soon as possible.
 		stop()
+	case <-closeAfter:
+		fmt.Fprintf(internal.Decorative(cfg.Out), "Closing after %s\n", cfg.CloseAfter)
+		stop()
 	}
 
-	// When Shutdown is called, ListenAndServe immediately returns ErrServerClosed.
+	// When Shutdown is called, Serve immediately returns ErrServerClosed on every listener.
 	err = srv.Shutdown(context.Background())
 	return
 }
 
-func newHTTPHandler() http.Handler {
+func newHTTPHandler(cfg serverConfig) http.Handler {
 	mux := http.NewServeMux()
 
 	// handleFunc is a replacement for mux.HandleFunc
@@ -124,7 +241,19 @@ func newHTTPHandler() http.Handler {
 	handleFunc("/rolldice/", rolldice)
 	handleFunc("/rolldice/{player}", rolldice)
 
+	// Limit the concurrency inside the instrumentation so that rejected requests are traced too.
+	var inner http.Handler = mux
+	if cfg.MaxConcurrent > 0 {
+		inner = limitConcurrency(mux, cfg.MaxConcurrent, cfg.Overflow, inflightCnt)
+	}
+	// Record outside the limit so that rejected requests are listed too.
+	if cfg.Admin {
+		accessLog := newAccessLog(cfg.AccessLogBuffer)
+		mux.Handle(adminPrefix+"requests", accessLog)
+		inner = recordAccess(inner, accessLog)
+	}
+
 	// Add HTTP instrumentation for the whole server.
-	handler := otelhttp.NewHandler(mux, "/")
+	handler := otelhttp.NewHandler(inner, "/")
 	return handler
 }
@@ -0,0 +1,28 @@
+package http
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"time"
+)
+
+// listenUnix listens on the Unix domain socket at path.
+// A socket file left behind by a server that did not shut down cleanly is replaced,
+// while one that still accepts connections is reported as in use.
+// The socket file is removed when the listener is closed.
+func listenUnix(path string) (net.Listener, error) {
+	if fi, err := os.Lstat(path); err == nil {
+		if fi.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("%s is in use by another server", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("could not remove stale socket %s: %w", path, err)
+		}
+	}
+	return net.Listen("unix", path)
+}
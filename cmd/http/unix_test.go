@@ -0,0 +1,57 @@
+package http
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestListenUnix(t *testing.T) {
+	// Table Driven Test
+	tests := []struct {
+		name    string
+		setup   func(t *testing.T, path string)
+		wantErr bool
+	}{
+		{name: "normal case", setup: func(t *testing.T, path string) {}},
+		{name: "stale socket case", setup: func(t *testing.T, path string) {
+			ln, err := net.Listen("unix", path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Keep the file as a crashed server would
+			ln.(*net.UnixListener).SetUnlinkOnClose(false)
+			ln.Close()
+		}},
+		{name: "socket in use case", setup: func(t *testing.T, path string) {
+			ln, err := net.Listen("unix", path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { ln.Close() })
+		}, wantErr: true},
+		{name: "regular file case", setup: func(t *testing.T, path string) {
+			if err := os.WriteFile(path, nil, 0o600); err != nil {
+				t.Fatal(err)
+			}
+		}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "http.sock")
+			tt.setup(t, path)
+			ln, err := listenUnix(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("%s: listenUnix() error = %v; want error %t", tt.name, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			ln.Close()
+			if _, err := os.Lstat(path); !os.IsNotExist(err) {
+				t.Errorf("%s: socket file still exists after close: %v", tt.name, err)
+			}
+		})
+	}
+}
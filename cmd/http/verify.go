@@ -0,0 +1,78 @@
+package http
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ks6088ts-labs/misctl/internal"
+)
+
+// verifyTLS loads the certificate and key, serves a single TLS handshake on a loopback listener
+// and checks that the certificate chain presented by the server is valid.
+func verifyTLS(cfg serverConfig) error {
+	tlsConfig, err := cfg.tlsConfig()
+	if err != nil {
+		return err
+	}
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", tlsConfig)
+	if err != nil {
+		return fmt.Errorf("could not listen: %w", err)
+	}
+	defer ln.Close()
+
+	srvErr := make(chan error, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			srvErr <- err
+			return
+		}
+		defer conn.Close()
+		srvErr <- conn.(*tls.Conn).Handshake()
+	}()
+
+	// The chain is verified below, the hostname is not known at this point
+	conn, err := tls.Dial("tcp", ln.Addr().String(), &tls.Config{InsecureSkipVerify: true})
+	if err != nil {
+		return fmt.Errorf("handshake failed: %w", err)
+	}
+	defer conn.Close()
+	if err := <-srvErr; err != nil {
+		return fmt.Errorf("handshake failed: %w", err)
+	}
+
+	state := conn.ConnectionState()
+	internal.PrintTLSConnectionState(cfg.Out, state)
+	return verifyChain(cfg.Out, state.PeerCertificates)
+}
+
+// verifyChain verifies the leaf certificate against the system roots, the intermediates of the chain
+// and the last certificate of the chain when it is self-signed.
+func verifyChain(out io.Writer, chain []*x509.Certificate) error {
+	if len(chain) == 0 {
+		return errors.New("no certificate presented")
+	}
+
+	roots, err := x509.SystemCertPool()
+	if err != nil {
+		roots = x509.NewCertPool()
+	}
+	intermediates := x509.NewCertPool()
+	for _, c := range chain[1:] {
+		intermediates.AddCert(c)
+	}
+	last := chain[len(chain)-1]
+	if last.CheckSignatureFrom(last) == nil {
+		roots.AddCert(last)
+	}
+
+	if _, err := chain[0].Verify(x509.VerifyOptions{Roots: roots, Intermediates: intermediates}); err != nil {
+		return fmt.Errorf("could not verify certificate chain: %w", err)
+	}
+	fmt.Fprintln(internal.Decorative(out), "Certificate chain verified")
+	return nil
+}
@@ -0,0 +1,90 @@
+package iot
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eclipse/paho.golang/paho"
+)
+
+// ackTrace holds the packet ID of a QoS 1/2 publish and when its acknowledgements arrived
+type ackTrace struct {
+	PacketID uint16
+	Sent     time.Time
+	// Acks are the arrival times of the PUBACK, or of the PUBREC then the PUBCOMP
+	Acks []time.Time
+}
+
+// ackTracer is a paho.MIDService which traces the packet IDs it hands out and the acknowledgements received for them.
+// It relies on the publishes being sent one at a time, as the last packet ID requested is the one of the current publish.
+type ackTracer struct {
+	paho.MIDService
+	now    func() time.Time
+	mu     sync.Mutex
+	traces map[uint16]*ackTrace
+	last   uint16
+}
+
+func newAckTracer() *ackTracer {
+	mids := &paho.MIDs{}
+	// Clear allocates the index of the default service
+	mids.Clear()
+	return &ackTracer{MIDService: mids, now: time.Now, traces: map[uint16]*ackTrace{}}
+}
+
+func (t *ackTracer) Request(c *paho.CPContext) (uint16, error) {
+	mid, err := t.MIDService.Request(c)
+	if err != nil {
+		return mid, err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.traces[mid] = &ackTrace{PacketID: mid, Sent: t.now()}
+	t.last = mid
+	return mid, nil
+}
+
+// Get is called by the client as each acknowledgement is received
+func (t *ackTracer) Get(mid uint16) *paho.CPContext {
+	t.mu.Lock()
+	if trace, ok := t.traces[mid]; ok {
+		trace.Acks = append(trace.Acks, t.now())
+	}
+	t.mu.Unlock()
+	return t.MIDService.Get(mid)
+}
+
+// take returns and forgets the trace of the last publish, nil if none was traced
+func (t *ackTracer) take() *ackTrace {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	trace := t.traces[t.last]
+	delete(t.traces, t.last)
+	return trace
+}
+
+// formatAckTrace describes the acknowledgements of a publish with their delay since it was sent.
+// resp carries the reason code of the last acknowledgement, nil when none was received.
+func formatAckTrace(qos byte, trace *ackTrace, resp *paho.PublishResponse, now time.Time) string {
+	names := []string{"PUBACK"}
+	if qos == 2 {
+		names = []string{"PUBREC", "PUBCOMP"}
+	}
+	if len(trace.Acks) == 0 {
+		return fmt.Sprintf("packet ID %d: no acknowledgement after %s", trace.PacketID, now.Sub(trace.Sent))
+	}
+	acks := make([]string, 0, len(trace.Acks))
+	for i, at := range trace.Acks {
+		name := "ack"
+		if i < len(names) {
+			name = names[i]
+		}
+		if i == len(trace.Acks)-1 && resp != nil {
+			name = fmt.Sprintf("%s reason code 0x%02x", name, resp.ReasonCode)
+		}
+		acks = append(acks, fmt.Sprintf("%s after %s", name, at.Sub(trace.Sent)))
+	}
+	return fmt.Sprintf("packet ID %d: %s", trace.PacketID, strings.Join(acks, ", "))
+}
@@ -0,0 +1,82 @@
+package iot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/eclipse/paho.golang/paho"
+)
+
+func TestAckTracer(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	tracer := newAckTracer()
+	tracer.now = func() time.Time { return now }
+
+	cp := &paho.CPContext{}
+	mid, err := tracer.Request(cp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(2 * time.Millisecond)
+	if got := tracer.Get(mid); got != cp {
+		t.Errorf("Get(%d) = %p; want %p", mid, got, cp)
+	}
+	// Acknowledgements of packet IDs which are not traced are ignored
+	tracer.Get(mid + 1)
+
+	trace := tracer.take()
+	if trace == nil || trace.PacketID != mid || len(trace.Acks) != 1 || trace.Acks[0].Sub(trace.Sent) != 2*time.Millisecond {
+		t.Fatalf("take() = %+v; want packet ID %d acknowledged after 2ms", trace, mid)
+	}
+	if trace := tracer.take(); trace != nil {
+		t.Errorf("take() = %+v after take; want nil", trace)
+	}
+}
+
+func TestFormatAckTrace(t *testing.T) {
+	sent := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	// Table Driven Test
+	tests := []struct {
+		name  string
+		qos   byte
+		trace *ackTrace
+		resp  *paho.PublishResponse
+		want  string
+	}{
+		{
+			name:  "QoS 1 case",
+			qos:   1,
+			trace: &ackTrace{PacketID: 3, Sent: sent, Acks: []time.Time{sent.Add(time.Millisecond)}},
+			resp:  &paho.PublishResponse{ReasonCode: 0x10},
+			want:  "packet ID 3: PUBACK reason code 0x10 after 1ms",
+		},
+		{
+			name:  "QoS 2 case",
+			qos:   2,
+			trace: &ackTrace{PacketID: 4, Sent: sent, Acks: []time.Time{sent.Add(time.Millisecond), sent.Add(3 * time.Millisecond)}},
+			resp:  &paho.PublishResponse{},
+			want:  "packet ID 4: PUBREC after 1ms, PUBCOMP reason code 0x00 after 3ms",
+		},
+		{
+			name:  "QoS 2 rejected case",
+			qos:   2,
+			trace: &ackTrace{PacketID: 5, Sent: sent, Acks: []time.Time{sent.Add(time.Millisecond)}},
+			resp:  &paho.PublishResponse{ReasonCode: 0x87},
+			want:  "packet ID 5: PUBREC reason code 0x87 after 1ms",
+		},
+		{
+			name:  "no acknowledgement case",
+			qos:   1,
+			trace: &ackTrace{PacketID: 6, Sent: sent},
+			want:  "packet ID 6: no acknowledgement after 5s",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatAckTrace(tt.qos, tt.trace, tt.resp, sent.Add(5*time.Second)); got != tt.want {
+				t.Errorf("%s: formatAckTrace() = %q; want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package iot
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/eclipse/paho.golang/paho"
+)
+
+// authContinue is the reason code of an AUTH packet continuing the enhanced authentication
+const authContinue = 0x18
+
+// Enhanced authentication options set by the `--auth-*` flags shared by the iot subcommands
+var (
+	authMethod  string
+	authData    string
+	authCommand string
+)
+
+// runAuthCommand runs command with the server authentication data on stdin and returns its stdout.
+// The command is split on white space, it is not run through a shell.
+func runAuthCommand(command, method string, challenge []byte) ([]byte, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return nil, errors.New("empty auth command")
+	}
+	var stdout, stderr bytes.Buffer
+	c := exec.Command(args[0], args[1:]...)
+	c.Stdin = bytes.NewReader(challenge)
+	c.Stdout = &stdout
+	c.Stderr = &stderr
+	c.Env = append(os.Environ(), "MQTT_AUTH_METHOD="+method)
+	if err := c.Run(); err != nil {
+		return nil, fmt.Errorf("auth command failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// commandAuther answers the AUTH challenges of the broker with an external command
+type commandAuther struct {
+	method  string
+	command string
+	// out receives the progress of the exchange, the failures are logged
+	out io.Writer
+}
+
+func (a *commandAuther) Authenticate(challenge *paho.Auth) *paho.Auth {
+	var data []byte
+	if challenge.Properties != nil {
+		data = challenge.Properties.AuthData
+	}
+	fmt.Fprintf(a.out, "received AUTH challenge; reason code: 0x%02x, %d byte(s) of data\n", challenge.ReasonCode, len(data))
+
+	// On failure an empty response is still sent, leaving the broker to end the exchange
+	response, err := runAuthCommand(a.command, a.method, data)
+	if err != nil {
+		log.Printf("could not compute AUTH response: %s", err)
+	}
+	return &paho.Auth{
+		ReasonCode: authContinue,
+		Properties: &paho.AuthProperties{
+			AuthMethod: a.method,
+			AuthData:   response,
+		},
+	}
+}
+
+func (a *commandAuther) Authenticated() {
+	fmt.Fprintf(a.out, "enhanced authentication with %s completed\n", a.method)
+}
+
+func init() {
+	iotCmd.PersistentFlags().StringVar(&authMethod, "auth-method", "", "MQTT v5 enhanced authentication method, e.g. SCRAM-SHA-256")
+	iotCmd.PersistentFlags().StringVar(&authData, "auth-data", "", "Initial authentication data sent in the CONNECT packet")
+	iotCmd.PersistentFlags().StringVar(&authCommand, "auth-command", "", "Command computing the response to each AUTH challenge, reading the challenge on stdin and writing the response on stdout")
+}
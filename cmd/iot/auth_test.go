@@ -0,0 +1,37 @@
+package iot
+
+import (
+	"os/exec"
+	"testing"
+)
+
+func TestRunAuthCommand(t *testing.T) {
+	if _, err := exec.LookPath("cat"); err != nil {
+		t.Skip("cat is not available")
+	}
+
+	// Table Driven Test
+	tests := []struct {
+		name      string
+		command   string
+		challenge string
+		want      string
+		wantErr   bool
+	}{
+		{name: "echo case", command: "cat", challenge: "server-first", want: "server-first"},
+		{name: "empty command case", command: "  ", wantErr: true},
+		{name: "failing command case", command: "cat /nonexistent/challenge", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := runAuthCommand(tt.command, "TEST", []byte(tt.challenge))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%s: runAuthCommand(%q) error = %v; wantErr %t", tt.name, tt.command, err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && string(got) != tt.want {
+				t.Errorf("%s: runAuthCommand(%q) = %q; want %q", tt.name, tt.command, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package iot
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// messagePrinter decouples the router from a slow output through a bounded buffer
+type messagePrinter struct {
+	out     io.Writer
+	lines   chan string
+	drop    bool
+	dropped atomic.Int64
+	done    chan struct{}
+	// mu guards closed so late messages are discarded rather than sent on a closed channel
+	mu     sync.RWMutex
+	closed bool
+}
+
+// newMessagePrinter starts printing lines to out. When drop is set, lines which do not fit in the buffer
+// are counted and discarded instead of blocking the caller.
+func newMessagePrinter(out io.Writer, size int, drop bool) *messagePrinter {
+	p := &messagePrinter{
+		out:   out,
+		lines: make(chan string, size),
+		drop:  drop,
+		done:  make(chan struct{}),
+	}
+	go func() {
+		defer close(p.done)
+		for line := range p.lines {
+			fmt.Fprint(p.out, line)
+		}
+	}()
+	return p
+}
+
+// printf queues a formatted line and reports whether it was accepted
+func (p *messagePrinter) printf(format string, a ...any) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return false
+	}
+	line := fmt.Sprintf(format, a...)
+	if !p.drop {
+		p.lines <- line
+		return true
+	}
+	select {
+	case p.lines <- line:
+		return true
+	default:
+		p.dropped.Add(1)
+		return false
+	}
+}
+
+// close flushes the queued lines and returns the number of dropped ones
+func (p *messagePrinter) close() int64 {
+	p.mu.Lock()
+	p.closed = true
+	close(p.lines)
+	p.mu.Unlock()
+	<-p.done
+	return p.dropped.Load()
+}
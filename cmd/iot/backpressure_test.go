@@ -0,0 +1,63 @@
+package iot
+
+import (
+	"strings"
+	"testing"
+)
+
+// blockingWriter blocks every write until released
+type blockingWriter struct {
+	release chan struct{}
+	b       strings.Builder
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	<-w.release
+	return w.b.Write(p)
+}
+
+func TestMessagePrinter(t *testing.T) {
+	// Table Driven Test
+	tests := []struct {
+		name       string
+		size       int
+		drop       bool
+		lines      int
+		minDropped int64
+		maxDropped int64
+	}{
+		{name: "fits in buffer case", size: 10, drop: true, lines: 5, minDropped: 0, maxDropped: 0},
+		// The printer may already hold one line in the blocked write
+		{name: "drop on full buffer case", size: 2, drop: true, lines: 6, minDropped: 3, maxDropped: 4},
+		{name: "no drop case", size: 2, drop: false, lines: 6, minDropped: 0, maxDropped: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &blockingWriter{release: make(chan struct{})}
+			p := newMessagePrinter(w, tt.size, tt.drop)
+			if !tt.drop {
+				// Blocking mode needs the writer to make progress
+				close(w.release)
+			}
+			accepted := 0
+			for i := 0; i < tt.lines; i++ {
+				if p.printf("line %d\n", i) {
+					accepted++
+				}
+			}
+			if tt.drop {
+				close(w.release)
+			}
+			dropped := p.close()
+			if int(dropped)+accepted != tt.lines {
+				t.Errorf("%s: dropped %d + accepted %d; want %d", tt.name, dropped, accepted, tt.lines)
+			}
+			if got := strings.Count(w.b.String(), "\n"); got != accepted {
+				t.Errorf("%s: printed %d line(s); want %d", tt.name, got, accepted)
+			}
+			if dropped < tt.minDropped || dropped > tt.maxDropped {
+				t.Errorf("%s: dropped = %d; want between %d and %d", tt.name, dropped, tt.minDropped, tt.maxDropped)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package iot
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/eclipse/paho.golang/paho"
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// CheckBroker connects to the broker of the .env file at path and disconnects, returning the time the connection took.
+// It is used by `misctl doctor`, so unlike the iot commands it returns its errors instead of exiting.
+func CheckBroker(ctx context.Context, path string) (time.Duration, error) {
+	cs, err := readConnectionSettings(path)
+	if err != nil {
+		return 0, err
+	}
+
+	start := time.Now()
+	if cs.ProtocolVersion == protocolVersion311 {
+		c := mqtt.NewClient(newLegacyClientOptions(ctx, cs))
+		if err := waitToken(ctx, c.Connect()); err != nil {
+			return 0, fmt.Errorf("could not connect to %s: %w", brokerAddress(cs), err)
+		}
+		elapsed := time.Since(start)
+		c.Disconnect(0)
+		return elapsed, nil
+	}
+
+	c, _, err := connect(ctx, io.Discard, cs, paho.ClientConfig{PingHandler: newOnDemandPinger()})
+	if err != nil {
+		return 0, fmt.Errorf("could not connect: %w", err)
+	}
+	elapsed := time.Since(start)
+	if err := c.Disconnect(&paho.Disconnect{ReasonCode: 0}); err != nil {
+		return elapsed, fmt.Errorf("could not disconnect: %w", err)
+	}
+	return elapsed, nil
+}
@@ -0,0 +1,86 @@
+package iot
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/eclipse/paho.golang/paho"
+)
+
+// printConnectPacket is set by a flag shared by the iot subcommands
+var printConnectPacket bool
+
+// secret returns value if showSecrets is set, or a placeholder giving only its length
+func secret(value []byte, showSecrets bool) string {
+	if showSecrets {
+		return string(value)
+	}
+	return fmt.Sprintf("<redacted, %d byte(s)>", len(value))
+}
+
+// optional formats a pointer property, unset properties are printed as such
+func optional[T any](v *T) string {
+	if v == nil {
+		return "unset"
+	}
+	return fmt.Sprint(*v)
+}
+
+// writeConnectPacket prints the fields of the CONNECT packet, the password and the
+// authentication data are redacted unless showSecrets is set.
+func writeConnectPacket(out io.Writer, cp *paho.Connect, showSecrets bool) {
+	fmt.Fprintln(out, "CONNECT packet")
+	fmt.Fprintf(out, "  Client ID: %q\n", cp.ClientID)
+	fmt.Fprintf(out, "  Keep alive: %ds\n", cp.KeepAlive)
+	fmt.Fprintf(out, "  Clean start: %t\n", cp.CleanStart)
+	fmt.Fprintf(out, "  Username flag: %t\n", cp.UsernameFlag)
+	if cp.UsernameFlag {
+		fmt.Fprintf(out, "  Username: %q\n", cp.Username)
+	}
+	fmt.Fprintf(out, "  Password flag: %t\n", cp.PasswordFlag)
+	if cp.PasswordFlag {
+		fmt.Fprintf(out, "  Password: %s\n", secret(cp.Password, showSecrets))
+	}
+
+	if p := cp.Properties; p != nil {
+		fmt.Fprintln(out, "  Properties:")
+		if p.AuthMethod != "" {
+			fmt.Fprintf(out, "    Auth method: %s\n", p.AuthMethod)
+			fmt.Fprintf(out, "    Auth data: %s\n", secret(p.AuthData, showSecrets))
+		}
+		fmt.Fprintf(out, "    Session expiry interval: %s\n", optional(p.SessionExpiryInterval))
+		fmt.Fprintf(out, "    Receive maximum: %s\n", optional(p.ReceiveMaximum))
+		fmt.Fprintf(out, "    Topic alias maximum: %s\n", optional(p.TopicAliasMaximum))
+		fmt.Fprintf(out, "    Maximum packet size: %s\n", optional(p.MaximumPacketSize))
+		fmt.Fprintf(out, "    Request problem info: %t\n", p.RequestProblemInfo)
+		fmt.Fprintf(out, "    Request response info: %t\n", p.RequestResponseInfo)
+		for _, u := range p.User {
+			fmt.Fprintf(out, "    User property: %s=%s\n", u.Key, u.Value)
+		}
+	}
+
+	if w := cp.WillMessage; w != nil {
+		fmt.Fprintln(out, "  Will:")
+		fmt.Fprintf(out, "    Topic: %s\n", w.Topic)
+		fmt.Fprintf(out, "    QoS: %d\n", w.QoS)
+		fmt.Fprintf(out, "    Retain: %t\n", w.Retain)
+		fmt.Fprintf(out, "    Payload: %s\n", w.Payload)
+		if wp := cp.WillProperties; wp != nil {
+			fmt.Fprintf(out, "    Delay interval: %s\n", optional(wp.WillDelayInterval))
+			fmt.Fprintf(out, "    Message expiry: %s\n", optional(wp.MessageExpiry))
+			if wp.ContentType != "" {
+				fmt.Fprintf(out, "    Content type: %s\n", wp.ContentType)
+			}
+			if wp.ResponseTopic != "" {
+				fmt.Fprintf(out, "    Response topic: %s\n", wp.ResponseTopic)
+			}
+			for _, u := range wp.User {
+				fmt.Fprintf(out, "    User property: %s=%s\n", u.Key, u.Value)
+			}
+		}
+	}
+}
+
+func init() {
+	iotCmd.PersistentFlags().BoolVar(&printConnectPacket, "print-connect-packet", false, "Print the CONNECT packet fields before dialing, with the password and auth data redacted unless --show-secrets is set")
+}
@@ -0,0 +1,65 @@
+package iot
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/eclipse/paho.golang/paho"
+)
+
+func TestWriteConnectPacket(t *testing.T) {
+	expiry := uint32(60)
+	cp := &paho.Connect{
+		ClientID:     "device1",
+		KeepAlive:    30,
+		CleanStart:   true,
+		Username:     "user",
+		UsernameFlag: true,
+		Password:     []byte("s3cret"),
+		PasswordFlag: true,
+		Properties: &paho.ConnectProperties{
+			AuthMethod:            "SCRAM-SHA-256",
+			AuthData:              []byte("client-first"),
+			SessionExpiryInterval: &expiry,
+		},
+		WillMessage: &paho.WillMessage{Topic: "status", QoS: 1, Payload: []byte("offline")},
+	}
+
+	// Table Driven Test
+	tests := []struct {
+		name        string
+		showSecrets bool
+		want        []string
+		notWant     []string
+	}{
+		{
+			name:    "redacted case",
+			want:    []string{`Client ID: "device1"`, `Username: "user"`, "Password: <redacted, 6 byte(s)>", "Auth data: <redacted, 12 byte(s)>", "Session expiry interval: 60", "Receive maximum: unset", "Payload: offline"},
+			notWant: []string{"s3cret", "client-first"},
+		},
+		{
+			name:        "show secrets case",
+			showSecrets: true,
+			want:        []string{"Password: s3cret", "Auth data: client-first"},
+			notWant:     []string{"redacted"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			writeConnectPacket(&buf, cp, tt.showSecrets)
+			got := buf.String()
+			for _, w := range tt.want {
+				if !strings.Contains(got, w) {
+					t.Errorf("%s: writeConnectPacket output does not contain %q:\n%s", tt.name, w, got)
+				}
+			}
+			for _, w := range tt.notWant {
+				if strings.Contains(got, w) {
+					t.Errorf("%s: writeConnectPacket output contains %q:\n%s", tt.name, w, got)
+				}
+			}
+		})
+	}
+}
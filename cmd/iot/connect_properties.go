@@ -0,0 +1,84 @@
+package iot
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/eclipse/paho.golang/paho"
+	"gopkg.in/yaml.v3"
+)
+
+// propertiesFile is the YAML file of CONNECT properties set by a flag shared by the iot subcommands
+var propertiesFile string
+
+// connectPropertiesFile is the YAML representation of the CONNECT properties, unset fields are not sent
+type connectPropertiesFile struct {
+	SessionExpiryInterval *uint32 `yaml:"session_expiry_interval"`
+	ReceiveMaximum        *uint16 `yaml:"receive_maximum"`
+	MaximumPacketSize     *uint32 `yaml:"maximum_packet_size"`
+	TopicAliasMaximum     *uint16 `yaml:"topic_alias_maximum"`
+	RequestProblemInfo    bool    `yaml:"request_problem_info"`
+	RequestResponseInfo   bool    `yaml:"request_response_info"`
+	AuthMethod            string  `yaml:"auth_method"`
+	AuthData              string  `yaml:"auth_data"`
+	// UserProperties is a list rather than a map since a key may be repeated
+	UserProperties []struct {
+		Key   string `yaml:"key"`
+		Value string `yaml:"value"`
+	} `yaml:"user_properties"`
+}
+
+// parseConnectProperties parses the YAML CONNECT properties. Unknown keys are errors so that typos are not silently ignored.
+func parseConnectProperties(r io.Reader) (*paho.ConnectProperties, error) {
+	var f connectPropertiesFile
+	decoder := yaml.NewDecoder(r)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&f); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if f.ReceiveMaximum != nil && *f.ReceiveMaximum == 0 {
+		return nil, errors.New("receive_maximum must not be 0")
+	}
+	if f.MaximumPacketSize != nil && *f.MaximumPacketSize == 0 {
+		return nil, errors.New("maximum_packet_size must not be 0")
+	}
+	p := &paho.ConnectProperties{
+		SessionExpiryInterval: f.SessionExpiryInterval,
+		ReceiveMaximum:        f.ReceiveMaximum,
+		MaximumPacketSize:     f.MaximumPacketSize,
+		TopicAliasMaximum:     f.TopicAliasMaximum,
+		RequestProblemInfo:    f.RequestProblemInfo,
+		RequestResponseInfo:   f.RequestResponseInfo,
+		AuthMethod:            f.AuthMethod,
+	}
+	if f.AuthData != "" {
+		p.AuthData = []byte(f.AuthData)
+	}
+	for _, u := range f.UserProperties {
+		if u.Key == "" {
+			return nil, errors.New("user property without key")
+		}
+		p.User.Add(u.Key, u.Value)
+	}
+	return p, nil
+}
+
+// loadConnectProperties reads the CONNECT properties of the file at path
+func loadConnectProperties(path string) (*paho.ConnectProperties, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p, err := parseConnectProperties(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid properties file %s: %w", path, err)
+	}
+	return p, nil
+}
+
+func init() {
+	iotCmd.PersistentFlags().StringVar(&propertiesFile, "properties-file", "", "YAML file of CONNECT properties (session_expiry_interval, receive_maximum, maximum_packet_size, topic_alias_maximum, request_problem_info, request_response_info, auth_method, auth_data, user_properties), --auth-method and --auth-data override its auth_method and auth_data when set")
+}
@@ -0,0 +1,108 @@
+package iot
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/eclipse/paho.golang/paho"
+)
+
+func TestParseConnectProperties(t *testing.T) {
+	sessionExpiry := uint32(3600)
+	receiveMaximum := uint16(10)
+	maximumPacketSize := uint32(65536)
+	topicAliasMaximum := uint16(5)
+
+	// Table Driven Test
+	tests := []struct {
+		name    string
+		data    string
+		want    *paho.ConnectProperties
+		wantErr bool
+	}{
+		{name: "empty case", data: "", want: &paho.ConnectProperties{}},
+		{
+			name: "normal case",
+			data: `session_expiry_interval: 3600
+receive_maximum: 10
+maximum_packet_size: 65536
+topic_alias_maximum: 5
+request_problem_info: true
+user_properties:
+  - key: site
+    value: tokyo
+  - key: site
+    value: osaka
+`,
+			want: &paho.ConnectProperties{
+				SessionExpiryInterval: &sessionExpiry,
+				ReceiveMaximum:        &receiveMaximum,
+				MaximumPacketSize:     &maximumPacketSize,
+				TopicAliasMaximum:     &topicAliasMaximum,
+				RequestProblemInfo:    true,
+				User:                  paho.UserProperties{{Key: "site", Value: "tokyo"}, {Key: "site", Value: "osaka"}},
+			},
+		},
+		{name: "auth case", data: "auth_method: SCRAM-SHA-256\nauth_data: n,,n=user\n", want: &paho.ConnectProperties{AuthMethod: "SCRAM-SHA-256", AuthData: []byte("n,,n=user")}},
+		{name: "unknown key case", data: "session_expiry: 3600\n", wantErr: true},
+		{name: "out of range case", data: "receive_maximum: 70000\n", wantErr: true},
+		{name: "zero receive maximum case", data: "receive_maximum: 0\n", wantErr: true},
+		{name: "zero maximum packet size case", data: "maximum_packet_size: 0\n", wantErr: true},
+		{name: "user property without key case", data: "user_properties:\n  - value: tokyo\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseConnectProperties(strings.NewReader(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("%s: parseConnectProperties() error = %v; want error %t", tt.name, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s: parseConnectProperties() = %+v; want %+v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewConnectPacketAuthOverridesProperties(t *testing.T) {
+	defer func(method, data string) { authMethod, authData = method, data }(authMethod, authData)
+	authMethod, authData = "PLAIN", "secret"
+
+	sessionExpiry := uint32(60)
+	props := &paho.ConnectProperties{SessionExpiryInterval: &sessionExpiry, AuthMethod: "SCRAM-SHA-256"}
+	cp := newConnectPacket(mqttConnectionSettings{ClientId: "c1"}, props)
+	if cp.Properties.AuthMethod != "PLAIN" || string(cp.Properties.AuthData) != "secret" {
+		t.Errorf("newConnectPacket() auth = %s/%s; want PLAIN/secret", cp.Properties.AuthMethod, cp.Properties.AuthData)
+	}
+	if cp.Properties.SessionExpiryInterval == nil || *cp.Properties.SessionExpiryInterval != 60 {
+		t.Errorf("newConnectPacket() session expiry = %v; want 60", cp.Properties.SessionExpiryInterval)
+	}
+}
+
+func TestNewConnectPacketAuthData(t *testing.T) {
+	defer func(method, data string) { authMethod, authData = method, data }(authMethod, authData)
+
+	// Table Driven Test
+	tests := []struct {
+		name       string
+		method     string
+		data       string
+		wantMethod string
+		wantData   string
+	}{
+		{name: "properties case", wantMethod: "SCRAM-SHA-256", wantData: "from-file"},
+		{name: "method only case", method: "PLAIN", wantMethod: "PLAIN", wantData: "from-file"},
+		{name: "data only case", data: "from-flag", wantMethod: "SCRAM-SHA-256", wantData: "from-flag"},
+		{name: "both case", method: "PLAIN", data: "from-flag", wantMethod: "PLAIN", wantData: "from-flag"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authMethod, authData = tt.method, tt.data
+			props := &paho.ConnectProperties{AuthMethod: "SCRAM-SHA-256", AuthData: []byte("from-file")}
+			cp := newConnectPacket(mqttConnectionSettings{ClientId: "c1"}, props)
+			if cp.Properties.AuthMethod != tt.wantMethod || string(cp.Properties.AuthData) != tt.wantData {
+				t.Errorf("%s: newConnectPacket() auth = %s/%s; want %s/%s", tt.name, cp.Properties.AuthMethod, cp.Properties.AuthData, tt.wantMethod, tt.wantData)
+			}
+		})
+	}
+}
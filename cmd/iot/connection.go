@@ -0,0 +1,173 @@
+package iot
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/eclipse/paho.golang/paho"
+	"github.com/ks6088ts-labs/misctl/internal"
+)
+
+// brokerAddress returns the host:port address of the broker.
+func brokerAddress(cs mqttConnectionSettings) string {
+	return net.JoinHostPort(cs.Hostname, strconv.Itoa(cs.TcpPort))
+}
+
+// dialAddress returns the address actually dialed, which uses the MQTT_RESOLVE override of the host name if any.
+func dialAddress(cs mqttConnectionSettings) string {
+	if ip, ok := cs.Resolve[cs.Hostname]; ok {
+		return net.JoinHostPort(ip, strconv.Itoa(cs.TcpPort))
+	}
+	return brokerAddress(cs)
+}
+
+// parseResolveOverrides parses a comma-separated list of host:ip overrides.
+func parseResolveOverrides(value string) (map[string]string, error) {
+	overrides := map[string]string{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		// The host cannot contain a colon while an IPv6 address does
+		host, ip, ok := strings.Cut(entry, ":")
+		if !ok || host == "" {
+			return nil, fmt.Errorf("invalid override %q, must be host:ip", entry)
+		}
+		if net.ParseIP(ip) == nil {
+			return nil, fmt.Errorf("invalid IP address %q for %s", ip, host)
+		}
+		overrides[host] = ip
+	}
+	return overrides, nil
+}
+
+// newNetDialer returns the dialer of the broker connections with the `--tcp-keepalive` period.
+// TCP keepalive probes are sent by the OS on an idle connection, independently of the MQTT keepalive
+// PINGREQs, so a half-open connection can be detected even with a long or disabled MQTT keepalive.
+func newNetDialer() *net.Dialer {
+	return &net.Dialer{KeepAlive: tcpKeepAlive}
+}
+
+// dial opens the network connection to the broker, over TLS if enabled.
+func dial(ctx context.Context, cs mqttConnectionSettings) (net.Conn, error) {
+	if cs.UseTls {
+		return getTlsConnection(ctx, cs)
+	}
+	return newNetDialer().DialContext(ctx, "tcp", dialAddress(cs))
+}
+
+// newConnectPacket builds the CONNECT packet from the connection settings.
+// props, if any, come from --properties-file and the --auth-* flags take precedence over them.
+func newConnectPacket(cs mqttConnectionSettings, props *paho.ConnectProperties) *paho.Connect {
+	cp := &paho.Connect{
+		KeepAlive:  cs.KeepAlive,
+		ClientID:   cs.ClientId,
+		CleanStart: cs.CleanSession,
+		Properties: props,
+	}
+
+	if cs.Username != "" {
+		cp.Username = cs.Username
+		cp.UsernameFlag = true
+	}
+
+	if cs.Password != "" {
+		cp.Password = []byte(cs.Password)
+		cp.PasswordFlag = true
+	}
+
+	// --auth-method and --auth-data each override the auth_method and auth_data of --properties-file, only when set
+	if authMethod != "" || authData != "" {
+		if cp.Properties == nil {
+			cp.Properties = &paho.ConnectProperties{}
+		}
+		if authMethod != "" {
+			cp.Properties.AuthMethod = authMethod
+		}
+		if authData != "" {
+			cp.Properties.AuthData = []byte(authData)
+		}
+	}
+
+	return cp
+}
+
+// connectErrorCode is the exit code of an error returned by connect: configuration errors keep their code,
+// the others are connection errors
+func connectErrorCode(err error) internal.ErrorCode {
+	var coded *internal.Error
+	if errors.As(err, &coded) {
+		return coded.Code
+	}
+	return internal.CodeConnection
+}
+
+// serverDisconnectError describes a DISCONNECT sent by the broker, with its reason string if any
+func serverDisconnectError(d *paho.Disconnect) error {
+	if d.Properties != nil && d.Properties.ReasonString != "" {
+		return internal.Errorf(internal.CodeConnection, "server requested disconnect; reason code: %d, %s", d.ReasonCode, d.Properties.ReasonString)
+	}
+	return internal.Errorf(internal.CodeConnection, "server requested disconnect; reason code: %d", d.ReasonCode)
+}
+
+// connect dials the broker, creates a Paho client with the given config and sends CONNECT.
+// The CONNACK is returned alongside the client so that callers can honour the server properties.
+func connect(ctx context.Context, out io.Writer, cs mqttConnectionSettings, cfg paho.ClientConfig) (*paho.Client, *paho.Connack, error) {
+	// Properties, topic aliases and enhanced authentication only exist in MQTT v5
+	if cs.ProtocolVersion == protocolVersion311 {
+		return nil, nil, internal.Errorf(internal.CodeConfig, "MQTT_PROTOCOL_VERSION %s is only supported by `iot sandbox`, this command requires MQTT v5", protocolVersion311)
+	}
+	var props *paho.ConnectProperties
+	if propertiesFile != "" {
+		p, err := loadConnectProperties(propertiesFile)
+		if err != nil {
+			return nil, nil, internal.Errorf(internal.CodeConfig, "could not load CONNECT properties: %w", err)
+		}
+		props = p
+	}
+	cp := newConnectPacket(cs, props)
+	if printConnectPacket {
+		writeConnectPacket(out, cp, !internal.DefaultRedactor.Enabled())
+	}
+
+	conn, err := dial(ctx, cs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not dial %s: %w", brokerAddress(cs), err)
+	}
+	if tc, ok := conn.(*tls.Conn); ok && tlsSessionResumption != nil {
+		tlsSessionResumption.observe(internal.Decorative(out), tc.ConnectionState())
+	}
+	if dumpTLSInfo {
+		if tc, ok := conn.(*tls.Conn); ok {
+			internal.PrintTLSConnectionState(out, tc.ConnectionState())
+		} else {
+			fmt.Fprintln(out, "TLS is not in use, nothing to dump")
+		}
+	}
+	if cp.Properties != nil && cp.Properties.AuthMethod != "" && authCommand != "" && cfg.AuthHandler == nil {
+		cfg.AuthHandler = &commandAuther{method: cp.Properties.AuthMethod, command: authCommand, out: internal.Decorative(out)}
+	}
+	cfg.Conn = conn
+	c := paho.NewClient(cfg)
+
+	if address := dialAddress(cs); address != brokerAddress(cs) {
+		fmt.Fprintf(internal.Decorative(out), "Attempting to connect to %s via %s\n", brokerAddress(cs), address)
+	} else {
+		fmt.Fprintf(internal.Decorative(out), "Attempting to connect to %s\n", brokerAddress(cs))
+	}
+	ca, err := c.Connect(ctx, cp)
+	if err != nil {
+		return nil, nil, err
+	}
+	if ca.ReasonCode != 0 {
+		return nil, nil, fmt.Errorf("failed to connect to %s : %d - %s", cs.Hostname, ca.ReasonCode, ca.Properties.ReasonString)
+	}
+	return c, ca, nil
+}
@@ -0,0 +1,92 @@
+package iot
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// iotHubAPIVersion is the API version sent in the username when connecting to Azure IoT Hub
+	iotHubAPIVersion = "2021-04-12"
+	// sasTokenLifetime is how long the SAS token generated from a connection string is valid
+	sasTokenLifetime = time.Hour
+)
+
+// connectionString holds the components of an Azure-style connection string
+type connectionString struct {
+	HostName        string
+	DeviceId        string
+	SharedAccessKey string
+}
+
+// parseConnectionString parses a connection string like `HostName=...;DeviceId=...;SharedAccessKey=...`
+func parseConnectionString(value string) (connectionString, error) {
+	cs := connectionString{}
+	for _, part := range strings.Split(value, ";") {
+		if part == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(part, "=")
+		if !ok || val == "" {
+			return cs, fmt.Errorf("malformed connection string component %q", part)
+		}
+		switch key {
+		case "HostName":
+			cs.HostName = val
+		case "DeviceId":
+			cs.DeviceId = val
+		case "SharedAccessKey":
+			cs.SharedAccessKey = val
+		}
+	}
+
+	for name, val := range map[string]string{
+		"HostName":        cs.HostName,
+		"DeviceId":        cs.DeviceId,
+		"SharedAccessKey": cs.SharedAccessKey,
+	} {
+		if val == "" {
+			return cs, fmt.Errorf("connection string is missing %s", name)
+		}
+	}
+	return cs, nil
+}
+
+// generateSasToken returns a SAS token signed with the shared access key, valid until expiry
+func generateSasToken(cs connectionString, expiry time.Time) (string, error) {
+	key, err := base64.StdEncoding.DecodeString(cs.SharedAccessKey)
+	if err != nil {
+		return "", fmt.Errorf("could not decode SharedAccessKey: %w", err)
+	}
+
+	resourceURI := url.QueryEscape(fmt.Sprintf("%s/devices/%s", cs.HostName, cs.DeviceId))
+	se := fmt.Sprintf("%d", expiry.Unix())
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(resourceURI + "\n" + se))
+	sig := url.QueryEscape(base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+
+	return fmt.Sprintf("SharedAccessSignature sr=%s&sig=%s&se=%s", resourceURI, sig, se), nil
+}
+
+// applyConnectionString populates the connection settings from a connection string
+func applyConnectionString(ms *mqttConnectionSettings, value string) error {
+	cs, err := parseConnectionString(value)
+	if err != nil {
+		return err
+	}
+	token, err := generateSasToken(cs, time.Now().Add(sasTokenLifetime))
+	if err != nil {
+		return err
+	}
+
+	ms.Hostname = cs.HostName
+	ms.ClientId = cs.DeviceId
+	ms.Username = fmt.Sprintf("%s/%s/?api-version=%s", cs.HostName, cs.DeviceId, iotHubAPIVersion)
+	ms.Password = token
+	return nil
+}
@@ -0,0 +1,53 @@
+package iot
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseConnectionString(t *testing.T) {
+	// Table Driven Test
+	tests := []struct {
+		name    string
+		value   string
+		want    connectionString
+		wantErr bool
+	}{
+		{
+			name:  "nominal case",
+			value: "HostName=hub.azure-devices.net;DeviceId=dev1;SharedAccessKey=c2VjcmV0",
+			want:  connectionString{HostName: "hub.azure-devices.net", DeviceId: "dev1", SharedAccessKey: "c2VjcmV0"},
+		},
+		{
+			name:  "padded key case",
+			value: "HostName=hub.azure-devices.net;DeviceId=dev1;SharedAccessKey=c2VjcmV0MQ==;",
+			want:  connectionString{HostName: "hub.azure-devices.net", DeviceId: "dev1", SharedAccessKey: "c2VjcmV0MQ=="},
+		},
+		{name: "missing component case", value: "HostName=hub.azure-devices.net;DeviceId=dev1", wantErr: true},
+		{name: "malformed component case", value: "HostName=hub.azure-devices.net;DeviceId;SharedAccessKey=c2VjcmV0", wantErr: true},
+		{name: "empty case", value: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseConnectionString(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("%s: parseConnectionString(%q) error = %v; wantErr %t", tt.name, tt.value, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("%s: parseConnectionString(%q) = %+v; want %+v", tt.name, tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateSasToken(t *testing.T) {
+	cs := connectionString{HostName: "hub.azure-devices.net", DeviceId: "dev1", SharedAccessKey: "c2VjcmV0"}
+	got, err := generateSasToken(cs, time.Unix(1700000000, 0))
+	if err != nil {
+		t.Fatalf("generateSasToken returned error: %v", err)
+	}
+	want := "SharedAccessSignature sr=hub.azure-devices.net%2Fdevices%2Fdev1&sig=1B3%2BXY3G8q61balM41o3sUBbAqkr1FDlwRPtTRsaFq0%3D&se=1700000000"
+	if got != want {
+		t.Errorf("generateSasToken = %q; want %q", got, want)
+	}
+}
@@ -0,0 +1,108 @@
+package iot
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/eclipse/paho.golang/paho"
+	"github.com/ks6088ts-labs/misctl/internal"
+)
+
+func TestParseResolveOverrides(t *testing.T) {
+	// Table Driven Test
+	tests := []struct {
+		name    string
+		value   string
+		want    map[string]string
+		wantErr bool
+	}{
+		{name: "empty case", value: "", want: map[string]string{}},
+		{name: "ipv4 case", value: "broker.example.com:10.0.0.5", want: map[string]string{"broker.example.com": "10.0.0.5"}},
+		{name: "ipv6 case", value: "broker.example.com:::1", want: map[string]string{"broker.example.com": "::1"}},
+		{name: "multiple case", value: "a.example.com:10.0.0.1, b.example.com:10.0.0.2", want: map[string]string{"a.example.com": "10.0.0.1", "b.example.com": "10.0.0.2"}},
+		{name: "missing ip case", value: "broker.example.com", wantErr: true},
+		{name: "invalid ip case", value: "broker.example.com:node1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseResolveOverrides(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%s: parseResolveOverrides(%q) error = %v; wantErr %t", tt.name, tt.value, err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s: parseResolveOverrides(%q) = %v; want %v", tt.name, tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDialAddress(t *testing.T) {
+	// Table Driven Test
+	tests := []struct {
+		name string
+		cs   mqttConnectionSettings
+		want string
+	}{
+		{name: "no override case", cs: mqttConnectionSettings{Hostname: "broker", TcpPort: 8883}, want: "broker:8883"},
+		{name: "override case", cs: mqttConnectionSettings{Hostname: "broker", TcpPort: 8883, Resolve: map[string]string{"broker": "10.0.0.5"}}, want: "10.0.0.5:8883"},
+		{name: "ipv6 override case", cs: mqttConnectionSettings{Hostname: "broker", TcpPort: 1883, Resolve: map[string]string{"broker": "::1"}}, want: "[::1]:1883"},
+		{name: "other host case", cs: mqttConnectionSettings{Hostname: "broker", TcpPort: 1883, Resolve: map[string]string{"other": "10.0.0.5"}}, want: "broker:1883"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dialAddress(tt.cs); got != tt.want {
+				t.Errorf("%s: dialAddress() = %s; want %s", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConnectConfigErrors(t *testing.T) {
+	defer func(path string) { propertiesFile = path }(propertiesFile)
+
+	// Table Driven Test
+	tests := []struct {
+		name       string
+		cs         mqttConnectionSettings
+		properties string
+	}{
+		{name: "MQTT 3.1.1 case", cs: mqttConnectionSettings{ProtocolVersion: protocolVersion311}},
+		{name: "missing properties file case", cs: mqttConnectionSettings{ProtocolVersion: protocolVersion5}, properties: filepath.Join(t.TempDir(), "missing.yaml")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			propertiesFile = tt.properties
+			_, _, err := connect(context.Background(), io.Discard, tt.cs, paho.ClientConfig{})
+			if code := connectErrorCode(err); err == nil || code != internal.CodeConfig {
+				t.Errorf("%s: connect() = %v with code %s; want a %s error", tt.name, err, code, internal.CodeConfig)
+			}
+		})
+	}
+}
+
+func TestServerDisconnectError(t *testing.T) {
+	// Table Driven Test
+	tests := []struct {
+		name string
+		d    *paho.Disconnect
+		want string
+	}{
+		{name: "reason code case", d: &paho.Disconnect{ReasonCode: 0x8b}, want: "server requested disconnect; reason code: 139"},
+		{name: "reason string case", d: &paho.Disconnect{ReasonCode: 0x8e, Properties: &paho.DisconnectProperties{ReasonString: "session taken over"}}, want: "server requested disconnect; reason code: 142, session taken over"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := serverDisconnectError(tt.d)
+			if err.Error() != tt.want {
+				t.Errorf("%s: serverDisconnectError() = %q; want %q", tt.name, err, tt.want)
+			}
+			if code := connectErrorCode(err); code != internal.CodeConnection {
+				t.Errorf("%s: serverDisconnectError() code = %v; want %v", tt.name, code, internal.CodeConnection)
+			}
+		})
+	}
+}
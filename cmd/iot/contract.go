@@ -0,0 +1,247 @@
+package iot
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultContractTimeout is the time allowed for the messages of a topic when the contract sets none
+const defaultContractTimeout = 10 * time.Second
+
+// maxContractViolations is the number of violations kept per topic for the report
+const maxContractViolations = 5
+
+// contract is the expected behaviour of the topics of a broker, read from a YAML file
+type contract struct {
+	// Timeout applies to the topics without their own timeout
+	Timeout time.Duration   `yaml:"timeout"`
+	Topics  []topicContract `yaml:"topics"`
+}
+
+// topicContract holds the assertions on the messages received on a topic filter
+type topicContract struct {
+	Topic string `yaml:"topic"`
+	// Timeout is the window, from the subscription, in which MinMessages must arrive
+	Timeout     time.Duration `yaml:"timeout"`
+	MinMessages int           `yaml:"min_messages"`
+	// PayloadPattern is a regular expression every payload must match
+	PayloadPattern string `yaml:"payload_pattern"`
+	// Fields are the required top-level JSON fields of the payload and their type:
+	// string, number, boolean, object, array, null or any
+	Fields map[string]string `yaml:"fields"`
+	// Properties are the required MQTT v5 properties, user properties or content_type, response_topic, correlation_data
+	Properties []string `yaml:"properties"`
+
+	pattern *regexp.Regexp
+}
+
+// jsonFieldTypes are the types accepted in the fields of a topic contract
+var jsonFieldTypes = map[string]bool{"string": true, "number": true, "boolean": true, "object": true, "array": true, "null": true, "any": true}
+
+// parseContract parses and validates a YAML contract, filling in the defaults. Unknown keys are errors so that typos are not silently ignored.
+func parseContract(data []byte) (contract, error) {
+	var c contract
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&c); err != nil && !errors.Is(err, io.EOF) {
+		return c, err
+	}
+	if len(c.Topics) == 0 {
+		return c, errors.New("no topics in contract")
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = defaultContractTimeout
+	}
+	for i := range c.Topics {
+		t := &c.Topics[i]
+		if t.Topic == "" {
+			return c, fmt.Errorf("topic #%d: missing topic", i+1)
+		}
+		if t.Timeout <= 0 {
+			t.Timeout = c.Timeout
+		}
+		if t.MinMessages <= 0 {
+			t.MinMessages = 1
+		}
+		if t.PayloadPattern != "" {
+			pattern, err := regexp.Compile(t.PayloadPattern)
+			if err != nil {
+				return c, fmt.Errorf("topic %s: invalid payload_pattern: %w", t.Topic, err)
+			}
+			t.pattern = pattern
+		}
+		for name, typ := range t.Fields {
+			if !jsonFieldTypes[typ] {
+				return c, fmt.Errorf("topic %s: invalid type %q of field %s", t.Topic, typ, name)
+			}
+		}
+	}
+	return c, nil
+}
+
+// loadContract reads the contract file at path
+func loadContract(path string) (contract, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return contract{}, err
+	}
+	return parseContract(data)
+}
+
+// topicMatches reports whether the topic matches the MQTT topic filter, with + and # wildcards
+func topicMatches(filter, topic string) bool {
+	filterLevels := strings.Split(filter, "/")
+	topicLevels := strings.Split(topic, "/")
+	for i, level := range filterLevels {
+		if level == "#" {
+			return true
+		}
+		if i >= len(topicLevels) || (level != "+" && level != topicLevels[i]) {
+			return false
+		}
+	}
+	return len(filterLevels) == len(topicLevels)
+}
+
+// jsonType returns the contract type name of a decoded JSON value
+func jsonType(v any) string {
+	switch v.(type) {
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	default:
+		return "null"
+	}
+}
+
+// violations returns why the message breaks the topic contract, nil when it complies
+func (t topicContract) violations(payload []byte, props map[string]string) []string {
+	var v []string
+	if t.pattern != nil && !t.pattern.Match(payload) {
+		v = append(v, fmt.Sprintf("payload does not match %q", t.PayloadPattern))
+	}
+	if len(t.Fields) > 0 {
+		var fields map[string]any
+		if err := json.Unmarshal(payload, &fields); err != nil {
+			v = append(v, "payload is not a JSON object")
+		} else {
+			names := make([]string, 0, len(t.Fields))
+			for name := range t.Fields {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				value, ok := fields[name]
+				want := t.Fields[name]
+				if !ok {
+					v = append(v, fmt.Sprintf("missing field %s", name))
+				} else if got := jsonType(value); want != "any" && got != want {
+					v = append(v, fmt.Sprintf("field %s is %s, want %s", name, got, want))
+				}
+			}
+		}
+	}
+	for _, name := range t.Properties {
+		if _, ok := props[name]; !ok {
+			v = append(v, fmt.Sprintf("missing property %s", name))
+		}
+	}
+	return v
+}
+
+// topicResult is the outcome of a topic contract
+type topicResult struct {
+	Topic       string   `json:"topic"`
+	Passed      bool     `json:"passed"`
+	Received    int      `json:"received"`
+	Late        int      `json:"late"`
+	MinMessages int      `json:"min_messages"`
+	FirstMs     *float64 `json:"first_ms,omitempty"`
+	Violations  []string `json:"violations,omitempty"`
+}
+
+// contractChecker records the messages against the contract while they arrive
+type contractChecker struct {
+	mu      sync.Mutex
+	topics  []topicContract
+	start   time.Time
+	results []topicResult
+}
+
+func newContractChecker(c contract, start time.Time) *contractChecker {
+	results := make([]topicResult, len(c.Topics))
+	for i, t := range c.Topics {
+		results[i] = topicResult{Topic: t.Topic, MinMessages: t.MinMessages}
+	}
+	return &contractChecker{topics: c.Topics, start: start, results: results}
+}
+
+// observe checks a message against every topic contract matching its topic.
+// Messages after the timeout of a contract are counted as late and do not count towards min_messages.
+func (c *contractChecker) observe(topic string, payload []byte, props map[string]string, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elapsed := now.Sub(c.start)
+	for i, t := range c.topics {
+		if !topicMatches(t.Topic, topic) {
+			continue
+		}
+		r := &c.results[i]
+		if elapsed > t.Timeout {
+			r.Late++
+			continue
+		}
+		r.Received++
+		if r.FirstMs == nil {
+			ms := durationMs(elapsed)
+			r.FirstMs = &ms
+		}
+		for _, v := range t.violations(payload, props) {
+			if len(r.Violations) < maxContractViolations {
+				r.Violations = append(r.Violations, fmt.Sprintf("%s: %s", topic, v))
+			}
+		}
+	}
+}
+
+// satisfied reports whether every topic received its minimum number of messages
+func (c *contractChecker) satisfied() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, r := range c.results {
+		if r.Received < r.MinMessages {
+			return false
+		}
+	}
+	return true
+}
+
+// report returns the results, a topic passes with enough messages in time and no violation
+func (c *contractChecker) report() []topicResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	results := make([]topicResult, len(c.results))
+	for i, r := range c.results {
+		r.Passed = r.Received >= r.MinMessages && len(r.Violations) == 0
+		results[i] = r
+	}
+	return results
+}
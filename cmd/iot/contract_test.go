@@ -0,0 +1,151 @@
+package iot
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseContract(t *testing.T) {
+	// Table Driven Test
+	tests := []struct {
+		name    string
+		data    string
+		want    []topicContract
+		wantErr bool
+	}{
+		{
+			name: "defaults case",
+			data: "topics:\n  - topic: a/b\n",
+			want: []topicContract{{Topic: "a/b", Timeout: defaultContractTimeout, MinMessages: 1}},
+		},
+		{
+			name: "explicit case",
+			data: "timeout: 3s\ntopics:\n  - topic: a/+\n    timeout: 1s\n    min_messages: 2\n    fields: {t: number}\n  - topic: c\n",
+			want: []topicContract{
+				{Topic: "a/+", Timeout: time.Second, MinMessages: 2, Fields: map[string]string{"t": "number"}},
+				{Topic: "c", Timeout: 3 * time.Second, MinMessages: 1},
+			},
+		},
+		{name: "no topics case", data: "timeout: 1s\n", wantErr: true},
+		{name: "missing topic case", data: "topics:\n  - min_messages: 1\n", wantErr: true},
+		{name: "invalid pattern case", data: "topics:\n  - topic: a\n    payload_pattern: '('\n", wantErr: true},
+		{name: "invalid type case", data: "topics:\n  - topic: a\n    fields: {t: integer}\n", wantErr: true},
+		{name: "invalid yaml case", data: "topics: [", wantErr: true},
+		{name: "unknown key case", data: "topics:\n  - topic: a\n    min_message: 2\n", wantErr: true},
+		{name: "unknown top-level key case", data: "timout: 1s\ntopics:\n  - topic: a\n", wantErr: true},
+		{name: "empty case", data: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseContract([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("%s: parseContract() error = %v; want error %t", tt.name, err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(got.Topics, tt.want) {
+				t.Errorf("%s: parseContract() = %+v; want %+v", tt.name, got.Topics, tt.want)
+			}
+		})
+	}
+}
+
+func TestTopicMatches(t *testing.T) {
+	// Table Driven Test
+	tests := []struct {
+		name   string
+		filter string
+		topic  string
+		want   bool
+	}{
+		{name: "exact case", filter: "a/b", topic: "a/b", want: true},
+		{name: "single level case", filter: "a/+", topic: "a/b", want: true},
+		{name: "single level too deep case", filter: "a/+", topic: "a/b/c", want: false},
+		{name: "multi level case", filter: "a/#", topic: "a/b/c", want: true},
+		{name: "root multi level case", filter: "#", topic: "a", want: true},
+		{name: "too short topic case", filter: "a/b/c", topic: "a/b", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := topicMatches(tt.filter, tt.topic); got != tt.want {
+				t.Errorf("%s: topicMatches(%q, %q) = %t; want %t", tt.name, tt.filter, tt.topic, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContractChecker(t *testing.T) {
+	c, err := parseContract([]byte(`
+topics:
+  - topic: devices/+/telemetry
+    timeout: 1s
+    min_messages: 2
+    fields: {temperature: number}
+    properties: [content_type]
+  - topic: devices/+/status
+    timeout: 1s
+    payload_pattern: ^(online|offline)$
+`))
+	if err != nil {
+		t.Fatalf("parseContract() error = %v", err)
+	}
+	type message struct {
+		topic   string
+		payload string
+		props   map[string]string
+		after   time.Duration
+	}
+	json := map[string]string{"content_type": "application/json"}
+
+	// Table Driven Test
+	tests := []struct {
+		name           string
+		messages       []message
+		wantPassed     []bool
+		wantViolations []int
+		wantSatisfied  bool
+	}{
+		{
+			name: "pass case",
+			messages: []message{
+				{topic: "devices/d1/telemetry", payload: `{"temperature": 21.5}`, props: json},
+				{topic: "devices/d2/telemetry", payload: `{"temperature": 20}`, props: json},
+				{topic: "devices/d1/status", payload: "online"},
+			},
+			wantPassed: []bool{true, true}, wantViolations: []int{0, 0}, wantSatisfied: true,
+		},
+		{
+			name: "violations case",
+			messages: []message{
+				{topic: "devices/d1/telemetry", payload: `{"temperature": "hot"}`},
+				{topic: "devices/d1/telemetry", payload: `not json`, props: json},
+				{topic: "devices/d1/status", payload: "rebooting"},
+			},
+			wantPassed: []bool{false, false}, wantViolations: []int{3, 1}, wantSatisfied: true,
+		},
+		{
+			name: "late case",
+			messages: []message{
+				{topic: "devices/d1/telemetry", payload: `{"temperature": 1}`, props: json},
+				{topic: "devices/d1/telemetry", payload: `{"temperature": 2}`, props: json, after: 2 * time.Second},
+			},
+			wantPassed: []bool{false, false}, wantViolations: []int{0, 0}, wantSatisfied: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			checker := newContractChecker(c, start)
+			for _, m := range tt.messages {
+				checker.observe(m.topic, []byte(m.payload), m.props, start.Add(m.after))
+			}
+			if got := checker.satisfied(); got != tt.wantSatisfied {
+				t.Errorf("%s: satisfied() = %t; want %t", tt.name, got, tt.wantSatisfied)
+			}
+			for i, r := range checker.report() {
+				if r.Passed != tt.wantPassed[i] || len(r.Violations) != tt.wantViolations[i] {
+					t.Errorf("%s: report()[%d] = %+v; want passed %t with %d violation(s)", tt.name, i, r, tt.wantPassed[i], tt.wantViolations[i])
+				}
+			}
+		})
+	}
+}
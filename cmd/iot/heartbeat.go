@@ -0,0 +1,70 @@
+package iot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/eclipse/paho.golang/paho"
+)
+
+// heartbeat is a liveness beacon published periodically to a topic
+type heartbeat struct {
+	topic    string
+	interval time.Duration
+}
+
+// parseHeartbeat parses a `<topic>:<interval>` value, e.g. devices/d1/presence:30s.
+// The interval is after the last colon so that the topic may contain colons.
+func parseHeartbeat(value string) (heartbeat, error) {
+	i := strings.LastIndex(value, ":")
+	if i <= 0 {
+		return heartbeat{}, fmt.Errorf("invalid heartbeat %q, must be <topic>:<interval>", value)
+	}
+	interval, err := time.ParseDuration(value[i+1:])
+	if err != nil {
+		return heartbeat{}, fmt.Errorf("invalid heartbeat interval %q: %w", value[i+1:], err)
+	}
+	if interval <= 0 {
+		return heartbeat{}, fmt.Errorf("invalid heartbeat interval %s, must be positive", interval)
+	}
+	return heartbeat{topic: value[:i], interval: interval}, nil
+}
+
+// heartbeatPayload is the payload of each heartbeat
+func heartbeatPayload(now time.Time, seq int) []byte {
+	payload, _ := json.Marshal(struct {
+		Timestamp time.Time `json:"timestamp"`
+		Seq       int       `json:"seq"`
+	}{Timestamp: now.UTC(), Seq: seq})
+	return payload
+}
+
+// run publishes a heartbeat right away then on every interval until ctx is done.
+// The returned channel is closed once the last heartbeat is sent, before the client disconnects.
+func (h heartbeat) run(ctx context.Context, out io.Writer, c *paho.Client, qos byte) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(h.interval)
+		defer ticker.Stop()
+		for seq := 1; ; seq++ {
+			if _, err := c.Publish(ctx, &paho.Publish{
+				Topic:   h.topic,
+				QoS:     qos,
+				Payload: heartbeatPayload(time.Now(), seq),
+			}); err != nil && ctx.Err() == nil {
+				fmt.Fprintf(out, "could not publish heartbeat to %s: %s\n", h.topic, err)
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return done
+}
@@ -0,0 +1,42 @@
+package iot
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseHeartbeat(t *testing.T) {
+	// Table Driven Test
+	tests := []struct {
+		name    string
+		value   string
+		want    heartbeat
+		wantErr bool
+	}{
+		{name: "normal case", value: "devices/d1/presence:30s", want: heartbeat{topic: "devices/d1/presence", interval: 30 * time.Second}},
+		{name: "colon in topic case", value: "urn:d1:5m", want: heartbeat{topic: "urn:d1", interval: 5 * time.Minute}},
+		{name: "missing interval case", value: "presence", wantErr: true},
+		{name: "missing topic case", value: ":10s", wantErr: true},
+		{name: "invalid interval case", value: "presence:often", wantErr: true},
+		{name: "zero interval case", value: "presence:0s", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseHeartbeat(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("%s: parseHeartbeat(%q) error = %v; want error %t", tt.name, tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("%s: parseHeartbeat(%q) = %+v; want %+v", tt.name, tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHeartbeatPayload(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.FixedZone("JST", 9*60*60))
+	want := `{"timestamp":"2024-01-01T18:04:05Z","seq":7}`
+	if got := string(heartbeatPayload(now, 7)); got != want {
+		t.Errorf("heartbeatPayload() = %s; want %s", got, want)
+	}
+}
@@ -0,0 +1,105 @@
+/*
+Copyright © 2024 ks6088ts
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package iot
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/ks6088ts-labs/misctl/internal"
+	"github.com/spf13/cobra"
+)
+
+var mqttSettingDescriptions = map[string]string{
+	"MQTT_CONNECTION_STRING":          "Azure-style connection string (HostName=...;DeviceId=...;SharedAccessKey=...), overrides host name, client ID, username and password",
+	"MQTT_HOST_NAME":                  "Hostname of the MQTT broker",
+	"MQTT_TCP_PORT":                   "TCP port of the MQTT broker",
+	"MQTT_USE_TLS":                    "Connect over TLS",
+	"MQTT_CLEAN_SESSION":              "Start a clean session on connect",
+	"MQTT_KEEP_ALIVE_IN_SECONDS":      "Keep alive interval in seconds",
+	"MQTT_CLIENT_ID":                  "Client identifier",
+	"MQTT_USERNAME":                   "Username used for authentication",
+	"MQTT_PASSWORD":                   "Password used for authentication",
+	"MQTT_CA_FILE":                    "Path to the CA certificate file (PEM)",
+	"MQTT_CERT_FILE":                  "Path to the client certificate file (PEM)",
+	"MQTT_KEY_FILE":                   "Path to the client private key file (PEM)",
+	"MQTT_KEY_FILE_PASSWORD":          "Password of the client private key file (not supported yet)",
+	"MQTT_TLS_CIPHER_SUITES":          "Comma-separated TLS 1.0-1.2 cipher suite names to enable, empty for the Go defaults",
+	"MQTT_TLS_ALLOW_INSECURE_CIPHERS": "Allow cipher suites known to be insecure in MQTT_TLS_CIPHER_SUITES",
+	"MQTT_TLS_REQUIRE_OCSP_STAPLING":  "Fail the connection unless the broker staples a good OCSP response for its certificate",
+	"MQTT_RESOLVE":                    "Comma-separated host:ip overrides dialing the IP while keeping the host name for TLS",
+	"MQTT_PROTOCOL_VERSION":           "MQTT protocol version: 5, or 3.1.1 for legacy brokers (only supported by iot sandbox)",
+}
+
+// renderEnvTemplate returns a commented .env template listing every MQTT setting.
+// Settings with a default value are written with it, the others are left empty.
+func renderEnvTemplate() string {
+	var sb strings.Builder
+	sb.WriteString("# MQTT connection settings for misctl iot commands\n")
+	for _, name := range mqttSettingNames {
+		sb.WriteString("\n")
+		fmt.Fprintf(&sb, "# %s\n", mqttSettingDescriptions[name])
+		if value, ok := defaults[name]; ok {
+			fmt.Fprintf(&sb, "# (default: %s)\n", value)
+		}
+		fmt.Fprintf(&sb, "%s=%s\n", name, defaults[name])
+	}
+	return sb.String()
+}
+
+// initCmd represents the init command
+var initCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Generate a .env template for iot commands",
+	Long:  `This command will write a commented .env template listing all the MQTT connection settings with their defaults.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		out := cmd.OutOrStdout()
+		// Parse flags
+		output, err := cmd.Flags().GetString("output")
+		if err != nil {
+			log.Fatalf("could not get `output` flag: %s", err)
+		}
+		force, err := cmd.Flags().GetBool("force")
+		if err != nil {
+			log.Fatalf("could not get `force` flag: %s", err)
+		}
+
+		if _, err := os.Stat(output); err == nil && !force {
+			internal.Fatalf(internal.CodeUsage, "%s already exists, use --force to overwrite it", output)
+		}
+
+		if err := os.WriteFile(output, []byte(renderEnvTemplate()), 0600); err != nil {
+			internal.Fatalf(internal.CodeRuntime, "could not write %s: %s", output, err)
+		}
+		// Progress text is dropped with --json, init has no JSON output
+		fmt.Fprintf(internal.Decorative(out), "Wrote %s\n", output)
+	},
+}
+
+func init() {
+	iotCmd.AddCommand(initCmd)
+
+	initCmd.Flags().StringP("output", "o", ".env", "Path to the .env file to write")
+	initCmd.Flags().BoolP("force", "f", false, "Overwrite the file if it already exists")
+}
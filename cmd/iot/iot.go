@@ -23,6 +23,7 @@ package iot
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/spf13/cobra"
 )
@@ -33,10 +34,22 @@ var iotCmd = &cobra.Command{
 	Short: "iot commands",
 	Long:  `iot commands`,
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("iot called")
+		fmt.Fprintln(cmd.OutOrStdout(), "iot called")
 	},
 }
 
+// Connection options set by flags shared by the iot subcommands
+var (
+	dumpTLSInfo bool
+	// tcpKeepAlive is the TCP keepalive period, 0 for the Go default and negative to disable it
+	tcpKeepAlive time.Duration
+)
+
+func init() {
+	iotCmd.PersistentFlags().BoolVar(&dumpTLSInfo, "dump-tls-info", false, "Print the negotiated TLS version, cipher suite and broker certificate chain before the MQTT handshake")
+	iotCmd.PersistentFlags().DurationVar(&tcpKeepAlive, "tcp-keepalive", 0, "TCP keepalive period of the broker connection, independent of the MQTT keepalive (MQTT_KEEP_ALIVE_IN_SECONDS); 0 for the Go default (15s), negative to disable")
+}
+
 func GetCommand() *cobra.Command {
 	return iotCmd
 }
@@ -0,0 +1,350 @@
+/*
+Copyright © 2024 ks6088ts
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package iot
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/eclipse/paho.golang/paho"
+	"github.com/ks6088ts-labs/misctl/internal"
+	"github.com/spf13/cobra"
+)
+
+// latencyOptions holds the options of a latency measurement
+type latencyOptions struct {
+	Topic    string
+	QoS      byte
+	Count    int
+	Interval time.Duration
+	Timeout  time.Duration
+}
+
+// latencyStats summarizes round-trip samples
+type latencyStats struct {
+	Min time.Duration
+	Avg time.Duration
+	Max time.Duration
+	P99 time.Duration
+}
+
+// computeLatencyStats returns the min/avg/max/p99 of the samples, which must not be empty
+func computeLatencyStats(samples []time.Duration) latencyStats {
+	sorted := append([]time.Duration(nil), samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var sum time.Duration
+	for _, s := range sorted {
+		sum += s
+	}
+	// Nearest-rank percentile
+	rank := int(math.Ceil(0.99*float64(len(sorted)))) - 1
+	return latencyStats{
+		Min: sorted[0],
+		Avg: sum / time.Duration(len(sorted)),
+		Max: sorted[len(sorted)-1],
+		P99: sorted[rank],
+	}
+}
+
+// measureLatency publishes sequenced messages to a topic it is subscribed to and returns the round-trip of each
+// message which came back within the timeout, along with the number of lost messages.
+func measureLatency(ctx context.Context, out io.Writer, cs mqttConnectionSettings, opts latencyOptions) ([]time.Duration, int, error) {
+	received := make(chan int, opts.Count)
+	c, _, err := connect(ctx, out, cs, paho.ClientConfig{
+		Router: paho.NewSingleHandlerRouter(func(m *paho.Publish) {
+			seq, err := strconv.Atoi(string(m.Payload))
+			if err != nil {
+				return
+			}
+			// Never block the router on unexpected traffic
+			select {
+			case received <- seq:
+			default:
+			}
+		}),
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() {
+		if err := c.Disconnect(&paho.Disconnect{ReasonCode: 0}); err != nil {
+			log.Printf("could not disconnect: %s", err)
+		}
+	}()
+
+	if _, err := c.Subscribe(ctx, &paho.Subscribe{
+		Subscriptions: []paho.SubscribeOptions{
+			{Topic: opts.Topic, QoS: opts.QoS},
+		},
+	}); err != nil {
+		return nil, 0, fmt.Errorf("could not subscribe to topic: %w", err)
+	}
+
+	samples := []time.Duration{}
+	lost := 0
+	for seq := 1; seq <= opts.Count && ctx.Err() == nil; seq++ {
+		start := time.Now()
+		if _, err := c.Publish(ctx, &paho.Publish{
+			Topic:   opts.Topic,
+			QoS:     opts.QoS,
+			Payload: []byte(strconv.Itoa(seq)),
+		}); err != nil {
+			return samples, lost, fmt.Errorf("could not publish message: %w", err)
+		}
+
+		timeout := time.After(opts.Timeout)
+	wait:
+		for {
+			select {
+			case got := <-received:
+				// Late replies of previous messages are ignored
+				if got != seq {
+					continue
+				}
+				rtt := time.Since(start)
+				samples = append(samples, rtt)
+				fmt.Fprintf(out, "seq=%d time=%s\n", seq, rtt)
+				break wait
+			case <-timeout:
+				lost++
+				fmt.Fprintf(out, "seq=%d timed out after %s\n", seq, opts.Timeout)
+				break wait
+			case <-ctx.Done():
+				break wait
+			}
+		}
+
+		if seq < opts.Count {
+			select {
+			case <-time.After(opts.Interval):
+			case <-ctx.Done():
+			}
+		}
+	}
+	return samples, lost, nil
+}
+
+// latencyResult is the outcome of a latency measurement against one target
+type latencyResult struct {
+	Target   string  `json:"target"`
+	Received int     `json:"received"`
+	Lost     int     `json:"lost"`
+	MinMs    float64 `json:"min_ms"`
+	AvgMs    float64 `json:"avg_ms"`
+	MaxMs    float64 `json:"max_ms"`
+	P99Ms    float64 `json:"p99_ms"`
+	Error    string  `json:"error,omitempty"`
+}
+
+// newLatencyResult summarizes the samples measured against a target
+func newLatencyResult(target string, samples []time.Duration, lost int, err error) latencyResult {
+	r := latencyResult{Target: target, Received: len(samples), Lost: lost}
+	if err != nil {
+		r.Error = err.Error()
+	} else if len(samples) == 0 {
+		r.Error = "no round-trip measured"
+	}
+	if len(samples) > 0 {
+		stats := computeLatencyStats(samples)
+		r.MinMs = durationMs(stats.Min)
+		r.AvgMs = durationMs(stats.Avg)
+		r.MaxMs = durationMs(stats.Max)
+		r.P99Ms = durationMs(stats.P99)
+	}
+	return r
+}
+
+// durationMs returns the duration in milliseconds
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// latencyTargetTopic returns the topic of the target at index i, suffixed with the index when several targets are compared
+// so that targets on the same broker do not receive the messages of each other
+func latencyTargetTopic(topic string, i, targets int) string {
+	if targets < 2 {
+		return topic
+	}
+	return fmt.Sprintf("%s/%d", topic, i)
+}
+
+// compareLatency measures the round-trip against every target in parallel, returning the results in target order
+func compareLatency(ctx context.Context, targets []string, settings []mqttConnectionSettings, opts latencyOptions) []latencyResult {
+	results := make([]latencyResult, len(targets))
+	var wg sync.WaitGroup
+	for i := range targets {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			opts := opts
+			opts.Topic = latencyTargetTopic(opts.Topic, i, len(targets))
+			samples, lost, err := measureLatency(ctx, io.Discard, settings[i], opts)
+			results[i] = newLatencyResult(targets[i], samples, lost, err)
+		}(i)
+	}
+	wg.Wait()
+	return results
+}
+
+// printLatencyTable prints the results side by side
+func printLatencyTable(out io.Writer, results []latencyResult) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TARGET\tRECEIVED\tLOST\tMIN (ms)\tAVG (ms)\tMAX (ms)\tP99 (ms)\tERROR")
+	for _, r := range results {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%.1f\t%.1f\t%.1f\t%.1f\t%s\n", r.Target, r.Received, r.Lost, r.MinMs, r.AvgMs, r.MaxMs, r.P99Ms, r.Error)
+	}
+	w.Flush()
+}
+
+// latencyCmd represents the latency command
+var latencyCmd = &cobra.Command{
+	Use:   "latency",
+	Short: "Measure the round-trip latency to the broker",
+	Long: `This command will publish messages to a topic it is also subscribed to and measure the round-trip
+of each message, like ping for MQTT, reporting min/avg/max/p99 at the end.
+
+Repeat --env to measure several brokers in parallel and compare them side by side,
+each target uses its own topic, --topic suffixed with /<index> of its --env starting at 0.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		out := cmd.OutOrStdout()
+		// Parse flags
+		envs, err := cmd.Flags().GetStringArray("env")
+		if err != nil {
+			log.Fatalf("could not get `env` flag: %s", err)
+		}
+		topic, err := cmd.Flags().GetString("topic")
+		if err != nil {
+			log.Fatalf("could not get `topic` flag: %s", err)
+		}
+		qos, err := cmd.Flags().GetUint8("qos")
+		if err != nil {
+			log.Fatalf("could not get `qos` flag: %s", err)
+		}
+		count, err := cmd.Flags().GetInt("count")
+		if err != nil {
+			log.Fatalf("could not get `count` flag: %s", err)
+		}
+		interval, err := cmd.Flags().GetDuration("interval")
+		if err != nil {
+			log.Fatalf("could not get `interval` flag: %s", err)
+		}
+		timeout, err := cmd.Flags().GetDuration("timeout")
+		if err != nil {
+			log.Fatalf("could not get `timeout` flag: %s", err)
+		}
+		format, err := cmd.Flags().GetString("format")
+		if err != nil {
+			log.Fatalf("could not get `format` flag: %s", err)
+		}
+		pretty, err := cmd.Flags().GetBool("pretty")
+		if err != nil {
+			log.Fatalf("could not get `pretty` flag: %s", err)
+		}
+		if count < 1 {
+			internal.Fatalf(internal.CodeUsage, "invalid `count` %d, must be at least 1", count)
+		}
+		if internal.JSONOutput() {
+			format = "json"
+		}
+		if format != "table" && format != "json" {
+			internal.Fatalf(internal.CodeUsage, "invalid `format` %q, must be table or json", format)
+		}
+		settings := make([]mqttConnectionSettings, len(envs))
+		for i, env := range envs {
+			settings[i] = loadConnectionSettings(env)
+		}
+		opts := latencyOptions{
+			Topic:    topic,
+			QoS:      qos,
+			Count:    count,
+			Interval: interval,
+			Timeout:  timeout,
+		}
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		// Several targets or JSON output only print the summary
+		if len(envs) > 1 || format == "json" {
+			results := compareLatency(ctx, envs, settings, opts)
+			if format == "json" {
+				data, err := internal.MarshalJSON(results, pretty)
+				if err != nil {
+					internal.Fatalf(internal.CodeRuntime, "could not marshal results: %s", err)
+				}
+				fmt.Fprintln(out, string(data))
+			} else {
+				printLatencyTable(out, results)
+			}
+			failed := 0
+			for _, r := range results {
+				if r.Error != "" {
+					failed++
+				}
+			}
+			if failed > 0 {
+				internal.Fatalf(internal.CodeRuntime, "%d of %d target(s) failed", failed, len(results))
+			}
+			return
+		}
+
+		samples, lost, err := measureLatency(ctx, out, settings[0], opts)
+		if err != nil {
+			internal.Fatalf(connectErrorCode(err), "%s", err)
+		}
+
+		fmt.Fprintf(out, "%d message(s) received, %d lost\n", len(samples), lost)
+		if len(samples) == 0 {
+			internal.Fatalf(internal.CodeTimeout, "no round-trip measured")
+		}
+		stats := computeLatencyStats(samples)
+		fmt.Fprintf(out, "round-trip min/avg/max/p99 = %s/%s/%s/%s\n", stats.Min, stats.Avg, stats.Max, stats.P99)
+	},
+}
+
+func init() {
+	iotCmd.AddCommand(latencyCmd)
+
+	latencyCmd.Flags().StringArrayP("env", "e", []string{}, "Path to .env file, repeat to compare several brokers")
+	latencyCmd.Flags().StringP("topic", "t", "sample/latency", "Topic to publish to and subscribe to, suffixed with /<index> of each --env when several are compared")
+	latencyCmd.Flags().Uint8P("qos", "q", 1, "QoS level")
+	latencyCmd.Flags().IntP("count", "c", 10, "Number of messages to send")
+	latencyCmd.Flags().DurationP("interval", "i", time.Second, "Interval between messages")
+	latencyCmd.Flags().Duration("timeout", 5*time.Second, "How long to wait for each message to come back")
+	latencyCmd.Flags().StringP("format", "f", "table", "Output format: table or json")
+
+	if err := latencyCmd.MarkFlagRequired("env"); err != nil {
+		log.Fatalf("could not mark `env` as required: %s", err)
+	}
+}
@@ -0,0 +1,78 @@
+package iot
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestComputeLatencyStats(t *testing.T) {
+	ms := time.Millisecond
+	hundred := make([]time.Duration, 0, 100)
+	for i := 100; i >= 1; i-- {
+		hundred = append(hundred, time.Duration(i)*ms)
+	}
+
+	// Table Driven Test
+	tests := []struct {
+		name    string
+		samples []time.Duration
+		want    latencyStats
+	}{
+		{name: "single sample case", samples: []time.Duration{5 * ms}, want: latencyStats{Min: 5 * ms, Avg: 5 * ms, Max: 5 * ms, P99: 5 * ms}},
+		{name: "unsorted case", samples: []time.Duration{3 * ms, 1 * ms, 2 * ms}, want: latencyStats{Min: 1 * ms, Avg: 2 * ms, Max: 3 * ms, P99: 3 * ms}},
+		{name: "hundred samples case", samples: hundred, want: latencyStats{Min: 1 * ms, Avg: 50500 * time.Microsecond, Max: 100 * ms, P99: 99 * ms}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := computeLatencyStats(tt.samples); got != tt.want {
+				t.Errorf("%s: computeLatencyStats = %+v; want %+v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewLatencyResult(t *testing.T) {
+	ms := time.Millisecond
+
+	// Table Driven Test
+	tests := []struct {
+		name    string
+		samples []time.Duration
+		lost    int
+		err     error
+		want    latencyResult
+	}{
+		{name: "measured case", samples: []time.Duration{1 * ms, 3 * ms}, lost: 1, want: latencyResult{Target: "a.env", Received: 2, Lost: 1, MinMs: 1, AvgMs: 2, MaxMs: 3, P99Ms: 3}},
+		{name: "all lost case", lost: 2, want: latencyResult{Target: "a.env", Lost: 2, Error: "no round-trip measured"}},
+		{name: "error case", err: errors.New("connection refused"), want: latencyResult{Target: "a.env", Error: "connection refused"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newLatencyResult("a.env", tt.samples, tt.lost, tt.err); got != tt.want {
+				t.Errorf("%s: newLatencyResult = %+v; want %+v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLatencyTargetTopic(t *testing.T) {
+	// Table Driven Test
+	tests := []struct {
+		name    string
+		i       int
+		targets int
+		want    string
+	}{
+		{name: "single target case", i: 0, targets: 1, want: "sample/latency"},
+		{name: "first target case", i: 0, targets: 2, want: "sample/latency/0"},
+		{name: "second target case", i: 1, targets: 2, want: "sample/latency/1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := latencyTargetTopic("sample/latency", tt.i, tt.targets); got != tt.want {
+				t.Errorf("%s: latencyTargetTopic() = %q; want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package iot
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/eclipse/paho.golang/paho"
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/ks6088ts-labs/misctl/internal"
+)
+
+// MQTT protocol versions of MQTT_PROTOCOL_VERSION
+const (
+	protocolVersion311 = "3.1.1"
+	protocolVersion5   = "5"
+)
+
+// legacyProtocolVersion is the protocol level of MQTT 3.1.1 in the CONNECT packet
+const legacyProtocolVersion = 4
+
+// newLegacyClientOptions builds the options of an MQTT 3.1.1 client. The connection is opened with dial
+// so that TLS, MQTT_RESOLVE and --tcp-keepalive behave as with the v5 client.
+func newLegacyClientOptions(ctx context.Context, cs mqttConnectionSettings) *mqtt.ClientOptions {
+	scheme := "tcp"
+	if cs.UseTls {
+		scheme = "ssl"
+	}
+	opts := mqtt.NewClientOptions().
+		AddBroker(fmt.Sprintf("%s://%s", scheme, brokerAddress(cs))).
+		SetProtocolVersion(legacyProtocolVersion).
+		SetClientID(cs.ClientId).
+		SetCleanSession(cs.CleanSession).
+		SetKeepAlive(time.Duration(cs.KeepAlive) * time.Second).
+		SetAutoReconnect(false).
+		SetCustomOpenConnectionFn(func(*url.URL, mqtt.ClientOptions) (net.Conn, error) {
+			return dial(ctx, cs)
+		})
+	if cs.Username != "" {
+		opts.SetUsername(cs.Username)
+	}
+	if cs.Password != "" {
+		opts.SetPassword(cs.Password)
+	}
+	return opts
+}
+
+// waitToken waits for the token to complete or for the context to be done
+func waitToken(ctx context.Context, t mqtt.Token) error {
+	select {
+	case <-t.Done():
+		return t.Error()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// checkLegacyFlags rejects the flags of MQTT v5 features, which the 3.1.1 client would silently ignore
+func checkLegacyFlags() error {
+	v5Flags := []struct {
+		name string
+		set  bool
+	}{
+		{"--auth-method", authMethod != ""},
+		{"--auth-data", authData != ""},
+		{"--auth-command", authCommand != ""},
+		{"--properties-file", propertiesFile != ""},
+		{"--print-connect-packet", printConnectPacket},
+	}
+	for _, flag := range v5Flags {
+		if flag.set {
+			return internal.Errorf(internal.CodeConfig, "%s requires MQTT v5, MQTT_PROTOCOL_VERSION is %s", flag.name, protocolVersion311)
+		}
+	}
+	return nil
+}
+
+// runLegacySandbox is the sandbox over MQTT 3.1.1, with the same output as the v5 one
+func runLegacySandbox(ctx context.Context, out io.Writer, cs mqttConnectionSettings, strategy string, topics []string) {
+	if err := checkLegacyFlags(); err != nil {
+		internal.Exit(err)
+	}
+	// Progress text is dropped with --json
+	text := internal.Decorative(out)
+	opts := newLegacyClientOptions(ctx, cs).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			fmt.Fprintf(text, "connection lost: %s\n", err)
+		})
+
+	fmt.Fprintf(text, "Creating Paho client (MQTT %s)\n", protocolVersion311)
+	c := mqtt.NewClient(opts)
+	fmt.Fprintf(text, "Attempting to connect to %s\n", brokerAddress(cs))
+	if err := waitToken(ctx, c.Connect()); err != nil {
+		internal.Fatalf(internal.CodeConnection, "%s", err)
+	}
+	defer c.Disconnect(250)
+
+	fmt.Fprintln(text, "Connection successful")
+	for _, topic := range topics {
+		filter := ""
+		if strategy == routerStandard {
+			filter = topic
+		}
+		handler := func(_ mqtt.Client, m mqtt.Message) {
+			printReceived(out, &paho.Publish{Topic: m.Topic(), QoS: m.Qos(), Retain: m.Retained(), Payload: m.Payload()}, filter)
+		}
+		if err := waitToken(ctx, c.Subscribe(topic, 1, handler)); err != nil {
+			internal.Fatalf(internal.CodeRuntime, "could not subscribe to topic: %s", err)
+		}
+	}
+
+	if err := waitToken(ctx, c.Publish("sample/topic1", 1, false, []byte("hello world"))); err != nil {
+		internal.Fatalf(internal.CodeRuntime, "could not publish message: %s", err)
+	}
+
+	<-ctx.Done() // Wait for user to trigger exit
+	fmt.Fprintf(text, "%s - exiting\n", internal.DoneReason(ctx))
+}
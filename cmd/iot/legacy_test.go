@@ -0,0 +1,87 @@
+package iot
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ks6088ts-labs/misctl/internal"
+)
+
+func TestNewLegacyClientOptions(t *testing.T) {
+	// Table Driven Test
+	tests := []struct {
+		name       string
+		cs         mqttConnectionSettings
+		wantBroker string
+	}{
+		{
+			name:       "tcp case",
+			cs:         mqttConnectionSettings{Hostname: "localhost", TcpPort: 1883, ClientId: "c1", KeepAlive: 30, CleanSession: true},
+			wantBroker: "tcp://localhost:1883",
+		},
+		{
+			name:       "tls case",
+			cs:         mqttConnectionSettings{Hostname: "broker.example.com", TcpPort: 8883, UseTls: true, ClientId: "c1", KeepAlive: 30, Username: "user", Password: "secret"},
+			wantBroker: "ssl://broker.example.com:8883",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := newLegacyClientOptions(context.Background(), tt.cs)
+			if len(opts.Servers) != 1 || opts.Servers[0].String() != tt.wantBroker {
+				t.Errorf("%s: Servers = %v; want %s", tt.name, opts.Servers, tt.wantBroker)
+			}
+			if opts.ProtocolVersion != legacyProtocolVersion {
+				t.Errorf("%s: ProtocolVersion = %d; want %d", tt.name, opts.ProtocolVersion, legacyProtocolVersion)
+			}
+			if opts.ClientID != tt.cs.ClientId || opts.CleanSession != tt.cs.CleanSession {
+				t.Errorf("%s: ClientID, CleanSession = %s, %t; want %s, %t", tt.name, opts.ClientID, opts.CleanSession, tt.cs.ClientId, tt.cs.CleanSession)
+			}
+			if opts.KeepAlive != int64(tt.cs.KeepAlive) {
+				t.Errorf("%s: KeepAlive = %d; want %d", tt.name, opts.KeepAlive, tt.cs.KeepAlive)
+			}
+			if opts.Username != tt.cs.Username || opts.Password != tt.cs.Password {
+				t.Errorf("%s: Username, Password = %s, %s; want %s, %s", tt.name, opts.Username, opts.Password, tt.cs.Username, tt.cs.Password)
+			}
+		})
+	}
+}
+
+func TestCheckLegacyFlags(t *testing.T) {
+	defer func(method, data, command, file string, print bool) {
+		authMethod, authData, authCommand, propertiesFile, printConnectPacket = method, data, command, file, print
+	}(authMethod, authData, authCommand, propertiesFile, printConnectPacket)
+
+	// Table Driven Test
+	tests := []struct {
+		name     string
+		set      func()
+		wantFlag string
+	}{
+		{name: "no flag case", set: func() {}},
+		{name: "auth method case", set: func() { authMethod = "PLAIN" }, wantFlag: "--auth-method"},
+		{name: "auth data case", set: func() { authData = "secret" }, wantFlag: "--auth-data"},
+		{name: "auth command case", set: func() { authCommand = "cat" }, wantFlag: "--auth-command"},
+		{name: "properties file case", set: func() { propertiesFile = "connect.yaml" }, wantFlag: "--properties-file"},
+		{name: "print connect packet case", set: func() { printConnectPacket = true }, wantFlag: "--print-connect-packet"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authMethod, authData, authCommand, propertiesFile, printConnectPacket = "", "", "", "", false
+			tt.set()
+			err := checkLegacyFlags()
+			if tt.wantFlag == "" {
+				if err != nil {
+					t.Errorf("%s: checkLegacyFlags() error = %v; want nil", tt.name, err)
+				}
+				return
+			}
+			var coded *internal.Error
+			if !errors.As(err, &coded) || coded.Code != internal.CodeConfig || !strings.Contains(err.Error(), tt.wantFlag) {
+				t.Errorf("%s: checkLegacyFlags() error = %v; want a config error naming %s", tt.name, err, tt.wantFlag)
+			}
+		})
+	}
+}
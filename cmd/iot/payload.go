@@ -0,0 +1,72 @@
+package iot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// parseHeaders parses "Name: value" headers
+func parseHeaders(headers []string) (http.Header, error) {
+	parsed := http.Header{}
+	for _, header := range headers {
+		name, value, ok := strings.Cut(header, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid header %q, must be Name: value", header)
+		}
+		parsed.Add(name, strings.TrimSpace(value))
+	}
+	return parsed, nil
+}
+
+// fetchPayload downloads the payload from url, responses other than 2xx are errors
+func fetchPayload(ctx context.Context, client *http.Client, url string, headers http.Header) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not create request: %w", err)
+	}
+	req.Header = headers
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch payload: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("could not fetch payload: %s", resp.Status)
+	}
+	payload, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("could not read payload: %w", err)
+	}
+	return payload, nil
+}
+
+// stdinPayload is the --message value reading the payload from stdin
+const stdinPayload = "-"
+
+// messageFlag returns the payload given with --message or its alias --payload, and the flag it was given with
+func messageFlag(message, payload string) (value, flag string, err error) {
+	if message != "" && payload != "" {
+		return "", "", errors.New("--message and --payload are mutually exclusive")
+	}
+	if payload != "" {
+		return payload, "--payload", nil
+	}
+	return message, "--message", nil
+}
+
+// readMessagePayload returns the payload of --message, read from stdin when it is stdinPayload
+func readMessagePayload(message string, stdin io.Reader) ([]byte, error) {
+	if message != stdinPayload {
+		return []byte(message), nil
+	}
+	payload, err := io.ReadAll(stdin)
+	if err != nil {
+		return nil, fmt.Errorf("could not read payload from stdin: %w", err)
+	}
+	return payload, nil
+}
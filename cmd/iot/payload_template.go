@@ -0,0 +1,64 @@
+package iot
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"os"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// payloadTemplateHelp documents the functions of --payload-template
+const payloadTemplateHelp = `Functions of --payload-template:
+  uuid          a random UUID, e.g. 0b5a3e5e-7c1e-4a43-9a4e-3c8f1b2d9e6f
+  now           the current time, e.g. {{now.UTC.Format "2006-01-02T15:04:05Z07:00"}} or {{now.UnixMilli}}
+  randInt a b   a random integer in [a, b)
+  env "VAR"     the value of the environment variable VAR, empty if unset
+  seq           the number of the message in the run, starting at 1`
+
+// payloadTemplate renders a --payload-template for each published message
+type payloadTemplate struct {
+	tmpl *template.Template
+	seq  int
+	// intn is rand.Intn outside of the tests
+	intn func(int) int
+	now  func() time.Time
+}
+
+// newPayloadTemplate parses a --payload-template with the functions of payloadTemplateHelp
+func newPayloadTemplate(text string) (*payloadTemplate, error) {
+	p := &payloadTemplate{intn: rand.Intn, now: time.Now}
+	tmpl, err := template.New("payload").Option("missingkey=error").Funcs(template.FuncMap{
+		"uuid":    func() string { return uuid.NewString() },
+		"now":     func() time.Time { return p.now() },
+		"randInt": p.randInt,
+		"env":     os.Getenv,
+		"seq":     func() int { return p.seq },
+	}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid payload template: %w", err)
+	}
+	p.tmpl = tmpl
+	return p, nil
+}
+
+// randInt returns a random integer in [a, b)
+func (p *payloadTemplate) randInt(a, b int) (int, error) {
+	if b <= a {
+		return 0, fmt.Errorf("randInt: %d must be greater than %d", b, a)
+	}
+	return a + p.intn(b-a), nil
+}
+
+// render renders the payload of the next message
+func (p *payloadTemplate) render() ([]byte, error) {
+	p.seq++
+	var b bytes.Buffer
+	if err := p.tmpl.Execute(&b, nil); err != nil {
+		return nil, fmt.Errorf("could not render payload template: %w", err)
+	}
+	return b.Bytes(), nil
+}
@@ -0,0 +1,68 @@
+package iot
+
+import (
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestPayloadTemplate(t *testing.T) {
+	t.Setenv("PAYLOAD_TEMPLATE_TEST", "device-1")
+
+	// Table Driven Test
+	tests := []struct {
+		name    string
+		text    string
+		want    []string
+		wantErr bool
+	}{
+		{name: "plain case", text: "hello", want: []string{"hello", "hello"}},
+		{name: "seq case", text: `{"seq":{{seq}}}`, want: []string{`{"seq":1}`, `{"seq":2}`}},
+		{name: "now case", text: "{{now.UTC.Format \"2006-01-02T15:04:05Z07:00\"}}", want: []string{"2024-01-02T03:04:05Z"}},
+		{name: "randInt case", text: "{{randInt 10 20}}", want: []string{"13"}},
+		{name: "env case", text: `{{env "PAYLOAD_TEMPLATE_TEST"}}/{{env "PAYLOAD_TEMPLATE_UNSET"}}`, want: []string{"device-1/"}},
+		{name: "empty randInt range case", text: "{{randInt 5 5}}", wantErr: true},
+		{name: "unknown function case", text: "{{rand}}", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := newPayloadTemplate(tt.text)
+			if err == nil {
+				p.intn = func(n int) int { return 3 }
+				p.now = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+				var got []byte
+				for _, want := range tt.want {
+					got, err = p.render()
+					if err == nil && string(got) != want {
+						t.Errorf("%s: render() = %q; want %q", tt.name, got, want)
+					}
+				}
+				if tt.wantErr {
+					_, err = p.render()
+				}
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%s: error = %v; want error %t", tt.name, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPayloadTemplateUUID(t *testing.T) {
+	p, err := newPayloadTemplate("{{uuid}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	first, err := p.render()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := p.render()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pattern.Match(first) || string(first) == string(second) {
+		t.Errorf("render() = %q, %q; want two distinct random UUIDs", first, second)
+	}
+}
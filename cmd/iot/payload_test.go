@@ -0,0 +1,127 @@
+package iot
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseHeaders(t *testing.T) {
+	// Table Driven Test
+	tests := []struct {
+		name    string
+		headers []string
+		want    http.Header
+		wantErr bool
+	}{
+		{name: "empty case", headers: nil, want: http.Header{}},
+		{name: "trimmed case", headers: []string{"Authorization:  Bearer abc ", "accept: application/json"}, want: http.Header{"Authorization": {"Bearer abc"}, "Accept": {"application/json"}}},
+		{name: "value with colon case", headers: []string{"X-Time: 12:00"}, want: http.Header{"X-Time": {"12:00"}}},
+		{name: "missing colon case", headers: []string{"Authorization"}, wantErr: true},
+		{name: "missing name case", headers: []string{": value"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseHeaders(tt.headers)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("%s: parseHeaders(%v) error = %v; want error %t", tt.name, tt.headers, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("%s: parseHeaders(%v) = %v; want %v", tt.name, tt.headers, got, tt.want)
+			}
+			for name := range tt.want {
+				if got.Get(name) != tt.want.Get(name) {
+					t.Errorf("%s: parseHeaders(%v) = %v; want %v", tt.name, tt.headers, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestFetchPayload(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer abc" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"t":21}`))
+	}))
+	defer srv.Close()
+
+	// Table Driven Test
+	tests := []struct {
+		name    string
+		headers http.Header
+		want    string
+		wantErr bool
+	}{
+		{name: "ok case", headers: http.Header{"Authorization": {"Bearer abc"}}, want: `{"t":21}`},
+		{name: "non-2xx case", headers: http.Header{}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := fetchPayload(context.Background(), srv.Client(), srv.URL, tt.headers)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("%s: fetchPayload error = %v; want error %t", tt.name, err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("%s: fetchPayload = %s; want %s", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadMessagePayload(t *testing.T) {
+	// Table Driven Test
+	tests := []struct {
+		name    string
+		message string
+		stdin   string
+		want    string
+	}{
+		{name: "message case", message: "hello", stdin: "ignored", want: "hello"},
+		{name: "empty message case", message: "", stdin: "ignored", want: ""},
+		{name: "stdin case", message: "-", stdin: "{\"t\":21}\n", want: "{\"t\":21}\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readMessagePayload(tt.message, strings.NewReader(tt.stdin))
+			if err != nil || string(got) != tt.want {
+				t.Errorf("%s: readMessagePayload(%q) = %q, %v; want %q", tt.name, tt.message, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestMessageFlag(t *testing.T) {
+	// Table Driven Test
+	tests := []struct {
+		name      string
+		message   string
+		payload   string
+		wantValue string
+		wantFlag  string
+		wantErr   bool
+	}{
+		{name: "none case", wantFlag: "--message"},
+		{name: "message case", message: "hello", wantValue: "hello", wantFlag: "--message"},
+		{name: "payload case", payload: "-", wantValue: "-", wantFlag: "--payload"},
+		{name: "both case", message: "hello", payload: "world", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, flag, err := messageFlag(tt.message, tt.payload)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("%s: messageFlag(%q, %q) error = %v; want error %t", tt.name, tt.message, tt.payload, err, tt.wantErr)
+			}
+			if value != tt.wantValue || flag != tt.wantFlag {
+				t.Errorf("%s: messageFlag(%q, %q) = %q, %q; want %q, %q", tt.name, tt.message, tt.payload, value, flag, tt.wantValue, tt.wantFlag)
+			}
+		})
+	}
+}
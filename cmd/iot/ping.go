@@ -0,0 +1,231 @@
+/*
+Copyright © 2024 ks6088ts
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package iot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/eclipse/paho.golang/packets"
+	"github.com/eclipse/paho.golang/paho"
+	"github.com/ks6088ts-labs/misctl/internal"
+	"github.com/spf13/cobra"
+)
+
+// onDemandPinger is a paho.Pinger which only sends a PINGREQ when asked to
+type onDemandPinger struct {
+	conn     net.Conn
+	started  chan struct{}
+	stopped  chan struct{}
+	resp     chan struct{}
+	stopOnce sync.Once
+}
+
+func newOnDemandPinger() *onDemandPinger {
+	return &onDemandPinger{
+		started: make(chan struct{}),
+		stopped: make(chan struct{}),
+		resp:    make(chan struct{}, 1),
+	}
+}
+
+// Start keeps the connection for ping until the client stops the pinger
+func (p *onDemandPinger) Start(conn net.Conn, _ time.Duration) {
+	p.conn = conn
+	close(p.started)
+	<-p.stopped
+}
+
+func (p *onDemandPinger) Stop() {
+	p.stopOnce.Do(func() { close(p.stopped) })
+}
+
+func (p *onDemandPinger) PingResp() {
+	select {
+	case p.resp <- struct{}{}:
+	default:
+	}
+}
+
+func (p *onDemandPinger) SetDebug(paho.Logger) {}
+
+// ping sends a PINGREQ and waits for the PINGRESP, returning the round-trip
+func (p *onDemandPinger) ping(ctx context.Context) (time.Duration, error) {
+	select {
+	case <-p.started:
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+	start := time.Now()
+	if _, err := packets.NewControlPacket(packets.PINGREQ).WriteTo(p.conn); err != nil {
+		return 0, fmt.Errorf("could not send PINGREQ: %w", err)
+	}
+	select {
+	case <-p.resp:
+		return time.Since(start), nil
+	case <-p.stopped:
+		return 0, errors.New("connection closed before PINGRESP")
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+// pingFailure exits with the timeout code when the deadline was hit, or the code of the connect error otherwise
+func pingFailure(ctx context.Context, format string, err error) {
+	code := connectErrorCode(err)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		code = internal.CodeTimeout
+	}
+	internal.Fatalf(code, format, err)
+}
+
+// pingResult is the JSON output of a successful ping
+type pingResult struct {
+	ConnectMs float64  `json:"connect_ms"`
+	PingMs    *float64 `json:"ping_ms,omitempty"`
+	TotalMs   float64  `json:"total_ms"`
+	// TLSHandshakes is the kind of handshake of each connection of --tls-resumption
+	TLSHandshakes []string `json:"tls_handshakes,omitempty"`
+}
+
+// pingCmd represents the ping command
+var pingCmd = &cobra.Command{
+	Use:   "ping",
+	Short: "Check that the broker accepts a connection",
+	Long: `This command will connect to the broker, optionally send a PINGREQ, and disconnect,
+reporting the timings. It exits with a non-zero code on failure, which suits readiness checks.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		out := cmd.OutOrStdout()
+		// Parse flags
+		env, err := cmd.Flags().GetString("env")
+		if err != nil {
+			log.Fatalf("could not get `env` flag: %s", err)
+		}
+		timeout, err := cmd.Flags().GetDuration("timeout")
+		if err != nil {
+			log.Fatalf("could not get `timeout` flag: %s", err)
+		}
+		sendPing, err := cmd.Flags().GetBool("pingreq")
+		if err != nil {
+			log.Fatalf("could not get `pingreq` flag: %s", err)
+		}
+		resumption, err := cmd.Flags().GetInt("tls-resumption")
+		if err != nil {
+			log.Fatalf("could not get `tls-resumption` flag: %s", err)
+		}
+		pretty, err := cmd.Flags().GetBool("pretty")
+		if err != nil {
+			log.Fatalf("could not get `pretty` flag: %s", err)
+		}
+		if timeout <= 0 {
+			internal.Fatalf(internal.CodeUsage, "invalid `timeout` %s, must be positive", timeout)
+		}
+		if resumption == 1 || resumption < 0 {
+			internal.Fatalf(internal.CodeUsage, "invalid `tls-resumption` %d, must be at least 2 connections", resumption)
+		}
+		cs := loadConnectionSettings(env)
+		if resumption > 0 && !cs.UseTls {
+			internal.Fatalf(internal.CodeUsage, "--tls-resumption requires MQTT_USE_TLS")
+		}
+
+		ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+		defer cancel()
+
+		if resumption > 0 {
+			tlsSessionResumption = newTLSResumption()
+			// The connections before the timed one below fill the session cache
+			for i := 1; i < resumption; i++ {
+				c, _, err := connect(ctx, out, cs, paho.ClientConfig{PingHandler: newOnDemandPinger()})
+				if err != nil {
+					pingFailure(ctx, "could not connect: %s", err)
+				}
+				if err := c.Disconnect(&paho.Disconnect{ReasonCode: 0}); err != nil {
+					pingFailure(ctx, "could not disconnect: %s", err)
+				}
+			}
+		}
+
+		pinger := newOnDemandPinger()
+		start := time.Now()
+		c, _, err := connect(ctx, out, cs, paho.ClientConfig{
+			PingHandler: pinger,
+		})
+		if err != nil {
+			pingFailure(ctx, "could not connect: %s", err)
+		}
+		connected := time.Since(start)
+		result := pingResult{ConnectMs: durationMs(connected)}
+		fmt.Fprintf(internal.Decorative(out), "connected in %s\n", connected)
+
+		if sendPing {
+			rtt, err := pinger.ping(ctx)
+			if err != nil {
+				if err := c.Disconnect(&paho.Disconnect{ReasonCode: 0}); err != nil {
+					log.Printf("could not disconnect: %s", err)
+				}
+				pingFailure(ctx, "ping failed: %s", err)
+			}
+			pingMs := durationMs(rtt)
+			result.PingMs = &pingMs
+			fmt.Fprintf(internal.Decorative(out), "PINGRESP received in %s\n", rtt)
+		}
+
+		if err := c.Disconnect(&paho.Disconnect{ReasonCode: 0}); err != nil {
+			internal.Fatalf(internal.CodeConnection, "could not disconnect: %s", err)
+		}
+		total := time.Since(start)
+		result.TotalMs = durationMs(total)
+		if tlsSessionResumption != nil {
+			result.TLSHandshakes = tlsSessionResumption.handshakes
+		}
+		if internal.JSONOutput() {
+			if err := internal.PrintJSON(out, result, pretty); err != nil {
+				internal.Fatalf(internal.CodeRuntime, "could not print result: %s", err)
+			}
+		} else {
+			fmt.Fprintf(out, "ok in %s\n", total)
+		}
+		if tlsSessionResumption != nil {
+			if n := tlsSessionResumption.notResumed(); n > 0 {
+				internal.Fatalf(internal.CodeRuntime, "the broker did not resume the TLS session on %d of %d reconnection(s)", n, resumption-1)
+			}
+		}
+	},
+}
+
+func init() {
+	iotCmd.AddCommand(pingCmd)
+
+	pingCmd.Flags().StringP("env", "e", "", "Path to .env file")
+	pingCmd.Flags().Duration("timeout", 5*time.Second, "How long the whole check may take")
+	pingCmd.Flags().Bool("pingreq", false, "Send a PINGREQ once connected and wait for the PINGRESP")
+	pingCmd.Flags().Int("tls-resumption", 0, "Connect this many times sharing a TLS session cache, reporting whether each handshake resumed the session, and fail if a reconnection did not; 0 to disable")
+
+	if err := pingCmd.MarkFlagRequired("env"); err != nil {
+		log.Fatalf("could not mark `env` as required: %s", err)
+	}
+}
@@ -0,0 +1,51 @@
+package iot
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/eclipse/paho.golang/packets"
+)
+
+func TestOnDemandPinger(t *testing.T) {
+	// Table Driven Test
+	tests := []struct {
+		name    string
+		respond bool
+		wantErr error
+	}{
+		{name: "pingresp case", respond: true, wantErr: nil},
+		{name: "no pingresp case", respond: false, wantErr: context.DeadlineExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, server := net.Pipe()
+			defer client.Close()
+			defer server.Close()
+
+			p := newOnDemandPinger()
+			go p.Start(client, 0)
+			defer p.Stop()
+
+			go func() {
+				cp, err := packets.ReadPacket(server)
+				if err != nil || cp.Type != packets.PINGREQ {
+					return
+				}
+				if tt.respond {
+					p.PingResp()
+				}
+			}()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			_, err := p.ping(ctx)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("%s: ping() error = %v; want %v", tt.name, err, tt.wantErr)
+			}
+		})
+	}
+}
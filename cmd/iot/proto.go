@@ -0,0 +1,59 @@
+package iot
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// parseProtoSpec parses a `<file.proto>:<MessageType>` value
+func parseProtoSpec(spec string) (file, messageType string, err error) {
+	i := strings.LastIndex(spec, ":")
+	if i <= 0 || i == len(spec)-1 {
+		return "", "", fmt.Errorf("invalid proto %q, must be <file.proto>:<MessageType>", spec)
+	}
+	return spec[:i], spec[i+1:], nil
+}
+
+// compileProtoMessage compiles the schema and returns the descriptor of the message type.
+// Imports are resolved next to the schema, and the well-known types are always available.
+// The type may omit the package of the schema.
+func compileProtoMessage(ctx context.Context, file, messageType string) (protoreflect.MessageDescriptor, error) {
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			ImportPaths: []string{filepath.Dir(file)},
+		}),
+	}
+	files, err := compiler.Compile(ctx, filepath.Base(file))
+	if err != nil {
+		return nil, fmt.Errorf("could not compile %s: %w", file, err)
+	}
+	fd := files[0]
+	name := protoreflect.FullName(messageType)
+	if pkg := fd.Package(); pkg != "" && !strings.HasPrefix(messageType, string(pkg)+".") {
+		name = pkg.Append(protoreflect.Name(messageType))
+	}
+	md, ok := fd.FindDescriptorByName(name).(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("message type %s not found in %s", name, file)
+	}
+	return md, nil
+}
+
+// encodeProtoJSON marshals the JSON representation of a message into its protobuf binary encoding.
+// Unknown fields and mismatched types are errors.
+// The encoding is deterministic so that the same JSON always publishes the same bytes, dynamic messages range over their fields in random order otherwise.
+func encodeProtoJSON(md protoreflect.MessageDescriptor, data []byte) ([]byte, error) {
+	msg := dynamicpb.NewMessage(md)
+	if err := protojson.Unmarshal(data, msg); err != nil {
+		return nil, fmt.Errorf("JSON does not match %s: %w", md.FullName(), err)
+	}
+	return proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+}
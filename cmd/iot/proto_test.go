@@ -0,0 +1,101 @@
+package iot
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testProtoSchema = `syntax = "proto3";
+package telemetry.v1;
+
+import "google/protobuf/timestamp.proto";
+
+message Reading {
+  string device_id = 1;
+  double temperature = 2;
+  google.protobuf.Timestamp time = 3;
+}
+`
+
+func TestParseProtoSpec(t *testing.T) {
+	// Table Driven Test
+	tests := []struct {
+		name        string
+		spec        string
+		wantFile    string
+		wantMessage string
+		wantErr     bool
+	}{
+		{name: "normal case", spec: "schema.proto:Reading", wantFile: "schema.proto", wantMessage: "Reading"},
+		{name: "windows path case", spec: `C:\protos\schema.proto:telemetry.v1.Reading`, wantFile: `C:\protos\schema.proto`, wantMessage: "telemetry.v1.Reading"},
+		{name: "missing type case", spec: "schema.proto", wantErr: true},
+		{name: "empty type case", spec: "schema.proto:", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, message, err := parseProtoSpec(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("%s: parseProtoSpec(%q) error = %v; want error %t", tt.name, tt.spec, err, tt.wantErr)
+			}
+			if file != tt.wantFile || message != tt.wantMessage {
+				t.Errorf("%s: parseProtoSpec(%q) = (%s, %s); want (%s, %s)", tt.name, tt.spec, file, message, tt.wantFile, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestEncodeProtoJSON(t *testing.T) {
+	dir := t.TempDir()
+	schema := filepath.Join(dir, "telemetry.proto")
+	if err := os.WriteFile(schema, []byte(testProtoSchema), 0644); err != nil {
+		t.Fatal(err)
+	}
+	broken := filepath.Join(dir, "broken.proto")
+	if err := os.WriteFile(broken, []byte("syntax = \"proto3\";\nmessage {"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Table Driven Test
+	tests := []struct {
+		name       string
+		file       string
+		message    string
+		json       string
+		want       []byte
+		wantErr    bool
+		wantEncErr bool
+	}{
+		{
+			name: "short name case", file: schema, message: "Reading", json: `{"deviceId": "d1", "temperature": 1.5}`,
+			want: []byte{0x0a, 0x02, 'd', '1', 0x11, 0, 0, 0, 0, 0, 0, 0xf8, 0x3f},
+		},
+		{name: "qualified name case", file: schema, message: "telemetry.v1.Reading", json: `{"device_id": "d1"}`, want: []byte{0x0a, 0x02, 'd', '1'}},
+		{name: "well known type case", file: schema, message: "Reading", json: `{"time": "1970-01-01T00:00:01Z"}`, want: []byte{0x1a, 0x02, 0x08, 0x01}},
+		{name: "unknown field case", file: schema, message: "Reading", json: `{"humidity": 3}`, wantEncErr: true},
+		{name: "wrong type case", file: schema, message: "Reading", json: `{"temperature": "hot"}`, wantEncErr: true},
+		{name: "unknown message case", file: schema, message: "Missing", wantErr: true},
+		{name: "broken schema case", file: broken, message: "Reading", wantErr: true},
+		{name: "missing schema case", file: filepath.Join(dir, "missing.proto"), message: "Reading", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			md, err := compileProtoMessage(context.Background(), tt.file, tt.message)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("%s: compileProtoMessage(%s) error = %v; want error %t", tt.name, tt.message, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			got, err := encodeProtoJSON(md, []byte(tt.json))
+			if (err != nil) != tt.wantEncErr {
+				t.Fatalf("%s: encodeProtoJSON(%s) error = %v; want error %t", tt.name, tt.json, err, tt.wantEncErr)
+			}
+			if err == nil && !bytes.Equal(got, tt.want) {
+				t.Errorf("%s: encodeProtoJSON(%s) = %x; want %x", tt.name, tt.json, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,572 @@
+/*
+Copyright © 2024 ks6088ts
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package iot
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/eclipse/paho.golang/paho"
+	"github.com/ks6088ts-labs/misctl/internal"
+	"github.com/spf13/cobra"
+)
+
+// deliveryStatus describes the outcome of a publish for its QoS level.
+// resp is nil for QoS 0 since the broker does not acknowledge it.
+func deliveryStatus(qos byte, resp *paho.PublishResponse, elapsed time.Duration) string {
+	switch {
+	case qos == 0:
+		return fmt.Sprintf("sent (fire-and-forget) in %s", elapsed)
+	case resp == nil:
+		return fmt.Sprintf("no acknowledgement after %s", elapsed)
+	case qos == 1:
+		return fmt.Sprintf("PUBACK reason code 0x%02x after %s", resp.ReasonCode, elapsed)
+	default:
+		return fmt.Sprintf("PUBCOMP reason code 0x%02x after %s", resp.ReasonCode, elapsed)
+	}
+}
+
+// Statuses of a publishRecord
+const (
+	publishStatusPublished = "published"
+	publishStatusFailed    = "failed"
+)
+
+// publishRecord is the outcome of a single message, printed as a JSON line with --json
+type publishRecord struct {
+	Message int    `json:"message"`
+	Topic   string `json:"topic"`
+	QoS     byte   `json:"qos"`
+	Status  string `json:"status"`
+	// ReasonCode is the one of the PUBACK or PUBCOMP, nil for QoS 0 and unacknowledged messages
+	ReasonCode *byte   `json:"reason_code,omitempty"`
+	Attempts   int     `json:"attempts"`
+	ElapsedMs  float64 `json:"elapsed_ms"`
+	Error      string  `json:"error,omitempty"`
+	// Ack is the --trace-acks trace of the acknowledgement
+	Ack string `json:"ack,omitempty"`
+	// Reply is the --expect-reply reply, in the schema of the messages posted by `iot webhook`
+	Reply *webhookMessage `json:"reply,omitempty"`
+}
+
+// newPublishRecord builds the record of the message-th message, err is the publish error if any
+func newPublishRecord(message int, qos byte, topic string, resp *paho.PublishResponse, attempts int, elapsed time.Duration, err error) publishRecord {
+	r := publishRecord{
+		Message:   message,
+		Topic:     topic,
+		QoS:       qos,
+		Status:    publishStatusPublished,
+		Attempts:  attempts,
+		ElapsedMs: durationMs(elapsed),
+	}
+	if qos > 0 && resp != nil {
+		reasonCode := resp.ReasonCode
+		r.ReasonCode = &reasonCode
+	}
+	if err != nil {
+		r.Status = publishStatusFailed
+		r.Error = err.Error()
+	}
+	return r
+}
+
+// printPublishRecord writes r as a JSON line with --json, it is a no-op otherwise
+func printPublishRecord(out io.Writer, r publishRecord) {
+	if !internal.JSONOutput() {
+		return
+	}
+	if err := internal.PrintJSON(out, r, false); err != nil {
+		log.Printf("could not print publish record: %s", err)
+	}
+}
+
+// publishFunc publishes a message, it is paho.Client.Publish outside of the tests
+type publishFunc func(context.Context, *paho.Publish) (*paho.PublishResponse, error)
+
+// publishWithRetries publishes pb, retrying up to retries times when a QoS 1/2 publish is not acknowledged within ackTimeout,
+// 0 for the client packet timeout. Rejections and cancellation of ctx are not retried.
+// MQTT 5 only allows resending a PUBLISH with the DUP flag after a reconnection, so each retry is a new PUBLISH
+// with its own packet ID and subscribers may receive the message more than once.
+// The response and error are those of the last attempt, alongside the number of attempts.
+func publishWithRetries(ctx context.Context, publish publishFunc, pb *paho.Publish, ackTimeout time.Duration, retries int) (*paho.PublishResponse, int, error) {
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if ackTimeout > 0 && pb.QoS > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, ackTimeout)
+		}
+		resp, err := publish(attemptCtx, pb)
+		cancel()
+		if err == nil || pb.QoS == 0 || attempt > retries || ctx.Err() != nil || !errors.Is(err, context.DeadlineExceeded) {
+			return resp, attempt, err
+		}
+	}
+}
+
+// publishOutcomes counts the final outcome of the messages of a run with --publish-retries
+type publishOutcomes struct {
+	firstAttempt, retried, failed int
+}
+
+func (o *publishOutcomes) record(attempts int, err error) {
+	switch {
+	case err != nil:
+		o.failed++
+	case attempts > 1:
+		o.retried++
+	default:
+		o.firstAttempt++
+	}
+}
+
+func (o publishOutcomes) String() string {
+	return fmt.Sprintf("%d acknowledged on the first attempt, %d after retries, %d failed", o.firstAttempt, o.retried, o.failed)
+}
+
+// parseQoSCycle validates the QoS levels of --qos-cycle
+func parseQoSCycle(levels []int) ([]byte, error) {
+	cycle := make([]byte, 0, len(levels))
+	for _, level := range levels {
+		if level < 0 || level > 2 {
+			return nil, fmt.Errorf("invalid QoS %d in `qos-cycle`, must be 0, 1 or 2", level)
+		}
+		cycle = append(cycle, byte(level))
+	}
+	return cycle, nil
+}
+
+// qosCounts counts the publishes per QoS level of a --qos-cycle run
+type qosCounts struct {
+	sent      [3]int
+	succeeded [3]int
+}
+
+func (q *qosCounts) record(qos byte, ok bool) {
+	q.sent[qos]++
+	if ok {
+		q.succeeded[qos]++
+	}
+}
+
+// failed returns the number of failed publishes across the levels
+func (q *qosCounts) failed() int {
+	failed := 0
+	for i := range q.sent {
+		failed += q.sent[i] - q.succeeded[i]
+	}
+	return failed
+}
+
+// print writes the success count of every level which was used
+func (q *qosCounts) print(out io.Writer) {
+	for i := range q.sent {
+		if q.sent[i] > 0 {
+			fmt.Fprintf(out, "QoS %d: %d of %d succeeded\n", i, q.succeeded[i], q.sent[i])
+		}
+	}
+}
+
+// publishCmd represents the publish command
+var publishCmd = &cobra.Command{
+	Use:   "publish",
+	Short: "Publish a message and report its delivery status",
+	Long: `This command will publish a message to the specified topic and report its delivery status:
+QoS 0 messages are sent without acknowledgement, QoS 1 reports the PUBACK and QoS 2 the PUBCOMP reason code.
+The payload of each message can be generated by --payload-template.
+
+With --json, the outcome of each message is printed as a JSON line
+{"message", "topic", "qos", "status" (published or failed), "reason_code", "attempts", "elapsed_ms", "error",
+"ack", "reply"}, where "ack" is the --trace-acks trace and "reply" the --expect-reply reply in the schema
+of ` + "`iot subscribe --json`" + `. The progress text and the run summaries are not printed.
+
+` + payloadTemplateHelp,
+	Run: func(cmd *cobra.Command, args []string) {
+		out := cmd.OutOrStdout()
+		// Progress text is dropped with --json
+		text := internal.Decorative(out)
+		// Parse flags
+		env, err := cmd.Flags().GetString("env")
+		if err != nil {
+			log.Fatalf("could not get `env` flag: %s", err)
+		}
+		topic, err := cmd.Flags().GetString("topic")
+		if err != nil {
+			log.Fatalf("could not get `topic` flag: %s", err)
+		}
+		message, err := cmd.Flags().GetString("message")
+		if err != nil {
+			log.Fatalf("could not get `message` flag: %s", err)
+		}
+		payloadMessage, err := cmd.Flags().GetString("payload")
+		if err != nil {
+			log.Fatalf("could not get `payload` flag: %s", err)
+		}
+		retain, err := cmd.Flags().GetBool("retain")
+		if err != nil {
+			log.Fatalf("could not get `retain` flag: %s", err)
+		}
+		qos, err := cmd.Flags().GetUint8("qos")
+		if err != nil {
+			log.Fatalf("could not get `qos` flag: %s", err)
+		}
+		count, err := cmd.Flags().GetInt("count")
+		if err != nil {
+			log.Fatalf("could not get `count` flag: %s", err)
+		}
+		payloadSize, err := cmd.Flags().GetInt("payload-size")
+		if err != nil {
+			log.Fatalf("could not get `payload-size` flag: %s", err)
+		}
+		limitRate, err := cmd.Flags().GetInt("limit-rate")
+		if err != nil {
+			log.Fatalf("could not get `limit-rate` flag: %s", err)
+		}
+		qosCycleLevels, err := cmd.Flags().GetIntSlice("qos-cycle")
+		if err != nil {
+			log.Fatalf("could not get `qos-cycle` flag: %s", err)
+		}
+		expectReply, err := cmd.Flags().GetBool("expect-reply")
+		if err != nil {
+			log.Fatalf("could not get `expect-reply` flag: %s", err)
+		}
+		replyTopic, err := cmd.Flags().GetString("reply-topic")
+		if err != nil {
+			log.Fatalf("could not get `reply-topic` flag: %s", err)
+		}
+		replyTimeout, err := cmd.Flags().GetDuration("reply-timeout")
+		if err != nil {
+			log.Fatalf("could not get `reply-timeout` flag: %s", err)
+		}
+		payloadURL, err := cmd.Flags().GetString("payload-from-url")
+		if err != nil {
+			log.Fatalf("could not get `payload-from-url` flag: %s", err)
+		}
+		payloadHeaders, err := cmd.Flags().GetStringArray("payload-header")
+		if err != nil {
+			log.Fatalf("could not get `payload-header` flag: %s", err)
+		}
+		payloadTimeout, err := cmd.Flags().GetDuration("payload-timeout")
+		if err != nil {
+			log.Fatalf("could not get `payload-timeout` flag: %s", err)
+		}
+		payloadProto, err := cmd.Flags().GetString("payload-proto")
+		if err != nil {
+			log.Fatalf("could not get `payload-proto` flag: %s", err)
+		}
+		payloadJSON, err := cmd.Flags().GetString("payload-json")
+		if err != nil {
+			log.Fatalf("could not get `payload-json` flag: %s", err)
+		}
+		payloadTemplateText, err := cmd.Flags().GetString("payload-template")
+		if err != nil {
+			log.Fatalf("could not get `payload-template` flag: %s", err)
+		}
+		traceAcks, err := cmd.Flags().GetBool("trace-acks")
+		if err != nil {
+			log.Fatalf("could not get `trace-acks` flag: %s", err)
+		}
+		ackTimeout, err := cmd.Flags().GetDuration("ack-timeout")
+		if err != nil {
+			log.Fatalf("could not get `ack-timeout` flag: %s", err)
+		}
+		retries, err := cmd.Flags().GetInt("publish-retries")
+		if err != nil {
+			log.Fatalf("could not get `publish-retries` flag: %s", err)
+		}
+		if ackTimeout < 0 || retries < 0 {
+			internal.Fatalf(internal.CodeUsage, "`ack-timeout` and `publish-retries` must not be negative")
+		}
+		if count < 1 {
+			internal.Fatalf(internal.CodeUsage, "invalid `count` %d, must be at least 1", count)
+		}
+		message, messageName, err := messageFlag(message, payloadMessage)
+		if err != nil {
+			internal.Fatalf(internal.CodeUsage, "%s", err)
+		}
+		if message == stdinPayload && (payloadURL != "" || payloadSize > 0 || payloadProto != "") {
+			// The payload would be replaced after blocking on stdin
+			internal.Fatalf(internal.CodeUsage, "%s - cannot be used with --payload-from-url, --payload-size or --payload-proto", messageName)
+		}
+		if payloadURL != "" && payloadSize > 0 {
+			internal.Fatalf(internal.CodeUsage, "--payload-from-url and --payload-size are mutually exclusive")
+		}
+		if (payloadProto == "") != (payloadJSON == "") {
+			internal.Fatalf(internal.CodeUsage, "--payload-proto and --payload-json must be used together")
+		}
+		if payloadProto != "" && (payloadURL != "" || payloadSize > 0) {
+			internal.Fatalf(internal.CodeUsage, "--payload-proto cannot be used with --payload-from-url or --payload-size")
+		}
+		if payloadTemplateText != "" && (message != "" || payloadURL != "" || payloadSize > 0 || payloadProto != "") {
+			internal.Fatalf(internal.CodeUsage, "--payload-template cannot be used with %s, --payload-from-url, --payload-size or --payload-proto", messageName)
+		}
+		var payloadTmpl *payloadTemplate
+		if payloadTemplateText != "" {
+			payloadTmpl, err = newPayloadTemplate(payloadTemplateText)
+			if err != nil {
+				internal.Fatalf(internal.CodeUsage, "%s", err)
+			}
+		}
+		headers, err := parseHeaders(payloadHeaders)
+		if err != nil {
+			internal.Fatalf(internal.CodeUsage, "%s", err)
+		}
+		if replyTopic == "" {
+			replyTopic = topic + "/reply"
+		}
+		qosCycle, err := parseQoSCycle(qosCycleLevels)
+		if err != nil {
+			internal.Fatalf(internal.CodeUsage, "%s", err)
+		}
+		if payloadSize < 0 || limitRate < 0 {
+			internal.Fatalf(internal.CodeUsage, "`payload-size` and `limit-rate` must not be negative")
+		}
+		if qos > 2 {
+			internal.Fatalf(internal.CodeUsage, "invalid `qos` %d, must be 0, 1 or 2", qos)
+		}
+		cs := loadConnectionSettings(env)
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		// Read before connecting so that a failed read does not touch the broker
+		payload, err := readMessagePayload(message, cmd.InOrStdin())
+		if err != nil {
+			internal.Fatalf(internal.CodeRuntime, "%s", err)
+		}
+		if payloadSize > 0 {
+			payload = bytes.Repeat([]byte("x"), payloadSize)
+		}
+		if payloadURL != "" {
+			// Fetch before connecting so that a failed fetch does not touch the broker
+			fetchCtx, cancel := context.WithTimeout(ctx, payloadTimeout)
+			payload, err = fetchPayload(fetchCtx, http.DefaultClient, payloadURL, headers)
+			cancel()
+			if err != nil {
+				internal.Fatalf(internal.CodeRuntime, "%s", err)
+			}
+			fmt.Fprintf(text, "Fetched %d byte(s) from %s\n", len(payload), payloadURL)
+		}
+
+		if payloadProto != "" {
+			// Encode before connecting so that an invalid schema or JSON does not touch the broker
+			file, messageType, err := parseProtoSpec(payloadProto)
+			if err != nil {
+				internal.Fatalf(internal.CodeUsage, "%s", err)
+			}
+			md, err := compileProtoMessage(ctx, file, messageType)
+			if err != nil {
+				internal.Fatalf(internal.CodeConfig, "%s", err)
+			}
+			payload, err = encodeProtoJSON(md, []byte(payloadJSON))
+			if err != nil {
+				internal.Fatalf(internal.CodeUsage, "%s", err)
+			}
+			fmt.Fprintf(text, "Encoded %d byte(s) of %s\n", len(payload), md.FullName())
+		}
+
+		replies := newReplyWaiter()
+		cfg := paho.ClientConfig{}
+		var tracer *ackTracer
+		if traceAcks {
+			tracer = newAckTracer()
+			cfg.MIDs = tracer
+		}
+		if expectReply {
+			cfg.Router = paho.NewSingleHandlerRouter(func(m *paho.Publish) { replies.deliver(m) })
+		}
+		c, _, err := connect(ctx, out, cs, cfg)
+		if err != nil {
+			internal.Fatalf(connectErrorCode(err), "%s", err)
+		}
+		disconnect := func() {
+			if err := c.Disconnect(&paho.Disconnect{ReasonCode: 0}); err != nil {
+				log.Printf("could not disconnect: %s", err)
+			}
+		}
+		defer disconnect()
+		if expectReply {
+			// Subscribe before publishing so that a fast reply is not missed
+			if _, err := c.Subscribe(ctx, &paho.Subscribe{
+				Subscriptions: []paho.SubscribeOptions{{Topic: replyTopic, QoS: qos}},
+			}); err != nil {
+				internal.Fatalf(internal.CodeRuntime, "could not subscribe to reply topic: %s", err)
+			}
+		}
+
+		var bucket *tokenBucket
+		if limitRate > 0 {
+			bucket = newTokenBucket(limitRate, time.Now())
+		}
+
+		begin := time.Now()
+		sent := 0
+		sentBytes := 0
+		var counts qosCounts
+		var outcomes publishOutcomes
+		for ; sent < count; sent++ {
+			if payloadTmpl != nil {
+				payload, err = payloadTmpl.render()
+				if err != nil {
+					internal.Fatalf(internal.CodeUsage, "%s", err)
+				}
+			}
+			if bucket != nil {
+				if err := bucket.wait(ctx, len(payload)); err != nil {
+					break
+				}
+			}
+			sentBytes += len(payload)
+			qos := qos
+			if len(qosCycle) > 0 {
+				qos = qosCycle[sent%len(qosCycle)]
+			}
+			pb := &paho.Publish{
+				Topic:   topic,
+				QoS:     qos,
+				Retain:  retain,
+				Payload: payload,
+			}
+			var reply <-chan *paho.Publish
+			var correlation []byte
+			if expectReply {
+				correlation, err = newCorrelationData()
+				if err != nil {
+					internal.Fatalf(internal.CodeRuntime, "could not generate correlation data: %s", err)
+				}
+				pb.Properties = &paho.PublishProperties{ResponseTopic: replyTopic, CorrelationData: correlation}
+				reply = replies.expect(correlation)
+			}
+			start := time.Now()
+			resp, attempts, err := publishWithRetries(ctx, c.Publish, pb, ackTimeout, retries)
+			record := newPublishRecord(sent+1, qos, topic, resp, attempts, time.Since(start), err)
+			if err != nil && reply != nil {
+				// No reply comes for a message which was not published, even when the run goes on
+				replies.forget(correlation)
+			}
+			status := deliveryStatus(qos, resp, time.Since(start))
+			if attempts > 1 {
+				status = fmt.Sprintf("%s, %d attempts", status, attempts)
+			}
+			if tracer != nil && qos > 0 {
+				if trace := tracer.take(); trace != nil {
+					record.Ack = formatAckTrace(qos, trace, resp, time.Now())
+					fmt.Fprintf(text, "ack of message %d to %s: %s\n", sent+1, topic, record.Ack)
+				}
+			}
+			if len(qosCycle) > 0 {
+				// Mixed QoS runs go on to compare the levels
+				counts.record(qos, err == nil)
+				if err != nil {
+					fmt.Fprintf(text, "could not publish to %s with QoS %d: %s (%s)\n", topic, qos, err, status)
+					printPublishRecord(out, record)
+					continue
+				}
+			} else if retries > 0 {
+				// Go on with the next messages to report the outcome of each one
+				outcomes.record(attempts, err)
+				if err != nil {
+					fmt.Fprintf(text, "could not publish message %d to %s: %s (%s)\n", sent+1, topic, err, status)
+					printPublishRecord(out, record)
+					continue
+				}
+			} else if err != nil {
+				// The reason code of a rejected message is still worth reporting
+				printPublishRecord(out, record)
+				internal.Fatalf(internal.CodeRuntime, "could not publish message to %s: %s (%s)", topic, err, status)
+			}
+			fmt.Fprintf(text, "published to %s with QoS %d: %s\n", topic, qos, status)
+			if reply != nil {
+				select {
+				case m := <-reply:
+					fmt.Fprintf(text, "reply on %s after %s: %s\n", m.Topic, time.Since(start), m.Payload)
+					wm := newWebhookMessage(m, time.Now())
+					record.Reply = &wm
+				case <-time.After(replyTimeout):
+					printPublishRecord(out, record)
+					disconnect()
+					internal.Fatalf(internal.CodeTimeout, "no reply on %s within %s", replyTopic, replyTimeout)
+				case <-ctx.Done():
+				}
+				replies.forget(correlation)
+			}
+			printPublishRecord(out, record)
+		}
+		if count > 1 {
+			elapsed := time.Since(begin)
+			fmt.Fprintf(text, "Published %d message(s), %d byte(s) in %s (%.0f B/s)\n", sent, sentBytes, elapsed, float64(sentBytes)/elapsed.Seconds())
+		}
+		if retries > 0 && len(qosCycle) == 0 {
+			fmt.Fprintf(text, "Outcomes: %s\n", outcomes)
+			if outcomes.failed > 0 {
+				internal.Fatalf(internal.CodeRuntime, "%d publish(es) failed after %d retries", outcomes.failed, retries)
+			}
+		}
+		if len(qosCycle) > 0 {
+			counts.print(text)
+			if failed := counts.failed(); failed > 0 {
+				internal.Fatalf(internal.CodeRuntime, "%d publish(es) failed", failed)
+			}
+		}
+	},
+}
+
+func init() {
+	iotCmd.AddCommand(publishCmd)
+
+	publishCmd.Flags().StringP("env", "e", "", "Path to .env file")
+	publishCmd.Flags().StringP("topic", "t", "", "Topic to publish to")
+	publishCmd.Flags().StringP("message", "m", "", "Message payload, - to read it from stdin")
+	publishCmd.Flags().StringP("payload", "p", "", "Alias of --message, in line with the --payload-* flags")
+	publishCmd.Flags().Uint8P("qos", "q", 1, "QoS level")
+	publishCmd.Flags().Bool("retain", false, "Ask the broker to retain the message as the last one of the topic")
+	publishCmd.Flags().IntP("count", "c", 1, "Number of messages to publish")
+	publishCmd.Flags().Int("payload-size", 0, "Publish a generated payload of this many bytes instead of --message")
+	publishCmd.Flags().IntSlice("qos-cycle", []int{}, "Rotate successive publishes through these QoS levels, e.g. 0,1,2, overriding --qos")
+	publishCmd.Flags().String("payload-from-url", "", "Fetch the payload from this URL before connecting, instead of --message")
+	publishCmd.Flags().StringArray("payload-header", []string{}, "Header sent with --payload-from-url as Name: value, repeatable")
+	publishCmd.Flags().Duration("payload-timeout", 10*time.Second, "Timeout of the --payload-from-url request")
+	publishCmd.Flags().String("payload-proto", "", "Publish --payload-json encoded as protobuf with this schema, as <file.proto>:<MessageType>")
+	publishCmd.Flags().String("payload-json", "", "JSON representation of the --payload-proto message")
+	publishCmd.Flags().String("payload-template", "", "Go template rendered as the payload of each message instead of --message, e.g. {\"id\":\"{{uuid}}\",\"seq\":{{seq}},\"temp\":{{randInt 15 30}}}; see the help of the command for the functions")
+	publishCmd.Flags().Bool("expect-reply", false, "Publish with a response topic and correlation data, then wait for the reply of each message")
+	publishCmd.Flags().String("reply-topic", "", "Response topic used by --expect-reply, defaults to <topic>/reply")
+	publishCmd.Flags().Duration("reply-timeout", 5*time.Second, "How long --expect-reply waits for each reply")
+	publishCmd.Flags().Duration("ack-timeout", 0, "How long a QoS 1/2 publish waits for its acknowledgement before it is retried, 0 for the client default of 10s")
+	publishCmd.Flags().Int("publish-retries", 0, "Retry an unacknowledged QoS 1/2 publish up to this many times, as a new PUBLISH so subscribers may get duplicates, and report the outcome of every message")
+	publishCmd.Flags().Bool("trace-acks", false, "Print the packet ID, reason code and delay of each QoS 1/2 acknowledgement, i.e. PUBACK, or PUBREC then PUBCOMP")
+	publishCmd.Flags().Int("limit-rate", 0, "Throttle the payload throughput to this many bytes per second, 0 for no limit")
+
+	if err := publishCmd.MarkFlagRequired("env"); err != nil {
+		log.Fatalf("could not mark `env` as required: %s", err)
+	}
+	if err := publishCmd.MarkFlagRequired("topic"); err != nil {
+		log.Fatalf("could not mark `topic` as required: %s", err)
+	}
+}
@@ -0,0 +1,173 @@
+package iot
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eclipse/paho.golang/paho"
+)
+
+func TestDeliveryStatus(t *testing.T) {
+	// Table Driven Test
+	tests := []struct {
+		name string
+		qos  byte
+		resp *paho.PublishResponse
+		want string
+	}{
+		{name: "qos 0 case", qos: 0, want: "sent (fire-and-forget) in 5ms"},
+		{name: "qos 1 case", qos: 1, resp: &paho.PublishResponse{ReasonCode: 0x10}, want: "PUBACK reason code 0x10 after 5ms"},
+		{name: "qos 2 case", qos: 2, resp: &paho.PublishResponse{ReasonCode: 0}, want: "PUBCOMP reason code 0x00 after 5ms"},
+		{name: "no response case", qos: 1, want: "no acknowledgement after 5ms"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := deliveryStatus(tt.qos, tt.resp, 5*time.Millisecond)
+			if got != tt.want {
+				t.Errorf("%s: deliveryStatus(%d, %v) = %q; want %q", tt.name, tt.qos, tt.resp, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseQoSCycle(t *testing.T) {
+	// Table Driven Test
+	tests := []struct {
+		name    string
+		levels  []int
+		want    []byte
+		wantErr bool
+	}{
+		{name: "empty case", levels: []int{}, want: []byte{}},
+		{name: "all levels case", levels: []int{0, 1, 2}, want: []byte{0, 1, 2}},
+		{name: "repeated case", levels: []int{2, 2, 0}, want: []byte{2, 2, 0}},
+		{name: "negative case", levels: []int{-1}, wantErr: true},
+		{name: "too high case", levels: []int{0, 3}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseQoSCycle(tt.levels)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("%s: parseQoSCycle(%v) error = %v; want error %t", tt.name, tt.levels, err, tt.wantErr)
+			}
+			if !tt.wantErr && !bytes.Equal(got, tt.want) {
+				t.Errorf("%s: parseQoSCycle(%v) = %v; want %v", tt.name, tt.levels, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQoSCounts(t *testing.T) {
+	var q qosCounts
+	q.record(0, true)
+	q.record(1, true)
+	q.record(1, false)
+	q.record(0, true)
+	if got := q.failed(); got != 1 {
+		t.Errorf("qosCounts.failed() = %d; want 1", got)
+	}
+	var out strings.Builder
+	q.print(&out)
+	if want := "QoS 0: 2 of 2 succeeded\nQoS 1: 1 of 2 succeeded\n"; out.String() != want {
+		t.Errorf("qosCounts.print() = %q; want %q", out.String(), want)
+	}
+}
+
+func TestPublishWithRetries(t *testing.T) {
+	timeout := func(ctx context.Context, _ *paho.Publish) (*paho.PublishResponse, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	// Table Driven Test
+	tests := []struct {
+		name         string
+		qos          byte
+		retries      int
+		failures     int
+		failure      func(ctx context.Context, pb *paho.Publish) (*paho.PublishResponse, error)
+		wantAttempts int
+		wantErr      bool
+	}{
+		{name: "acknowledged case", qos: 1, retries: 3, wantAttempts: 1},
+		{name: "retried case", qos: 1, retries: 3, failures: 2, failure: timeout, wantAttempts: 3},
+		{name: "exhausted case", qos: 2, retries: 2, failures: 5, failure: timeout, wantAttempts: 3, wantErr: true},
+		{name: "rejected case", qos: 1, retries: 3, failures: 1, failure: func(context.Context, *paho.Publish) (*paho.PublishResponse, error) {
+			return &paho.PublishResponse{ReasonCode: 0x87}, errors.New("error publishing: not authorized")
+		}, wantAttempts: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			publish := func(ctx context.Context, pb *paho.Publish) (*paho.PublishResponse, error) {
+				calls++
+				if calls <= tt.failures {
+					return tt.failure(ctx, pb)
+				}
+				return &paho.PublishResponse{}, nil
+			}
+			_, attempts, err := publishWithRetries(context.Background(), publish, &paho.Publish{Topic: "t", QoS: tt.qos}, time.Millisecond, tt.retries)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("%s: publishWithRetries() error = %v; want error %t", tt.name, err, tt.wantErr)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("%s: publishWithRetries() attempts = %d; want %d", tt.name, attempts, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestPublishOutcomes(t *testing.T) {
+	var o publishOutcomes
+	o.record(1, nil)
+	o.record(3, nil)
+	o.record(4, errors.New("context deadline exceeded"))
+	want := "1 acknowledged on the first attempt, 1 after retries, 1 failed"
+	if got := o.String(); got != want {
+		t.Errorf("publishOutcomes.String() = %q; want %q", got, want)
+	}
+}
+
+func TestNewPublishRecord(t *testing.T) {
+	reasonCode := byte(0x10)
+
+	// Table Driven Test
+	tests := []struct {
+		name string
+		qos  byte
+		resp *paho.PublishResponse
+		err  error
+		want publishRecord
+	}{
+		{
+			name: "qos 0 case",
+			qos:  0,
+			want: publishRecord{Message: 1, Topic: "a/b", QoS: 0, Status: publishStatusPublished, Attempts: 1, ElapsedMs: 5},
+		},
+		{
+			name: "acknowledged case",
+			qos:  1,
+			resp: &paho.PublishResponse{ReasonCode: 0x10},
+			want: publishRecord{Message: 1, Topic: "a/b", QoS: 1, Status: publishStatusPublished, ReasonCode: &reasonCode, Attempts: 1, ElapsedMs: 5},
+		},
+		{
+			name: "failed case",
+			qos:  2,
+			err:  errors.New("timeout"),
+			want: publishRecord{Message: 1, Topic: "a/b", QoS: 2, Status: publishStatusFailed, Attempts: 1, ElapsedMs: 5, Error: "timeout"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newPublishRecord(1, tt.qos, "a/b", tt.resp, 1, 5*time.Millisecond, tt.err)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s: newPublishRecord() = %+v; want %+v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package iot
+
+import (
+	"context"
+	"time"
+)
+
+// tokenBucket throttles a byte stream to rate bytes per second with a burst of one second worth of bytes.
+// A request larger than the available tokens is let through and paid back by the following ones,
+// so payloads larger than the burst are still sent at the average rate.
+type tokenBucket struct {
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate int, now time.Time) *tokenBucket {
+	return &tokenBucket{rate: float64(rate), tokens: float64(rate), last: now}
+}
+
+// reserve takes n tokens at now and returns how long to wait before sending them
+func (b *tokenBucket) reserve(n int, now time.Time) time.Duration {
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.rate {
+		b.tokens = b.rate
+	}
+	b.last = now
+
+	b.tokens -= float64(n)
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// wait blocks until n bytes may be sent or ctx is done
+func (b *tokenBucket) wait(ctx context.Context, n int) error {
+	delay := b.reserve(n, time.Now())
+	if delay == 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
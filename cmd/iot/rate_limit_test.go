@@ -0,0 +1,36 @@
+package iot
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTokenBucketReserve(t *testing.T) {
+	start := time.Now()
+
+	// Table Driven Test
+	tests := []struct {
+		name    string
+		elapsed time.Duration
+		n       int
+		want    time.Duration
+	}{
+		{name: "within burst case", elapsed: 0, n: 600, want: 0},
+		{name: "exceeding burst case", elapsed: 0, n: 600, want: 200 * time.Millisecond},
+		{name: "debt paid back case", elapsed: 200 * time.Millisecond, n: 0, want: 0},
+		{name: "refilled case", elapsed: 700 * time.Millisecond, n: 500, want: 0},
+		{name: "capped burst case", elapsed: 10 * time.Second, n: 1500, want: 500 * time.Millisecond},
+	}
+	// The cases share the bucket, elapsed is relative to the previous case
+	b := newTokenBucket(1000, start)
+	now := start
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now = now.Add(tt.elapsed)
+			got := b.reserve(tt.n, now)
+			if got != tt.want {
+				t.Errorf("%s: reserve(%d) = %s; want %s", tt.name, tt.n, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package iot
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+
+	"github.com/eclipse/paho.golang/paho"
+)
+
+// replyWaiter hands the replies received on the response topic to the request with the same correlation data
+type replyWaiter struct {
+	mu      sync.Mutex
+	pending map[string]chan *paho.Publish
+}
+
+func newReplyWaiter() *replyWaiter {
+	return &replyWaiter{pending: map[string]chan *paho.Publish{}}
+}
+
+// newCorrelationData returns random correlation data for a request
+func newCorrelationData() ([]byte, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// expect registers a request, the reply is sent on the returned channel
+func (w *replyWaiter) expect(correlation []byte) <-chan *paho.Publish {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	ch := make(chan *paho.Publish, 1)
+	w.pending[hex.EncodeToString(correlation)] = ch
+	return ch
+}
+
+// forget drops a request which is no longer waited for
+func (w *replyWaiter) forget(correlation []byte) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.pending, hex.EncodeToString(correlation))
+}
+
+// deliver hands the message to the matching request and reports whether there was one.
+// Only the first reply of a request is kept.
+func (w *replyWaiter) deliver(m *paho.Publish) bool {
+	if m.Properties == nil || len(m.Properties.CorrelationData) == 0 {
+		return false
+	}
+	key := hex.EncodeToString(m.Properties.CorrelationData)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	ch, ok := w.pending[key]
+	if !ok {
+		return false
+	}
+	delete(w.pending, key)
+	ch <- m
+	return true
+}
@@ -0,0 +1,40 @@
+package iot
+
+import (
+	"testing"
+
+	"github.com/eclipse/paho.golang/paho"
+)
+
+func TestReplyWaiter(t *testing.T) {
+	w := newReplyWaiter()
+	ch := w.expect([]byte("req-1"))
+
+	// Table Driven Test
+	tests := []struct {
+		name string
+		m    *paho.Publish
+		want bool
+	}{
+		{name: "no properties case", m: &paho.Publish{Topic: "reply"}, want: false},
+		{name: "unknown correlation case", m: &paho.Publish{Topic: "reply", Properties: &paho.PublishProperties{CorrelationData: []byte("req-2")}}, want: false},
+		{name: "matching correlation case", m: &paho.Publish{Topic: "reply", Payload: []byte("pong"), Properties: &paho.PublishProperties{CorrelationData: []byte("req-1")}}, want: true},
+		{name: "duplicate reply case", m: &paho.Publish{Topic: "reply", Properties: &paho.PublishProperties{CorrelationData: []byte("req-1")}}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := w.deliver(tt.m); got != tt.want {
+				t.Errorf("%s: replyWaiter.deliver() = %t; want %t", tt.name, got, tt.want)
+			}
+		})
+	}
+	if reply := <-ch; string(reply.Payload) != "pong" {
+		t.Errorf("reply payload = %s; want pong", reply.Payload)
+	}
+
+	w.expect([]byte("req-3"))
+	w.forget([]byte("req-3"))
+	if w.deliver(&paho.Publish{Properties: &paho.PublishProperties{CorrelationData: []byte("req-3")}}) {
+		t.Errorf("replyWaiter.deliver() of a forgotten request = true; want false")
+	}
+}
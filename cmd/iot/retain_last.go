@@ -0,0 +1,54 @@
+package iot
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/eclipse/paho.golang/paho"
+)
+
+// lastMessage keeps the most recently received payload so it can be republished as retained
+type lastMessage struct {
+	mu      sync.Mutex
+	topic   string
+	payload []byte
+	ok      bool
+}
+
+// set records a received message
+func (l *lastMessage) set(topic string, payload []byte) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.topic = topic
+	l.payload = bytes.Clone(payload)
+	l.ok = true
+}
+
+// get returns the last received message, ok is false when nothing was received yet
+func (l *lastMessage) get() (topic string, payload []byte, ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.topic, l.payload, l.ok
+}
+
+// republishLast publishes the last received payload to target with the retain flag set
+func republishLast(ctx context.Context, out io.Writer, c *paho.Client, last *lastMessage, target string, qos byte) {
+	topic, payload, ok := last.get()
+	if !ok {
+		fmt.Fprintf(out, "no message received yet; nothing to republish to %s\n", target)
+		return
+	}
+	if _, err := c.Publish(ctx, &paho.Publish{
+		Topic:   target,
+		QoS:     qos,
+		Retain:  true,
+		Payload: payload,
+	}); err != nil {
+		fmt.Fprintf(out, "could not republish to %s: %s\n", target, err)
+		return
+	}
+	fmt.Fprintf(out, "republished %d byte(s) from topic %s to %s as retained\n", len(payload), topic, target)
+}
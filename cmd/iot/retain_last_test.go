@@ -0,0 +1,42 @@
+package iot
+
+import (
+	"testing"
+)
+
+func TestLastMessage(t *testing.T) {
+	// Table Driven Test
+	tests := []struct {
+		name        string
+		sets        [][2]string
+		wantTopic   string
+		wantPayload string
+		wantOk      bool
+	}{
+		{name: "empty case", sets: nil, wantOk: false},
+		{name: "single case", sets: [][2]string{{"a", "first"}}, wantTopic: "a", wantPayload: "first", wantOk: true},
+		{name: "latest wins case", sets: [][2]string{{"a", "first"}, {"b", "second"}, {"a", "third"}}, wantTopic: "a", wantPayload: "third", wantOk: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var l lastMessage
+			for _, s := range tt.sets {
+				l.set(s[0], []byte(s[1]))
+			}
+			topic, payload, ok := l.get()
+			if topic != tt.wantTopic || string(payload) != tt.wantPayload || ok != tt.wantOk {
+				t.Errorf("%s: lastMessage.get() = (%s, %s, %t); want (%s, %s, %t)", tt.name, topic, payload, ok, tt.wantTopic, tt.wantPayload, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestLastMessageCopiesPayload(t *testing.T) {
+	var l lastMessage
+	payload := []byte("first")
+	l.set("a", payload)
+	payload[0] = 'F'
+	if _, got, _ := l.get(); string(got) != "first" {
+		t.Errorf("lastMessage.get() = %s; want first", got)
+	}
+}
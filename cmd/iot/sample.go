@@ -0,0 +1,38 @@
+package iot
+
+import (
+	"fmt"
+	"math/rand"
+	"sync/atomic"
+)
+
+// sampler randomly keeps a fraction of the messages, counting all of them
+type sampler struct {
+	rate    float64
+	random  func() float64
+	total   atomic.Int64
+	sampled atomic.Int64
+}
+
+// newSampler keeps each message with the probability rate, which must be in (0, 1]
+func newSampler(rate float64) (*sampler, error) {
+	if rate <= 0 || rate > 1 {
+		return nil, fmt.Errorf("invalid sample rate %g, must be greater than 0 and at most 1", rate)
+	}
+	return &sampler{rate: rate, random: rand.Float64}, nil
+}
+
+// keep counts a message and reports whether it is sampled
+func (s *sampler) keep() bool {
+	s.total.Add(1)
+	if s.rate < 1 && s.random() >= s.rate {
+		return false
+	}
+	s.sampled.Add(1)
+	return true
+}
+
+// summary describes how many messages were sampled
+func (s *sampler) summary() string {
+	return fmt.Sprintf("%d of %d message(s) sampled at rate %g", s.sampled.Load(), s.total.Load(), s.rate)
+}
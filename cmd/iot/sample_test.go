@@ -0,0 +1,41 @@
+package iot
+
+import "testing"
+
+func TestSampler(t *testing.T) {
+	// Table Driven Test
+	tests := []struct {
+		name        string
+		rate        float64
+		randoms     []float64
+		wantSampled int64
+		wantErr     bool
+	}{
+		{name: "keep all case", rate: 1, randoms: []float64{0.1, 0.99, 0.5}, wantSampled: 3},
+		{name: "partial case", rate: 0.25, randoms: []float64{0.1, 0.3, 0.24, 0.25, 0.9}, wantSampled: 2},
+		{name: "zero rate case", rate: 0, wantErr: true},
+		{name: "above one case", rate: 1.5, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := newSampler(tt.rate)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("%s: newSampler(%g) error = %v; want error %t", tt.name, tt.rate, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			i := 0
+			s.random = func() float64 { i++; return tt.randoms[i-1] }
+			for range tt.randoms {
+				s.keep()
+			}
+			if got := s.sampled.Load(); got != tt.wantSampled {
+				t.Errorf("%s: sampled = %d; want %d", tt.name, got, tt.wantSampled)
+			}
+			if got := s.total.Load(); got != int64(len(tt.randoms)) {
+				t.Errorf("%s: total = %d; want %d", tt.name, got, len(tt.randoms))
+			}
+		})
+	}
+}
@@ -24,17 +24,20 @@ package iot
 import (
 	"context"
 	"crypto/tls"
-	"crypto/x509"
 	"fmt"
+	"io"
 	"log"
 	"net"
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/eclipse/paho.golang/paho"
 	"github.com/joho/godotenv"
+	"github.com/ks6088ts-labs/misctl/internal"
 	"github.com/spf13/cobra"
 )
 
@@ -48,13 +51,23 @@ type mqttConnectionSettings struct {
 	CertFile        string
 	KeyFile         string
 	KeyFilePassword string
-	KeepAlive       uint16
-	ClientId        string
-	Username        string
-	Password        string
+	// TlsCipherSuites are the cipher suite names to enable, empty keeps the defaults
+	TlsCipherSuites      []string
+	AllowInsecureCiphers bool
+	// RequireOCSPStapling fails the connection unless the broker staples a good OCSP response
+	RequireOCSPStapling bool
+	// Resolve maps host names to the IP address dialed instead of resolving them
+	Resolve   map[string]string
+	KeepAlive uint16
+	ClientId  string
+	Username  string
+	Password  string
+	// ProtocolVersion is protocolVersion5 or protocolVersion311
+	ProtocolVersion string
 }
 
-var mqttSettingNames = [12]string{
+var mqttSettingNames = [18]string{
+	"MQTT_CONNECTION_STRING",
 	"MQTT_HOST_NAME",
 	"MQTT_TCP_PORT",
 	"MQTT_USE_TLS",
@@ -67,93 +80,128 @@ var mqttSettingNames = [12]string{
 	"MQTT_CERT_FILE",
 	"MQTT_KEY_FILE",
 	"MQTT_KEY_FILE_PASSWORD",
+	"MQTT_TLS_CIPHER_SUITES",
+	"MQTT_TLS_ALLOW_INSECURE_CIPHERS",
+	"MQTT_TLS_REQUIRE_OCSP_STAPLING",
+	"MQTT_RESOLVE",
+	"MQTT_PROTOCOL_VERSION",
 }
 
 var defaults = map[string]string{
-	"MQTT_TCP_PORT":              "8883",
-	"MQTT_USE_TLS":               "true",
-	"MQTT_CLEAN_SESSION":         "true",
-	"MQTT_KEEP_ALIVE_IN_SECONDS": "30",
+	"MQTT_TCP_PORT":                   "8883",
+	"MQTT_USE_TLS":                    "true",
+	"MQTT_CLEAN_SESSION":              "true",
+	"MQTT_KEEP_ALIVE_IN_SECONDS":      "30",
+	"MQTT_TLS_ALLOW_INSECURE_CIPHERS": "false",
+	"MQTT_TLS_REQUIRE_OCSP_STAPLING":  "false",
+	"MQTT_PROTOCOL_VERSION":           protocolVersion5,
 }
 
-func parseIntValue(value string) int {
+// parseIntValue parses the integer value of the setting name
+func parseIntValue(name, value string) (int, error) {
 	parsed, err := strconv.Atoi(value)
 	if err != nil {
-		panic(err)
+		return 0, fmt.Errorf("invalid %s: %w", name, err)
 	}
-	return parsed
+	return parsed, nil
 }
 
-func parseBoolValue(value string) bool {
+// parseBoolValue parses the boolean value of the setting name
+func parseBoolValue(name, value string) (bool, error) {
 	parsed, err := strconv.ParseBool(value)
 	if err != nil {
-		panic(err)
+		return false, fmt.Errorf("invalid %s: %w", name, err)
 	}
-	return parsed
+	return parsed, nil
 }
 
-func getTlsConnection(cs mqttConnectionSettings) *tls.Conn {
-
-	cfg := &tls.Config{}
-
-	if cs.CertFile != "" && cs.KeyFile != "" {
-		if cs.KeyFilePassword != "" {
-			log.Fatal("Password protected key files are not supported at this time.")
-		}
-
-		cert, err := tls.LoadX509KeyPair(cs.CertFile, cs.KeyFile)
-		if err != nil {
-			log.Fatal(err)
-		}
-
-		cfg.Certificates = []tls.Certificate{cert}
+func getTlsConnection(ctx context.Context, cs mqttConnectionSettings) (net.Conn, error) {
+
+	cfg, err := internal.BuildTLSConfig(internal.TLSOptions{
+		CertFile:             cs.CertFile,
+		KeyFile:              cs.KeyFile,
+		KeyFilePassword:      cs.KeyFilePassword,
+		CAFile:               cs.CaFile,
+		CipherSuites:         cs.TlsCipherSuites,
+		AllowInsecureCiphers: cs.AllowInsecureCiphers,
+		RequireOCSPStapling:  cs.RequireOCSPStapling,
+	})
+	if err != nil {
+		return nil, internal.Errorf(internal.CodeConfig, "%w", err)
 	}
 
-	if cs.CaFile != "" {
-		ca, err := os.ReadFile(cs.CaFile)
-		if err != nil {
-			panic(err)
-		}
-
-		caCertPool := x509.NewCertPool()
-		caCertPool.AppendCertsFromPEM(ca)
-		cfg.RootCAs = caCertPool
+	// Keep the host name for SNI and verification when MQTT_RESOLVE dials another address
+	cfg.ServerName = cs.Hostname
+	if tlsSessionResumption != nil {
+		cfg.ClientSessionCache = tlsSessionResumption.cache
 	}
+	d := tls.Dialer{NetDialer: newNetDialer(), Config: cfg}
+	return d.DialContext(ctx, "tcp", dialAddress(cs))
+}
 
-	fmt.Println(cs.Hostname)
-	conn, err := tls.Dial("tcp", fmt.Sprintf("%s:%d", cs.Hostname, cs.TcpPort), cfg)
+func loadConnectionSettings(path string) mqttConnectionSettings {
+	cs, err := readConnectionSettings(path)
 	if err != nil {
-		panic(err)
+		internal.Fatalf(internal.CodeConfig, "%s", err)
 	}
-
-	return conn
+	return cs
 }
 
-func loadConnectionSettings(path string) mqttConnectionSettings {
-	if err := godotenv.Load(path); err != nil {
-		log.Fatalf("could not load .env file: %s", err)
+// readConnectionSettings is loadConnectionSettings returning an error instead of exiting
+func readConnectionSettings(path string) (mqttConnectionSettings, error) {
+	// Read the file instead of loading it into the environment so several files can be used side by side
+	file, err := godotenv.Read(path)
+	if err != nil {
+		return mqttConnectionSettings{}, fmt.Errorf("could not load .env file: %w", err)
 	}
 	cs := mqttConnectionSettings{}
 	envVars := make(map[string]string)
+	effective := make(map[string]internal.ConfigValue)
 
 	// Check to see which env vars are set
 	for i := 0; i < len(mqttSettingNames); i++ {
 		name := mqttSettingNames[i]
-		value := os.Getenv(name)
+		// Variables already set in the environment take precedence over the file
+		source := internal.ConfigSourceEnv
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			source = internal.ConfigSourceFile
+			value, ok = file[name]
+		}
 		// If var is not set, check if it has a default value
+		if !ok || value == "" && defaults[name] != "" {
+			source = internal.ConfigSourceDefault
+		}
 		if value == "" && defaults[name] != "" {
 			value = defaults[name]
 		}
 
 		envVars[name] = value
+		effective[name] = internal.ConfigValue{Value: value, Source: source}
+	}
+	// Print before parsing so that an invalid value can be traced to its source
+	if internal.PrintConfig() {
+		if err := internal.WriteConfig(os.Stderr, internal.EffectiveConfig{Section: "mqtt", Path: path, Settings: effective}); err != nil {
+			log.Printf("could not print MQTT settings: %s", err)
+		}
 	}
 
 	// Based on which vars are set, construct MqttConnectionSettings
 	cs.Hostname = envVars["MQTT_HOST_NAME"]
-	cs.TcpPort = parseIntValue(envVars["MQTT_TCP_PORT"])
-	cs.UseTls = parseBoolValue(envVars["MQTT_USE_TLS"])
-	cs.CleanSession = parseBoolValue(envVars["MQTT_CLEAN_SESSION"])
-	cs.KeepAlive = uint16(parseIntValue(envVars["MQTT_KEEP_ALIVE_IN_SECONDS"]))
+	if cs.TcpPort, err = parseIntValue("MQTT_TCP_PORT", envVars["MQTT_TCP_PORT"]); err != nil {
+		return cs, err
+	}
+	if cs.UseTls, err = parseBoolValue("MQTT_USE_TLS", envVars["MQTT_USE_TLS"]); err != nil {
+		return cs, err
+	}
+	if cs.CleanSession, err = parseBoolValue("MQTT_CLEAN_SESSION", envVars["MQTT_CLEAN_SESSION"]); err != nil {
+		return cs, err
+	}
+	keepAlive, err := parseIntValue("MQTT_KEEP_ALIVE_IN_SECONDS", envVars["MQTT_KEEP_ALIVE_IN_SECONDS"])
+	if err != nil {
+		return cs, err
+	}
+	cs.KeepAlive = uint16(keepAlive)
 	cs.ClientId = envVars["MQTT_CLIENT_ID"]
 	cs.Username = envVars["MQTT_USERNAME"]
 	cs.Password = envVars["MQTT_PASSWORD"]
@@ -161,82 +209,143 @@ func loadConnectionSettings(path string) mqttConnectionSettings {
 	cs.CertFile = envVars["MQTT_CERT_FILE"]
 	cs.KeyFile = envVars["MQTT_KEY_FILE"]
 	cs.KeyFilePassword = envVars["MQTT_KEY_FILE_PASSWORD"]
+	if value := envVars["MQTT_TLS_CIPHER_SUITES"]; value != "" {
+		cs.TlsCipherSuites = strings.Split(value, ",")
+	}
+	if cs.AllowInsecureCiphers, err = parseBoolValue("MQTT_TLS_ALLOW_INSECURE_CIPHERS", envVars["MQTT_TLS_ALLOW_INSECURE_CIPHERS"]); err != nil {
+		return cs, err
+	}
+	if cs.RequireOCSPStapling, err = parseBoolValue("MQTT_TLS_REQUIRE_OCSP_STAPLING", envVars["MQTT_TLS_REQUIRE_OCSP_STAPLING"]); err != nil {
+		return cs, err
+	}
+	resolve, err := parseResolveOverrides(envVars["MQTT_RESOLVE"])
+	if err != nil {
+		return cs, fmt.Errorf("could not parse MQTT_RESOLVE: %w", err)
+	}
+	cs.Resolve = resolve
+	cs.ProtocolVersion = envVars["MQTT_PROTOCOL_VERSION"]
+	if cs.ProtocolVersion != protocolVersion5 && cs.ProtocolVersion != protocolVersion311 {
+		return cs, fmt.Errorf("invalid MQTT_PROTOCOL_VERSION %q, must be %s or %s", cs.ProtocolVersion, protocolVersion311, protocolVersion5)
+	}
 
-	return cs
+	// A connection string takes precedence over the individual settings it covers
+	if value := envVars["MQTT_CONNECTION_STRING"]; value != "" {
+		if err := applyConnectionString(&cs, value); err != nil {
+			return cs, fmt.Errorf("could not parse MQTT_CONNECTION_STRING: %w", err)
+		}
+	}
+	// Keep the secrets out of the output and the logs
+	internal.DefaultRedactor.Add(cs.Password, cs.KeyFilePassword)
+
+	return cs, nil
+}
+
+// Router strategies of the sandbox
+const (
+	routerSingle   = "single"
+	routerStandard = "standard"
+)
+
+// printReceived prints a message received by the sandbox, filter is the filter it was routed by, if any
+func printReceived(out io.Writer, m *paho.Publish, filter string) {
+	if internal.JSONOutput() {
+		line, err := messageJSON(m, filter, time.Now())
+		if err != nil {
+			log.Printf("could not format message on topic %s: %s", m.Topic, err)
+			return
+		}
+		fmt.Fprintln(out, line)
+		return
+	}
+	if filter != "" {
+		fmt.Fprintf(out, "received message on topic %s via filter %s; body: %s (retain: %t)\n", m.Topic, filter, m.Payload, m.Retain)
+		return
+	}
+	fmt.Fprintf(out, "received message on topic %s; body: %s (retain: %t)\n", m.Topic, m.Payload, m.Retain)
+}
+
+// newSandboxRouter builds the router of the given strategy. The standard router gets one handler per
+// filter so the output shows which filter each message was routed to.
+func newSandboxRouter(strategy string, filters []string, out io.Writer) (paho.Router, error) {
+	switch strategy {
+	case routerSingle:
+		return paho.NewSingleHandlerRouter(func(m *paho.Publish) {
+			printReceived(out, m, "")
+		}), nil
+	case routerStandard:
+		r := paho.NewStandardRouter()
+		for _, filter := range filters {
+			filter := filter
+			r.RegisterHandler(filter, func(m *paho.Publish) {
+				printReceived(out, m, filter)
+			})
+		}
+		return r, nil
+	default:
+		return nil, fmt.Errorf("invalid router %q, must be %s or %s", strategy, routerSingle, routerStandard)
+	}
 }
 
 // sandboxCmd represents the sandbox command
 var sandboxCmd = &cobra.Command{
 	Use:   "sandbox",
 	Short: "Sandboxes the Paho MQTT client",
-	Long:  `This command will create a Paho MQTT client and connect to the specified broker.`,
+	Long: `This command will create a Paho MQTT client and connect to the specified broker.
+
+With --json, each received message is printed as a JSON line in the schema of ` + "`iot subscribe --json`" + `,
+with the "filter" it was routed by when --router is standard.`,
 	Run: func(cmd *cobra.Command, args []string) {
+		out := cmd.OutOrStdout()
+		// Progress text is dropped with --json
+		text := internal.Decorative(out)
 		// Parse flags
 		env, err := cmd.Flags().GetString("env")
 		if err != nil {
 			log.Fatalf("could not get `env` flag: %s", err)
 		}
+		strategy, err := cmd.Flags().GetString("router")
+		if err != nil {
+			log.Fatalf("could not get `router` flag: %s", err)
+		}
+		topics, err := cmd.Flags().GetStringArray("topic")
+		if err != nil {
+			log.Fatalf("could not get `topic` flag: %s", err)
+		}
+		router, err := newSandboxRouter(strategy, topics, out)
+		if err != nil {
+			internal.Fatalf(internal.CodeUsage, "%s", err)
+		}
 		var cs mqttConnectionSettings = loadConnectionSettings(env)
 
-		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
 		defer stop()
-		fmt.Println("Creating Paho client")
-		c := paho.NewClient(paho.ClientConfig{
-			Router: paho.NewSingleHandlerRouter(func(m *paho.Publish) {
-				fmt.Printf("received message on topic %s; body: %s (retain: %t)\n", m.Topic, m.Payload, m.Retain)
-			}),
-			OnClientError: func(err error) { fmt.Printf("server requested disconnect: %s\n", err) },
+		if cs.ProtocolVersion == protocolVersion311 {
+			runLegacySandbox(ctx, out, cs, strategy, topics)
+			return
+		}
+		fmt.Fprintln(text, "Creating Paho client")
+		c, _, err := connect(ctx, out, cs, paho.ClientConfig{
+			Router:        router,
+			OnClientError: func(err error) { fmt.Fprintf(text, "server requested disconnect: %s\n", err) },
 			OnServerDisconnect: func(d *paho.Disconnect) {
 				if d.Properties != nil {
-					fmt.Printf("server requested disconnect: %s\n", d.Properties.ReasonString)
+					fmt.Fprintf(text, "server requested disconnect: %s\n", d.Properties.ReasonString)
 				} else {
-					fmt.Printf("server requested disconnect; reason code: %d\n", d.ReasonCode)
+					fmt.Fprintf(text, "server requested disconnect; reason code: %d\n", d.ReasonCode)
 				}
 			},
 		})
-
-		if cs.UseTls {
-			c.Conn = getTlsConnection(cs)
-		} else {
-			conn, err := net.Dial("tcp", fmt.Sprintf("%s:%d", cs.Hostname, cs.TcpPort))
-			if err != nil {
-				panic(err)
-			}
-			c.Conn = conn
-		}
-
-		cp := &paho.Connect{
-			KeepAlive:  cs.KeepAlive,
-			ClientID:   cs.ClientId,
-			CleanStart: cs.CleanSession,
-		}
-
-		if cs.Username != "" {
-			cp.Username = cs.Username
-			cp.UsernameFlag = true
-		}
-
-		if cs.Password != "" {
-			cp.Password = []byte(cs.Password)
-			cp.PasswordFlag = true
-		}
-
-		fmt.Printf("Attempting to connect to %s:%d\n", cs.Hostname, cs.TcpPort)
-		ca, err := c.Connect(ctx, cp)
 		if err != nil {
-			log.Fatalln(err)
-		}
-		if ca.ReasonCode != 0 {
-			log.Fatalf("Failed to connect to %s : %d - %s", cs.Hostname, ca.ReasonCode, ca.Properties.ReasonString)
+			internal.Fatalf(connectErrorCode(err), "%s", err)
 		}
 
-		fmt.Printf("Connection successful")
-		if _, err := c.Subscribe(ctx, &paho.Subscribe{
-			Subscriptions: []paho.SubscribeOptions{
-				{Topic: "sample/+", QoS: byte(1)},
-			},
-		}); err != nil {
-			log.Fatalf("could not subscribe to topic: %s", err)
+		fmt.Fprintln(text, "Connection successful")
+		subscriptions := make([]paho.SubscribeOptions, 0, len(topics))
+		for _, topic := range topics {
+			subscriptions = append(subscriptions, paho.SubscribeOptions{Topic: topic, QoS: byte(1)})
+		}
+		if _, err := c.Subscribe(ctx, &paho.Subscribe{Subscriptions: subscriptions}); err != nil {
+			internal.Fatalf(internal.CodeRuntime, "could not subscribe to topic: %s", err)
 		}
 
 		if _, err := c.Publish(context.Background(), &paho.Publish{
@@ -245,11 +354,11 @@ var sandboxCmd = &cobra.Command{
 			Retain:  false,
 			Payload: []byte("hello world"),
 		}); err != nil {
-			log.Fatalf("could not publish message: %s", err)
+			internal.Fatalf(internal.CodeRuntime, "could not publish message: %s", err)
 		}
 
 		<-ctx.Done() // Wait for user to trigger exit
-		fmt.Println("signal caught - exiting")
+		fmt.Fprintf(text, "%s - exiting\n", internal.DoneReason(ctx))
 	},
 }
 
@@ -257,6 +366,8 @@ func init() {
 	iotCmd.AddCommand(sandboxCmd)
 
 	sandboxCmd.Flags().StringP("env", "e", "", "Path to .env file")
+	sandboxCmd.Flags().String("router", routerSingle, "Router strategy: single (one handler for every message) or standard (one handler per topic filter)")
+	sandboxCmd.Flags().StringArrayP("topic", "t", []string{"sample/+"}, "Topic filter to subscribe to")
 
 	if err := sandboxCmd.MarkFlagRequired("env"); err != nil {
 		log.Fatalf("could not mark `env` as required: %s", err)
@@ -0,0 +1,40 @@
+package iot
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/eclipse/paho.golang/packets"
+)
+
+func TestNewSandboxRouter(t *testing.T) {
+	// Table Driven Test
+	tests := []struct {
+		name     string
+		strategy string
+		topic    string
+		want     string
+		wantErr  bool
+	}{
+		{name: "single case", strategy: "single", topic: "sample/a", want: "received message on topic sample/a; body: hi (retain: false)\n"},
+		{name: "standard case", strategy: "standard", topic: "sample/a", want: "received message on topic sample/a via filter sample/+; body: hi (retain: false)\n"},
+		{name: "standard unmatched case", strategy: "standard", topic: "other/a", want: ""},
+		{name: "invalid case", strategy: "fanout", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			r, err := newSandboxRouter(tt.strategy, []string{"sample/+", "status/#"}, &out)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("%s: newSandboxRouter(%q) error = %v; want error %t", tt.name, tt.strategy, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			r.Route(&packets.Publish{Topic: tt.topic, Payload: []byte("hi"), Properties: &packets.Properties{}})
+			if got := out.String(); got != tt.want {
+				t.Errorf("%s: routed output = %q; want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package iot
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/eclipse/paho.golang/paho"
+)
+
+// savedMessage is a received message as written by --save, one JSON object per line.
+// It has no timestamp so that the records of different runs can be compared.
+type savedMessage struct {
+	Topic         string `json:"topic"`
+	Payload       string `json:"payload,omitempty"`
+	PayloadBase64 string `json:"payload_base64,omitempty"`
+}
+
+// messageSaver appends the received messages to a file
+type messageSaver struct {
+	mu sync.Mutex
+	f  *os.File
+	w  *bufio.Writer
+}
+
+func newMessageSaver(path string) (*messageSaver, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not create save file: %w", err)
+	}
+	return &messageSaver{f: f, w: bufio.NewWriter(f)}, nil
+}
+
+// save writes the message as a JSON line, payloads which are not valid UTF-8 are base64 encoded
+func (s *messageSaver) save(m *paho.Publish) error {
+	sm := savedMessage{Topic: m.Topic}
+	if utf8.Valid(m.Payload) {
+		sm.Payload = string(m.Payload)
+	} else {
+		sm.PayloadBase64 = base64.StdEncoding.EncodeToString(m.Payload)
+	}
+	data, err := json.Marshal(sm)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.w.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	return nil
+}
+
+// close flushes and closes the file
+func (s *messageSaver) close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.w.Flush(); err != nil {
+		s.f.Close()
+		return err
+	}
+	return s.f.Close()
+}
+
+// normalizeLines sorts the lines and/or removes duplicate ones, keeping the first occurrence
+func normalizeLines(lines []string, sorted, unique bool) []string {
+	result := append([]string(nil), lines...)
+	if sorted {
+		sort.Strings(result)
+	}
+	if unique {
+		seen := make(map[string]bool, len(result))
+		kept := result[:0]
+		for _, line := range result {
+			if seen[line] {
+				continue
+			}
+			seen[line] = true
+			kept = append(kept, line)
+		}
+		result = kept
+	}
+	return result
+}
+
+// normalizeFile rewrites the lines of the file with normalizeLines and returns the number of lines before and after
+func normalizeFile(path string, sorted, unique bool) (before, after int, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	content := strings.TrimSuffix(string(data), "\n")
+	if content == "" {
+		return 0, 0, nil
+	}
+	lines := strings.Split(content, "\n")
+	normalized := normalizeLines(lines, sorted, unique)
+	if err := os.WriteFile(path, []byte(strings.Join(normalized, "\n")+"\n"), 0o644); err != nil {
+		return 0, 0, err
+	}
+	return len(lines), len(normalized), nil
+}
@@ -0,0 +1,69 @@
+package iot
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/eclipse/paho.golang/paho"
+)
+
+func TestNormalizeLines(t *testing.T) {
+	// Table Driven Test
+	tests := []struct {
+		name   string
+		lines  []string
+		sorted bool
+		unique bool
+		want   []string
+	}{
+		{name: "untouched case", lines: []string{"b", "a", "b"}, want: []string{"b", "a", "b"}},
+		{name: "sort case", lines: []string{"b", "a", "b"}, sorted: true, want: []string{"a", "b", "b"}},
+		{name: "unique keeps first occurrence case", lines: []string{"b", "a", "b"}, unique: true, want: []string{"b", "a"}},
+		{name: "sort and unique case", lines: []string{"c", "a", "c", "b", "a"}, sorted: true, unique: true, want: []string{"a", "b", "c"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeLines(tt.lines, tt.sorted, tt.unique); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s: normalizeLines(%v) = %v; want %v", tt.name, tt.lines, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMessageSaver(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "messages.jsonl")
+	s, err := newMessageSaver(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range []*paho.Publish{
+		{Topic: "b", Payload: []byte("2")},
+		{Topic: "a", Payload: []byte{0xff}},
+		{Topic: "b", Payload: []byte("2")},
+	} {
+		if err := s.save(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	before, after, err := normalizeFile(path, true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before != 3 || after != 2 {
+		t.Errorf("normalizeFile = (%d, %d); want (3, 2)", before, after)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"topic":"a","payload_base64":"/w=="}` + "\n" + `{"topic":"b","payload":"2"}` + "\n"
+	if string(data) != want {
+		t.Errorf("saved file = %q; want %q", data, want)
+	}
+}
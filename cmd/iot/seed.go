@@ -0,0 +1,213 @@
+/*
+Copyright © 2024 ks6088ts
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package iot
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"text/template"
+
+	"github.com/eclipse/paho.golang/paho"
+	"github.com/ks6088ts-labs/misctl/internal"
+	"github.com/spf13/cobra"
+)
+
+// seedFile holds the fields of a corpus file available to --topic-template
+type seedFile struct {
+	// Name is the file name without its extension
+	Name string
+	// File is the file name
+	File string
+	// Ext is the extension without its leading dot
+	Ext string
+}
+
+func newSeedFile(path string) seedFile {
+	file := filepath.Base(path)
+	ext := filepath.Ext(file)
+	return seedFile{Name: strings.TrimSuffix(file, ext), File: file, Ext: strings.TrimPrefix(ext, ".")}
+}
+
+// seedFiles lists the regular files of the corpus directory in name order, skipping subdirectories and hidden files
+func seedFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		files = append(files, filepath.Join(dir, entry.Name()))
+	}
+	return files, nil
+}
+
+// seedTopic renders the topic of a corpus file, which must not be empty nor contain wildcards
+func seedTopic(tmpl *template.Template, path string) (string, error) {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, newSeedFile(path)); err != nil {
+		return "", err
+	}
+	topic := b.String()
+	if topic == "" || strings.ContainsAny(topic, "+#") {
+		return "", fmt.Errorf("invalid topic %q for %s", topic, filepath.Base(path))
+	}
+	return topic, nil
+}
+
+// seedResult is the summary of a run printed with --json
+type seedResult struct {
+	Files     int `json:"files"`
+	Published int `json:"published"`
+	Failed    int `json:"failed"`
+	Bytes     int `json:"bytes"`
+	// FailedFiles are the paths of the files which could not be published
+	FailedFiles []string `json:"failed_files"`
+}
+
+// seedCmd represents the seed command
+var seedCmd = &cobra.Command{
+	Use:   "seed",
+	Short: "Publish every file of a directory as a message",
+	Long: `This command will publish the content of every file in the corpus directory as a message,
+to the topic rendered from --topic-template with .Name (file name without extension), .File and .Ext.
+It reports how many files were published and exits with a non-zero code if any could not be read or published,
+the failures are logged to stderr.
+
+With --json, a single object is printed at the end:
+  {"files", "published", "failed", "bytes", "failed_files"}`,
+	Run: func(cmd *cobra.Command, args []string) {
+		out := cmd.OutOrStdout()
+		// Parse flags
+		env, err := cmd.Flags().GetString("env")
+		if err != nil {
+			log.Fatalf("could not get `env` flag: %s", err)
+		}
+		dir, err := cmd.Flags().GetString("corpus")
+		if err != nil {
+			log.Fatalf("could not get `corpus` flag: %s", err)
+		}
+		topicTemplate, err := cmd.Flags().GetString("topic-template")
+		if err != nil {
+			log.Fatalf("could not get `topic-template` flag: %s", err)
+		}
+		qos, err := cmd.Flags().GetUint8("qos")
+		if err != nil {
+			log.Fatalf("could not get `qos` flag: %s", err)
+		}
+		retain, err := cmd.Flags().GetBool("retain")
+		if err != nil {
+			log.Fatalf("could not get `retain` flag: %s", err)
+		}
+		if qos > 2 {
+			internal.Fatalf(internal.CodeUsage, "invalid `qos` %d, must be 0, 1 or 2", qos)
+		}
+		tmpl, err := template.New("topic").Option("missingkey=error").Parse(topicTemplate)
+		if err != nil {
+			internal.Fatalf(internal.CodeUsage, "invalid topic template: %s", err)
+		}
+		files, err := seedFiles(dir)
+		if err != nil {
+			internal.Fatalf(internal.CodeUsage, "could not read corpus: %s", err)
+		}
+		if len(files) == 0 {
+			internal.Fatalf(internal.CodeUsage, "no file to publish in %s", dir)
+		}
+		cs := loadConnectionSettings(env)
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		c, _, err := connect(ctx, out, cs, paho.ClientConfig{})
+		if err != nil {
+			internal.Fatalf(connectErrorCode(err), "%s", err)
+		}
+		defer func() {
+			if err := c.Disconnect(&paho.Disconnect{ReasonCode: 0}); err != nil {
+				log.Printf("could not disconnect: %s", err)
+			}
+		}()
+
+		// Progress text is dropped with --json
+		text := internal.Decorative(out)
+		result := seedResult{Files: len(files), FailedFiles: []string{}}
+		for _, file := range files {
+			if ctx.Err() != nil {
+				break
+			}
+			topic, err := seedTopic(tmpl, file)
+			if err != nil {
+				log.Printf("skipped %s: %s", file, err)
+				result.FailedFiles = append(result.FailedFiles, file)
+				continue
+			}
+			payload, err := os.ReadFile(file)
+			if err != nil {
+				log.Printf("could not read %s: %s", file, err)
+				result.FailedFiles = append(result.FailedFiles, file)
+				continue
+			}
+			if _, err := c.Publish(ctx, &paho.Publish{Topic: topic, QoS: qos, Retain: retain, Payload: payload}); err != nil {
+				log.Printf("could not publish %s to %s: %s", file, topic, err)
+				result.FailedFiles = append(result.FailedFiles, file)
+				continue
+			}
+			fmt.Fprintf(text, "published %s to %s (%d byte(s))\n", filepath.Base(file), topic, len(payload))
+			result.Published++
+			result.Bytes += len(payload)
+		}
+		result.Failed = len(result.FailedFiles)
+		fmt.Fprintf(text, "Published %d of %d file(s), %d byte(s), %d failed\n", result.Published, result.Files, result.Bytes, result.Failed)
+		if internal.JSONOutput() {
+			if err := internal.PrintJSON(out, result, false); err != nil {
+				internal.Fatalf(internal.CodeRuntime, "could not print result: %s", err)
+			}
+		}
+		if result.Failed > 0 {
+			internal.Fatalf(internal.CodeRuntime, "%d file(s) could not be published", result.Failed)
+		}
+	},
+}
+
+func init() {
+	iotCmd.AddCommand(seedCmd)
+
+	seedCmd.Flags().StringP("env", "e", "", "Path to .env file")
+	seedCmd.Flags().String("corpus", "", "Directory whose files are published, one message per file")
+	seedCmd.Flags().String("topic-template", "{{.Name}}", "Go template of the topic of each file, with .Name, .File and .Ext, e.g. devices/{{.Name}}/state")
+	seedCmd.Flags().Uint8P("qos", "q", 1, "QoS level")
+	seedCmd.Flags().Bool("retain", false, "Publish the messages as retained so that later subscribers receive them")
+
+	if err := seedCmd.MarkFlagRequired("env"); err != nil {
+		log.Fatalf("could not mark `env` as required: %s", err)
+	}
+	if err := seedCmd.MarkFlagRequired("corpus"); err != nil {
+		log.Fatalf("could not mark `corpus` as required: %s", err)
+	}
+}
@@ -0,0 +1,59 @@
+package iot
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"text/template"
+)
+
+func TestSeedFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b.json", "a.bin", ".hidden"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	got, err := seedFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(dir, "a.bin"), filepath.Join(dir, "b.json")}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("seedFiles() = %v; want %v", got, want)
+	}
+}
+
+func TestSeedTopic(t *testing.T) {
+	// Table Driven Test
+	tests := []struct {
+		name     string
+		template string
+		path     string
+		want     string
+		wantErr  bool
+	}{
+		{name: "default case", template: "{{.Name}}", path: "corpus/sensor-1.json", want: "sensor-1"},
+		{name: "prefix case", template: "devices/{{.Name}}/{{.Ext}}", path: "corpus/d1.bin", want: "devices/d1/bin"},
+		{name: "no extension case", template: "{{.File}}", path: "corpus/README", want: "README"},
+		{name: "empty topic case", template: "{{.Ext}}", path: "corpus/README", wantErr: true},
+		{name: "wildcard case", template: "{{.Name}}", path: "corpus/a+b.json", wantErr: true},
+		{name: "unknown field case", template: "{{.Size}}", path: "corpus/a.json", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl := template.Must(template.New("topic").Option("missingkey=error").Parse(tt.template))
+			got, err := seedTopic(tmpl, tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("%s: seedTopic(%q) error = %v; want error %t", tt.name, tt.path, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("%s: seedTopic(%q) = %q; want %q", tt.name, tt.path, got, tt.want)
+			}
+		})
+	}
+}
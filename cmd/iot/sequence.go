@@ -0,0 +1,378 @@
+/*
+Copyright © 2024 ks6088ts
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package iot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/eclipse/paho.golang/paho"
+	"github.com/ks6088ts-labs/misctl/internal"
+	"github.com/spf13/cobra"
+)
+
+// sequenceProperty is the user property carrying the sequence number of a message
+const sequenceProperty = "seq"
+
+// workerProperty is the user property carrying the publishing worker of a message.
+// Workers publish concurrently, so sequence numbers only go out in order within a worker.
+const workerProperty = "worker"
+
+// sequenceTracker records received sequence numbers to detect reordering and loss
+type sequenceTracker struct {
+	mu   sync.Mutex
+	seen map[uint64]bool
+	// last is the last sequence number received per worker, messages without a worker share one order
+	last       map[string]uint64
+	received   int
+	outOfOrder int
+	duplicates int
+	invalid    int
+}
+
+func newSequenceTracker() *sequenceTracker {
+	return &sequenceTracker{seen: map[uint64]bool{}, last: map[string]uint64{}}
+}
+
+// observe records a sequence number received from worker
+func (t *sequenceTracker) observe(worker string, seq uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.received++
+	if t.seen[seq] {
+		t.duplicates++
+		return
+	}
+	t.seen[seq] = true
+	if seq < t.last[worker] {
+		t.outOfOrder++
+		return
+	}
+	t.last[worker] = seq
+}
+
+// observeInvalid records a received message without a valid sequence number
+func (t *sequenceTracker) observeInvalid() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.invalid++
+}
+
+// missing returns the sequence numbers in [1, count] which were not received
+func (t *sequenceTracker) missing(count uint64) []uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	missing := []uint64{}
+	for seq := uint64(1); seq <= count; seq++ {
+		if !t.seen[seq] {
+			missing = append(missing, seq)
+		}
+	}
+	return missing
+}
+
+// unique returns the number of distinct sequence numbers received
+func (t *sequenceTracker) unique() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.seen)
+}
+
+// summary returns a one-line summary of the received messages
+func (t *sequenceTracker) summary() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return fmt.Sprintf("Received %d message(s): %d out of order, %d duplicate(s), %d without sequence",
+		t.received, t.outOfOrder, t.duplicates, t.invalid)
+}
+
+// sequencePublished is the publishing side of a sequenceResult
+type sequencePublished struct {
+	Messages  uint64  `json:"messages"`
+	Workers   int     `json:"workers"`
+	Failed    int     `json:"failed"`
+	ElapsedMs float64 `json:"elapsed_ms"`
+}
+
+// sequenceReceived is the subscribing side of a sequenceResult
+type sequenceReceived struct {
+	Messages        int      `json:"messages"`
+	OutOfOrder      int      `json:"out_of_order"`
+	Duplicates      int      `json:"duplicates"`
+	WithoutSequence int      `json:"without_sequence"`
+	Missing         []uint64 `json:"missing"`
+	TimedOut        bool     `json:"timed_out"`
+}
+
+// sequenceResult is the result of a run printed with --json, the side of a role which did not run is omitted
+type sequenceResult struct {
+	Published *sequencePublished `json:"published,omitempty"`
+	Received  *sequenceReceived  `json:"received,omitempty"`
+}
+
+// result returns the received messages of a run of count messages
+func (t *sequenceTracker) result(count uint64) *sequenceReceived {
+	missing := t.missing(count)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return &sequenceReceived{
+		Messages:        t.received,
+		OutOfOrder:      t.outOfOrder,
+		Duplicates:      t.duplicates,
+		WithoutSequence: t.invalid,
+		Missing:         missing,
+	}
+}
+
+// inflightLimit returns the number of unacknowledged publishes allowed at once.
+// The broker's ReceiveMaximum is used as a ceiling, 0 means unbounded.
+func inflightLimit(maxInflight int, ca *paho.Connack) int {
+	if ca == nil || ca.Properties == nil || ca.Properties.ReceiveMaximum == nil {
+		return maxInflight
+	}
+	receiveMaximum := int(*ca.Properties.ReceiveMaximum)
+	if maxInflight == 0 || maxInflight > receiveMaximum {
+		return receiveMaximum
+	}
+	return maxInflight
+}
+
+// publishSequence publishes count messages from workers goroutines, each tagged with a monotonic sequence number
+// and its worker. A worker publishes its messages one after the other, so they go out in order, while the
+// messages of different workers race each other on the wire and are not compared by the tracker.
+// When maxInflight is positive, at most maxInflight QoS 1/2 publishes are left unacknowledged at once.
+func publishSequence(ctx context.Context, publish publishFunc, topic string, qos byte, count uint64, workers int, maxInflight int) int {
+	var inflight chan struct{}
+	if maxInflight > 0 && qos > 0 {
+		inflight = make(chan struct{}, maxInflight)
+	}
+	var next atomic.Uint64
+	var failed atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		worker := strconv.Itoa(i + 1)
+		go func() {
+			defer wg.Done()
+			for {
+				seq := next.Add(1)
+				if seq > count || ctx.Err() != nil {
+					return
+				}
+				props := &paho.PublishProperties{}
+				props.User.Add(sequenceProperty, strconv.FormatUint(seq, 10))
+				props.User.Add(workerProperty, worker)
+				if inflight != nil {
+					inflight <- struct{}{}
+				}
+				if _, err := publish(ctx, &paho.Publish{
+					Topic:      topic,
+					QoS:        qos,
+					Properties: props,
+					Payload:    []byte(strconv.FormatUint(seq, 10)),
+				}); err != nil {
+					log.Printf("could not publish sequence %d: %s", seq, err)
+					failed.Add(1)
+				}
+				if inflight != nil {
+					<-inflight
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return int(failed.Load())
+}
+
+// sequenceCmd represents the sequence command
+var sequenceCmd = &cobra.Command{
+	Use:   "sequence",
+	Short: "Check message ordering and loss with sequenced messages",
+	Long: `This command will publish sequenced messages from multiple goroutines, tagging each one with a
+monotonic sequence number in the "seq" user property and its worker in the "worker" one, and/or subscribe
+to them to report out-of-order and missing sequence numbers. The order is checked per worker, since the
+workers publish concurrently and their messages may leave the client in any order.
+
+With --json, the run is printed as a single JSON object
+{"published": {"messages", "workers", "failed", "elapsed_ms"},
+"received": {"messages", "out_of_order", "duplicates", "without_sequence", "missing", "timed_out"}},
+without the side of the role which did not run.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		out := cmd.OutOrStdout()
+		// Progress text is dropped with --json
+		text := internal.Decorative(out)
+		// Parse flags
+		env, err := cmd.Flags().GetString("env")
+		if err != nil {
+			log.Fatalf("could not get `env` flag: %s", err)
+		}
+		topic, err := cmd.Flags().GetString("topic")
+		if err != nil {
+			log.Fatalf("could not get `topic` flag: %s", err)
+		}
+		count, err := cmd.Flags().GetUint64("count")
+		if err != nil {
+			log.Fatalf("could not get `count` flag: %s", err)
+		}
+		workers, err := cmd.Flags().GetInt("workers")
+		if err != nil {
+			log.Fatalf("could not get `workers` flag: %s", err)
+		}
+		qos, err := cmd.Flags().GetUint8("qos")
+		if err != nil {
+			log.Fatalf("could not get `qos` flag: %s", err)
+		}
+		role, err := cmd.Flags().GetString("role")
+		if err != nil {
+			log.Fatalf("could not get `role` flag: %s", err)
+		}
+		timeout, err := cmd.Flags().GetDuration("timeout")
+		if err != nil {
+			log.Fatalf("could not get `timeout` flag: %s", err)
+		}
+		maxInflight, err := cmd.Flags().GetInt("max-inflight")
+		if err != nil {
+			log.Fatalf("could not get `max-inflight` flag: %s", err)
+		}
+		pretty, err := cmd.Flags().GetBool("pretty")
+		if err != nil {
+			log.Fatalf("could not get `pretty` flag: %s", err)
+		}
+		if role != "both" && role != "publish" && role != "subscribe" {
+			internal.Fatalf(internal.CodeUsage, "invalid `role` %q, must be one of both, publish or subscribe", role)
+		}
+		if workers < 1 {
+			internal.Fatalf(internal.CodeUsage, "invalid `workers` %d, must be at least 1", workers)
+		}
+		if maxInflight < 0 {
+			internal.Fatalf(internal.CodeUsage, "invalid `max-inflight` %d, must not be negative", maxInflight)
+		}
+		cs := loadConnectionSettings(env)
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		tracker := newSequenceTracker()
+		done := make(chan struct{})
+		var doneOnce sync.Once
+		c, ca, err := connect(ctx, out, cs, paho.ClientConfig{
+			Router: paho.NewSingleHandlerRouter(func(m *paho.Publish) {
+				if m.Properties == nil {
+					tracker.observeInvalid()
+					return
+				}
+				seq, err := strconv.ParseUint(m.Properties.User.Get(sequenceProperty), 10, 64)
+				if err != nil {
+					tracker.observeInvalid()
+					return
+				}
+				tracker.observe(m.Properties.User.Get(workerProperty), seq)
+				if uint64(tracker.unique()) >= count {
+					doneOnce.Do(func() { close(done) })
+				}
+			}),
+		})
+		if err != nil {
+			internal.Fatalf(connectErrorCode(err), "%s", err)
+		}
+
+		if role != "publish" {
+			if _, err := c.Subscribe(ctx, &paho.Subscribe{
+				Subscriptions: []paho.SubscribeOptions{
+					{Topic: topic, QoS: qos},
+				},
+			}); err != nil {
+				internal.Fatalf(internal.CodeRuntime, "could not subscribe to topic: %s", err)
+			}
+		}
+
+		var result sequenceResult
+		if role != "subscribe" {
+			limit := inflightLimit(maxInflight, ca)
+			if limit != maxInflight {
+				fmt.Fprintf(text, "Limiting in-flight publishes to the broker's ReceiveMaximum of %d\n", limit)
+			}
+			start := time.Now()
+			failed := publishSequence(ctx, c.Publish, topic, qos, count, workers, limit)
+			elapsed := time.Since(start)
+			result.Published = &sequencePublished{Messages: count, Workers: workers, Failed: failed, ElapsedMs: durationMs(elapsed)}
+			fmt.Fprintf(text, "Published %d message(s) from %d worker(s) in %s, %d failed\n", count, workers, elapsed, failed)
+		}
+
+		if role != "publish" {
+			timedOut := false
+			select {
+			case <-done:
+			case <-time.After(timeout):
+				timedOut = true
+				fmt.Fprintf(text, "timed out after %s waiting for messages\n", timeout)
+			case <-ctx.Done():
+			}
+
+			result.Received = tracker.result(count)
+			result.Received.TimedOut = timedOut
+			missing := result.Received.Missing
+			fmt.Fprintln(text, tracker.summary())
+			fmt.Fprintf(text, "Missing %d sequence number(s)", len(missing))
+			if len(missing) > 0 {
+				fmt.Fprintf(text, ": %v", missing)
+			}
+			fmt.Fprintln(text)
+		}
+		if internal.JSONOutput() {
+			if err := internal.PrintJSON(out, result, pretty); err != nil {
+				internal.Fatalf(internal.CodeRuntime, "could not print result: %s", err)
+			}
+		}
+
+		if err := c.Disconnect(&paho.Disconnect{ReasonCode: 0}); err != nil {
+			log.Printf("could not disconnect: %s", err)
+		}
+	},
+}
+
+func init() {
+	iotCmd.AddCommand(sequenceCmd)
+
+	sequenceCmd.Flags().StringP("env", "e", "", "Path to .env file")
+	sequenceCmd.Flags().StringP("topic", "t", "sample/sequence", "Topic to publish sequenced messages to")
+	sequenceCmd.Flags().Uint64P("count", "c", 100, "Number of messages to publish")
+	sequenceCmd.Flags().IntP("workers", "w", 4, "Number of publishing goroutines")
+	sequenceCmd.Flags().Uint8P("qos", "q", 1, "QoS level")
+	sequenceCmd.Flags().String("role", "both", "Role of this client: both, publish or subscribe")
+	sequenceCmd.Flags().Duration("timeout", 10*time.Second, "How long to wait for all messages when subscribing")
+	sequenceCmd.Flags().Int("max-inflight", 0, "Maximum number of unacknowledged QoS 1/2 publishes, 0 for no limit")
+
+	if err := sequenceCmd.MarkFlagRequired("env"); err != nil {
+		log.Fatalf("could not mark `env` as required: %s", err)
+	}
+}
@@ -0,0 +1,134 @@
+package iot
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/eclipse/paho.golang/paho"
+)
+
+// sequenceObservation is a sequence number received from a worker
+type sequenceObservation struct {
+	worker string
+	seq    uint64
+}
+
+func TestSequenceTracker(t *testing.T) {
+	// Table Driven Test
+	tests := []struct {
+		name           string
+		observed       []sequenceObservation
+		count          uint64
+		wantOutOfOrder int
+		wantDuplicates int
+		wantMissing    []uint64
+	}{
+		{name: "in order case", observed: []sequenceObservation{{"", 1}, {"", 2}, {"", 3}}, count: 3, wantMissing: []uint64{}},
+		{name: "out of order case", observed: []sequenceObservation{{"", 1}, {"", 3}, {"", 2}}, count: 3, wantOutOfOrder: 1, wantMissing: []uint64{}},
+		{name: "interleaved workers case", observed: []sequenceObservation{{"1", 1}, {"2", 3}, {"1", 2}, {"2", 4}}, count: 4, wantMissing: []uint64{}},
+		{name: "out of order worker case", observed: []sequenceObservation{{"1", 1}, {"2", 2}, {"1", 4}, {"1", 3}}, count: 4, wantOutOfOrder: 1, wantMissing: []uint64{}},
+		{name: "duplicate case", observed: []sequenceObservation{{"", 1}, {"", 2}, {"", 2}, {"", 3}}, count: 3, wantDuplicates: 1, wantMissing: []uint64{}},
+		{name: "loss case", observed: []sequenceObservation{{"", 1}, {"", 4}}, count: 5, wantMissing: []uint64{2, 3, 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := newSequenceTracker()
+			for _, o := range tt.observed {
+				tracker.observe(o.worker, o.seq)
+			}
+			if tracker.outOfOrder != tt.wantOutOfOrder {
+				t.Errorf("%s: outOfOrder = %d; want %d", tt.name, tracker.outOfOrder, tt.wantOutOfOrder)
+			}
+			if tracker.duplicates != tt.wantDuplicates {
+				t.Errorf("%s: duplicates = %d; want %d", tt.name, tracker.duplicates, tt.wantDuplicates)
+			}
+			if got := tracker.missing(tt.count); !reflect.DeepEqual(got, tt.wantMissing) {
+				t.Errorf("%s: missing(%d) = %v; want %v", tt.name, tt.count, got, tt.wantMissing)
+			}
+		})
+	}
+}
+
+func TestInflightLimit(t *testing.T) {
+	receiveMaximum := uint16(10)
+	withReceiveMaximum := &paho.Connack{Properties: &paho.ConnackProperties{ReceiveMaximum: &receiveMaximum}}
+
+	// Table Driven Test
+	tests := []struct {
+		name        string
+		maxInflight int
+		ca          *paho.Connack
+		want        int
+	}{
+		{name: "no connack case", maxInflight: 5, ca: nil, want: 5},
+		{name: "below ceiling case", maxInflight: 5, ca: withReceiveMaximum, want: 5},
+		{name: "above ceiling case", maxInflight: 20, ca: withReceiveMaximum, want: 10},
+		{name: "unbounded case", maxInflight: 0, ca: withReceiveMaximum, want: 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := inflightLimit(tt.maxInflight, tt.ca); got != tt.want {
+				t.Errorf("%s: inflightLimit(%d) = %d; want %d", tt.name, tt.maxInflight, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPublishSequence(t *testing.T) {
+	// Table Driven Test
+	tests := []struct {
+		name    string
+		count   uint64
+		workers int
+	}{
+		{name: "single worker case", count: 50, workers: 1},
+		{name: "several workers case", count: 500, workers: 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The messages are recorded in the order they go out
+			var mu sync.Mutex
+			var sent []sequenceObservation
+			publish := func(_ context.Context, p *paho.Publish) (*paho.PublishResponse, error) {
+				seq, err := strconv.ParseUint(p.Properties.User.Get(sequenceProperty), 10, 64)
+				if err != nil {
+					t.Errorf("%s: invalid %s property: %s", tt.name, sequenceProperty, err)
+				}
+				mu.Lock()
+				sent = append(sent, sequenceObservation{worker: p.Properties.User.Get(workerProperty), seq: seq})
+				mu.Unlock()
+				return &paho.PublishResponse{}, nil
+			}
+			if failed := publishSequence(context.Background(), publish, "topic", 1, tt.count, tt.workers, 0); failed != 0 {
+				t.Errorf("%s: publishSequence() = %d failed; want 0", tt.name, failed)
+			}
+
+			tracker := newSequenceTracker()
+			for _, o := range sent {
+				tracker.observe(o.worker, o.seq)
+			}
+			if tracker.outOfOrder != 0 || tracker.duplicates != 0 {
+				t.Errorf("%s: %s; want every worker to publish in order without duplicates", tt.name, tracker.summary())
+			}
+			if missing := tracker.missing(tt.count); len(missing) != 0 || uint64(len(sent)) != tt.count {
+				t.Errorf("%s: published %d message(s), missing %v; want %d", tt.name, len(sent), missing, tt.count)
+			}
+		})
+	}
+}
+
+func TestSequenceTrackerResult(t *testing.T) {
+	tracker := newSequenceTracker()
+	for _, o := range []sequenceObservation{{"1", 1}, {"1", 3}, {"1", 2}, {"1", 3}} {
+		tracker.observe(o.worker, o.seq)
+	}
+	tracker.observeInvalid()
+
+	want := &sequenceReceived{Messages: 4, OutOfOrder: 1, Duplicates: 1, WithoutSequence: 1, Missing: []uint64{4}}
+	if got := tracker.result(4); !reflect.DeepEqual(got, want) {
+		t.Errorf("result(4) = %+v; want %+v", got, want)
+	}
+}
@@ -0,0 +1,151 @@
+/*
+Copyright © 2024 ks6088ts
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package iot
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/eclipse/paho.golang/paho"
+	"github.com/ks6088ts-labs/misctl/internal"
+	"github.com/spf13/cobra"
+)
+
+// retainedMessage is a retained message captured by the snapshot command
+type retainedMessage struct {
+	Topic   string `json:"topic"`
+	Payload string `json:"payload"`
+	QoS     byte   `json:"qos"`
+}
+
+// snapshotCmd represents the snapshot command
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Capture the retained messages of a topic space",
+	Long: `This command will subscribe to the specified topic filter, collect the retained messages
+delivered by the broker during a short window, print them and exit.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		out := cmd.OutOrStdout()
+		// Parse flags
+		env, err := cmd.Flags().GetString("env")
+		if err != nil {
+			log.Fatalf("could not get `env` flag: %s", err)
+		}
+		topic, err := cmd.Flags().GetString("topic")
+		if err != nil {
+			log.Fatalf("could not get `topic` flag: %s", err)
+		}
+		window, err := cmd.Flags().GetDuration("window")
+		if err != nil {
+			log.Fatalf("could not get `window` flag: %s", err)
+		}
+		save, err := cmd.Flags().GetString("save")
+		if err != nil {
+			log.Fatalf("could not get `save` flag: %s", err)
+		}
+		pretty, err := cmd.Flags().GetBool("pretty")
+		if err != nil {
+			log.Fatalf("could not get `pretty` flag: %s", err)
+		}
+		cs := loadConnectionSettings(env)
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		var mu sync.Mutex
+		retained := []retainedMessage{}
+		live := 0
+		c, _, err := connect(ctx, out, cs, paho.ClientConfig{
+			Router: paho.NewSingleHandlerRouter(func(m *paho.Publish) {
+				mu.Lock()
+				defer mu.Unlock()
+				// Retained messages are delivered right after subscribing, anything else is live traffic
+				if !m.Retain {
+					live++
+					return
+				}
+				retained = append(retained, retainedMessage{Topic: m.Topic, Payload: string(m.Payload), QoS: m.QoS})
+			}),
+		})
+		if err != nil {
+			internal.Fatalf(connectErrorCode(err), "%s", err)
+		}
+
+		if _, err := c.Subscribe(ctx, &paho.Subscribe{
+			Subscriptions: []paho.SubscribeOptions{
+				{Topic: topic, QoS: byte(1)},
+			},
+		}); err != nil {
+			internal.Fatalf(internal.CodeRuntime, "could not subscribe to topic: %s", err)
+		}
+
+		select {
+		case <-time.After(window):
+		case <-ctx.Done():
+		}
+		if err := c.Disconnect(&paho.Disconnect{ReasonCode: 0}); err != nil {
+			log.Printf("could not disconnect: %s", err)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if internal.JSONOutput() {
+			if err := internal.PrintJSON(out, retained, pretty); err != nil {
+				internal.Fatalf(internal.CodeRuntime, "could not print snapshot: %s", err)
+			}
+		} else {
+			for _, m := range retained {
+				fmt.Fprintf(out, "retained message on topic %s; body: %s (qos: %d)\n", m.Topic, m.Payload, m.QoS)
+			}
+			fmt.Fprintf(out, "%d retained message(s), %d live message(s) ignored\n", len(retained), live)
+		}
+
+		if save != "" {
+			data, err := internal.MarshalJSON(retained, pretty)
+			if err != nil {
+				internal.Fatalf(internal.CodeRuntime, "could not marshal snapshot: %s", err)
+			}
+			if err := os.WriteFile(save, data, 0644); err != nil {
+				internal.Fatalf(internal.CodeRuntime, "could not write %s: %s", save, err)
+			}
+			fmt.Fprintf(internal.Decorative(out), "Saved snapshot to %s\n", save)
+		}
+	},
+}
+
+func init() {
+	iotCmd.AddCommand(snapshotCmd)
+
+	snapshotCmd.Flags().StringP("env", "e", "", "Path to .env file")
+	snapshotCmd.Flags().StringP("topic", "t", "#", "Topic filter to subscribe to")
+	snapshotCmd.Flags().DurationP("window", "w", 2*time.Second, "How long to collect retained messages")
+	snapshotCmd.Flags().StringP("save", "s", "", "Path to a JSON file to save the snapshot to")
+
+	if err := snapshotCmd.MarkFlagRequired("env"); err != nil {
+		log.Fatalf("could not mark `env` as required: %s", err)
+	}
+}
@@ -0,0 +1,522 @@
+/*
+Copyright © 2024 ks6088ts
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package iot
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"regexp"
+	"sync/atomic"
+	"syscall"
+	"text/template"
+	"time"
+
+	"github.com/eclipse/paho.golang/paho"
+	"github.com/ks6088ts-labs/misctl/internal"
+	"github.com/spf13/cobra"
+)
+
+// deduper suppresses consecutive identical payloads received on the same topic
+type deduper struct {
+	last    map[string][]byte
+	repeats map[string]int
+}
+
+func newDeduper() *deduper {
+	return &deduper{last: map[string][]byte{}, repeats: map[string]int{}}
+}
+
+// observe records a message and reports whether it repeats the previous payload of its topic.
+// When the payload changes, the number of suppressed repeats of the previous payload is returned.
+func (d *deduper) observe(topic string, payload []byte) (duplicate bool, repeats int) {
+	last, ok := d.last[topic]
+	if ok && bytes.Equal(last, payload) {
+		d.repeats[topic]++
+		return true, 0
+	}
+	repeats = d.repeats[topic]
+	d.last[topic] = bytes.Clone(payload)
+	d.repeats[topic] = 0
+	return false, repeats
+}
+
+// hexdumpMessage formats the message header followed by its payload as a hex/ASCII dump like `hexdump -C`
+func hexdumpMessage(m *paho.Publish) string {
+	return fmt.Sprintf("received message on topic %s; %d bytes (retain: %t)\n%s", m.Topic, len(m.Payload), m.Retain, hex.Dump(m.Payload))
+}
+
+// receivedMessage is a message printed with --json, in the schema of the messages posted by `iot webhook`.
+// Filter is the matching filter of the sandbox subscriptions.
+type receivedMessage struct {
+	webhookMessage
+	Filter string `json:"filter,omitempty"`
+}
+
+// messageJSON formats the message as a single JSON line
+func messageJSON(m *paho.Publish, filter string, receivedAt time.Time) (string, error) {
+	data, err := json.Marshal(receivedMessage{webhookMessage: newWebhookMessage(m, receivedAt), Filter: filter})
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// printMessage prints the message as JSON, with the template, one line per message, as a hex dump, or with the default format
+func printMessage(p *messagePrinter, m *paho.Publish, tmpl *template.Template, hexdump bool) {
+	if internal.JSONOutput() {
+		line, err := messageJSON(m, "", time.Now())
+		if err != nil {
+			log.Printf("could not format message on topic %s: %s", m.Topic, err)
+			return
+		}
+		p.printf("%s\n", line)
+		return
+	}
+	if hexdump {
+		p.printf("%s", hexdumpMessage(m))
+		return
+	}
+	if tmpl != nil {
+		line, err := renderMessage(tmpl, m)
+		if err != nil {
+			log.Printf("could not render message on topic %s: %s", m.Topic, err)
+			return
+		}
+		p.printf("%s\n", line)
+		return
+	}
+	p.printf("received message on topic %s; body: %s (retain: %t)\n", m.Topic, m.Payload, m.Retain)
+}
+
+// matchesRetain reports whether a message with the retain bit passes the --only-retained and --only-live filters
+func matchesRetain(retain, onlyRetained, onlyLive bool) bool {
+	if onlyRetained {
+		return retain
+	}
+	if onlyLive {
+		return !retain
+	}
+	return true
+}
+
+// subscribeCmd represents the subscribe command
+var subscribeCmd = &cobra.Command{
+	Use:   "subscribe",
+	Short: "Subscribe to topics and print the received messages",
+	Long: `This command will subscribe to the specified topic filters and print the received messages until interrupted.
+
+With --json, each message is printed as a JSON line
+{"topic", "payload" or "payload_base64", "qos", "retain", "properties", "received_at"},
+the schema of the messages posted by ` + "`iot webhook`" + `. The per-topic counts of --count-by-topic,
+--count-only and --stats-interval are printed as {"topics": [{"topic", "messages", "bytes"}], "messages", "bytes"}.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		out := cmd.OutOrStdout()
+		// Progress text is dropped with --json
+		text := internal.Decorative(out)
+		// Parse flags
+		env, err := cmd.Flags().GetString("env")
+		if err != nil {
+			log.Fatalf("could not get `env` flag: %s", err)
+		}
+		topics, err := cmd.Flags().GetStringArray("topic")
+		if err != nil {
+			log.Fatalf("could not get `topic` flag: %s", err)
+		}
+		qos, err := cmd.Flags().GetUint8("qos")
+		if err != nil {
+			log.Fatalf("could not get `qos` flag: %s", err)
+		}
+		dedup, err := cmd.Flags().GetBool("dedup")
+		if err != nil {
+			log.Fatalf("could not get `dedup` flag: %s", err)
+		}
+		countByTopic, err := cmd.Flags().GetBool("count-by-topic")
+		if err != nil {
+			log.Fatalf("could not get `count-by-topic` flag: %s", err)
+		}
+		statsInterval, err := cmd.Flags().GetDuration("stats-interval")
+		if err != nil {
+			log.Fatalf("could not get `stats-interval` flag: %s", err)
+		}
+		countOnly, err := cmd.Flags().GetBool("count-only")
+		if err != nil {
+			log.Fatalf("could not get `count-only` flag: %s", err)
+		}
+		retainLast, err := cmd.Flags().GetString("retain-last")
+		if err != nil {
+			log.Fatalf("could not get `retain-last` flag: %s", err)
+		}
+		retainInterval, err := cmd.Flags().GetDuration("retain-interval")
+		if err != nil {
+			log.Fatalf("could not get `retain-interval` flag: %s", err)
+		}
+		idleTimeout, err := cmd.Flags().GetDuration("idle-timeout")
+		if err != nil {
+			log.Fatalf("could not get `idle-timeout` flag: %s", err)
+		}
+		bufferSize, err := cmd.Flags().GetInt("buffer-size")
+		if err != nil {
+			log.Fatalf("could not get `buffer-size` flag: %s", err)
+		}
+		dropOnBackpressure, err := cmd.Flags().GetBool("drop-on-backpressure")
+		if err != nil {
+			log.Fatalf("could not get `drop-on-backpressure` flag: %s", err)
+		}
+		savePath, err := cmd.Flags().GetString("save")
+		if err != nil {
+			log.Fatalf("could not get `save` flag: %s", err)
+		}
+		sortSaved, err := cmd.Flags().GetBool("sort")
+		if err != nil {
+			log.Fatalf("could not get `sort` flag: %s", err)
+		}
+		uniqueSaved, err := cmd.Flags().GetBool("unique")
+		if err != nil {
+			log.Fatalf("could not get `unique` flag: %s", err)
+		}
+		if (sortSaved || uniqueSaved) && savePath == "" {
+			internal.Fatalf(internal.CodeUsage, "--sort and --unique require --save")
+		}
+		formatTemplate, err := cmd.Flags().GetString("format-template")
+		if err != nil {
+			log.Fatalf("could not get `format-template` flag: %s", err)
+		}
+		var tmpl *template.Template
+		if formatTemplate != "" {
+			tmpl, err = parseMessageTemplate(formatTemplate)
+			if err != nil {
+				internal.Fatalf(internal.CodeUsage, "%s", err)
+			}
+		}
+		hexdump, err := cmd.Flags().GetBool("hexdump")
+		if err != nil {
+			log.Fatalf("could not get `hexdump` flag: %s", err)
+		}
+		if hexdump && tmpl != nil {
+			internal.Fatalf(internal.CodeUsage, "--hexdump and --format-template are mutually exclusive")
+		}
+		if internal.JSONOutput() && (hexdump || tmpl != nil) {
+			internal.Fatalf(internal.CodeUsage, "--json cannot be used with --hexdump or --format-template")
+		}
+		sampleRate, err := cmd.Flags().GetFloat64("sample-rate")
+		if err != nil {
+			log.Fatalf("could not get `sample-rate` flag: %s", err)
+		}
+		var sample *sampler
+		if sampleRate != 1 {
+			sample, err = newSampler(sampleRate)
+			if err != nil {
+				internal.Fatalf(internal.CodeUsage, "%s", err)
+			}
+		}
+		filter, err := cmd.Flags().GetString("filter")
+		if err != nil {
+			log.Fatalf("could not get `filter` flag: %s", err)
+		}
+		var filterRe *regexp.Regexp
+		if filter != "" {
+			filterRe, err = regexp.Compile(filter)
+			if err != nil {
+				internal.Fatalf(internal.CodeUsage, "invalid `filter` %q: %s", filter, err)
+			}
+		}
+		exitOnFirst, err := cmd.Flags().GetBool("exit-on-first-message")
+		if err != nil {
+			log.Fatalf("could not get `exit-on-first-message` flag: %s", err)
+		}
+		heartbeatValue, err := cmd.Flags().GetString("heartbeat")
+		if err != nil {
+			log.Fatalf("could not get `heartbeat` flag: %s", err)
+		}
+		var beat heartbeat
+		if heartbeatValue != "" {
+			beat, err = parseHeartbeat(heartbeatValue)
+			if err != nil {
+				internal.Fatalf(internal.CodeUsage, "%s", err)
+			}
+		}
+		onlyRetained, err := cmd.Flags().GetBool("only-retained")
+		if err != nil {
+			log.Fatalf("could not get `only-retained` flag: %s", err)
+		}
+		onlyLive, err := cmd.Flags().GetBool("only-live")
+		if err != nil {
+			log.Fatalf("could not get `only-live` flag: %s", err)
+		}
+		if onlyRetained && onlyLive {
+			internal.Fatalf(internal.CodeUsage, "--only-retained and --only-live are mutually exclusive")
+		}
+		if exitOnFirst && countOnly {
+			internal.Fatalf(internal.CodeUsage, "--exit-on-first-message cannot be used with --count-only")
+		}
+		if bufferSize < 0 {
+			internal.Fatalf(internal.CodeUsage, "invalid `buffer-size` %d, must not be negative", bufferSize)
+		}
+		cs := loadConnectionSettings(env)
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		var saver *messageSaver
+		if savePath != "" {
+			saver, err = newMessageSaver(savePath)
+			if err != nil {
+				internal.Fatalf(internal.CodeRuntime, "%s", err)
+			}
+		}
+		printer := newMessagePrinter(out, bufferSize, dropOnBackpressure)
+		d := newDeduper()
+		stats := newTopicStats()
+		last := &lastMessage{}
+		var received atomic.Int64
+		activity := make(chan struct{}, 1)
+		first := make(chan struct{})
+		var firstSeen atomic.Bool
+		serverDisconnect := make(chan *paho.Disconnect, 1)
+		c, _, err := connect(ctx, out, cs, paho.ClientConfig{
+			Router: paho.NewSingleHandlerRouter(func(m *paho.Publish) {
+				stats.observe(m.Topic, len(m.Payload))
+				// Messages not matching the filters neither count as activity nor are printed
+				if !matchesRetain(m.Retain, onlyRetained, onlyLive) || (filterRe != nil && !filterRe.Match(m.Payload)) {
+					return
+				}
+				if exitOnFirst && firstSeen.Load() {
+					return
+				}
+				received.Add(1)
+				select {
+				case activity <- struct{}{}:
+				default:
+				}
+				// Skip our own republished messages so the source payload is kept
+				if retainLast != "" && m.Topic != retainLast {
+					last.set(m.Topic, m.Payload)
+				}
+				// Statistics count every message, printing and saving only the sampled ones
+				if sample != nil && !sample.keep() {
+					return
+				}
+				if saver != nil {
+					if err := saver.save(m); err != nil {
+						log.Printf("could not save message: %s", err)
+					}
+				}
+				if countOnly {
+					return
+				}
+				if dedup {
+					duplicate, repeats := d.observe(m.Topic, m.Payload)
+					if duplicate {
+						return
+					}
+					if repeats > 0 && !internal.JSONOutput() {
+						printer.printf("previous message on topic %s repeated %d more time(s)\n", m.Topic, repeats)
+					}
+				}
+				printMessage(printer, m, tmpl, hexdump)
+				if exitOnFirst && firstSeen.CompareAndSwap(false, true) {
+					close(first)
+				}
+			}),
+			OnServerDisconnect: func(d *paho.Disconnect) {
+				select {
+				case serverDisconnect <- d:
+				default:
+				}
+			},
+		})
+		if err != nil {
+			internal.Fatalf(connectErrorCode(err), "%s", err)
+		}
+
+		subscriptions := make([]paho.SubscribeOptions, 0, len(topics))
+		for _, topic := range topics {
+			subscriptions = append(subscriptions, paho.SubscribeOptions{Topic: topic, QoS: qos})
+		}
+		if _, err := c.Subscribe(ctx, &paho.Subscribe{Subscriptions: subscriptions}); err != nil {
+			internal.Fatalf(internal.CodeRuntime, "could not subscribe to topic: %s", err)
+		}
+
+		if statsInterval > 0 {
+			go func() {
+				ticker := time.NewTicker(statsInterval)
+				defer ticker.Stop()
+				for {
+					select {
+					case <-ticker.C:
+						stats.print(out)
+					case <-ctx.Done():
+						return
+					}
+				}
+			}()
+		}
+
+		if retainLast != "" {
+			go func() {
+				// SIGHUP triggers a republish, the interval is optional
+				hup := make(chan os.Signal, 1)
+				signal.Notify(hup, syscall.SIGHUP)
+				defer signal.Stop(hup)
+				var tick <-chan time.Time
+				if retainInterval > 0 {
+					ticker := time.NewTicker(retainInterval)
+					defer ticker.Stop()
+					tick = ticker.C
+				}
+				for {
+					select {
+					case <-hup:
+						republishLast(ctx, text, c, last, retainLast, qos)
+					case <-tick:
+						republishLast(ctx, text, c, last, retainLast, qos)
+					case <-ctx.Done():
+						return
+					}
+				}
+			}()
+		}
+
+		var heartbeatDone <-chan struct{}
+		if beat.topic != "" {
+			heartbeatDone = beat.run(ctx, text, c, qos)
+		}
+
+		idle := make(chan struct{})
+		if idleTimeout > 0 {
+			go func() {
+				timer := time.NewTimer(idleTimeout)
+				defer timer.Stop()
+				for {
+					select {
+					case <-activity:
+						// Reset the window on every message
+						if !timer.Stop() {
+							<-timer.C
+						}
+						timer.Reset(idleTimeout)
+					case <-timer.C:
+						close(idle)
+						return
+					case <-ctx.Done():
+						return
+					}
+				}
+			}()
+		}
+
+		// Wait for user to trigger exit, for the idle timeout or for the first message
+		idled := false
+		var disconnectErr error
+		select {
+		case <-ctx.Done():
+			fmt.Fprintf(text, "%s - exiting\n", internal.DoneReason(ctx))
+		case <-idle:
+			idled = true
+			fmt.Fprintf(text, "no message received for %s - exiting\n", idleTimeout)
+			stop()
+		case <-first:
+			stop()
+		case d := <-serverDisconnect:
+			// Reported on exit, once the messages are saved
+			disconnectErr = serverDisconnectError(d)
+			stop()
+		}
+		// Let the heartbeat stop before disconnecting
+		if heartbeatDone != nil {
+			<-heartbeatDone
+		}
+		// The broker already closed the connection after its DISCONNECT
+		if disconnectErr == nil {
+			if err := c.Disconnect(&paho.Disconnect{ReasonCode: 0}); err != nil {
+				log.Printf("could not disconnect: %s", err)
+			}
+		}
+		if dropped := printer.close(); dropOnBackpressure {
+			fmt.Fprintf(text, "%d message(s) dropped due to backpressure\n", dropped)
+		}
+		if countByTopic || countOnly {
+			stats.print(out)
+		}
+		if sample != nil {
+			fmt.Fprintln(text, sample.summary())
+		}
+		if saver != nil {
+			if err := saver.close(); err != nil {
+				internal.Fatalf(internal.CodeRuntime, "could not write save file: %s", err)
+			}
+			// Normalize once the session is over so the live output is untouched
+			if sortSaved || uniqueSaved {
+				before, after, err := normalizeFile(savePath, sortSaved, uniqueSaved)
+				if err != nil {
+					internal.Fatalf(internal.CodeRuntime, "could not normalize save file: %s", err)
+				}
+				fmt.Fprintf(text, "Normalized %s, kept %d of %d record(s)\n", savePath, after, before)
+			} else {
+				fmt.Fprintf(text, "Saved messages to %s\n", savePath)
+			}
+		}
+		if disconnectErr != nil {
+			internal.Exit(disconnectErr)
+		}
+		if idled && received.Load() == 0 {
+			internal.Fatalf(internal.CodeTimeout, "idle timeout: no message received within %s", idleTimeout)
+		}
+	},
+}
+
+func init() {
+	iotCmd.AddCommand(subscribeCmd)
+
+	subscribeCmd.Flags().StringP("env", "e", "", "Path to .env file")
+	subscribeCmd.Flags().StringArrayP("topic", "t", []string{"#"}, "Topic filter to subscribe to")
+	subscribeCmd.Flags().Uint8P("qos", "q", 1, "QoS level")
+	subscribeCmd.Flags().Bool("dedup", false, "Suppress consecutive identical messages on the same topic")
+	subscribeCmd.Flags().Bool("count-by-topic", false, "Print the message count and bytes received per topic on exit")
+	subscribeCmd.Flags().Duration("stats-interval", 0, "Print the per-topic counts periodically, 0 to disable")
+	subscribeCmd.Flags().Bool("count-only", false, "Only count messages per topic instead of printing them")
+	subscribeCmd.Flags().Duration("idle-timeout", 0, "Exit when no message arrives within this duration, with a non-zero code if none arrived at all, 0 to disable")
+	subscribeCmd.Flags().String("filter", "", "Regular expression the raw payload bytes must match for the message to be printed, saved and counted as activity")
+	subscribeCmd.Flags().Bool("only-retained", false, "Only handle the retained messages, i.e. the initial state of the topics")
+	subscribeCmd.Flags().Bool("only-live", false, "Only handle the live messages, ignoring the retained ones")
+	subscribeCmd.Flags().Bool("exit-on-first-message", false, "Exit with code 0 after printing the first matching message, use with --idle-timeout to fail when none arrives")
+	subscribeCmd.Flags().String("format-template", "", "Go template applied to each message, with .Topic, .Payload, .QoS, .Retain and .Properties, instead of the default line")
+	subscribeCmd.Flags().Bool("hexdump", false, "Print the payloads as a hex/ASCII dump like `hexdump -C` instead of text, for binary payloads")
+	subscribeCmd.Flags().Float64("sample-rate", 1, "Fraction of the messages printed and saved, e.g. 0.01 for 1%, while the statistics count all of them")
+	subscribeCmd.Flags().Int("buffer-size", 1024, "Number of messages buffered between the broker and the output")
+	subscribeCmd.Flags().Bool("drop-on-backpressure", false, "Drop and count messages instead of blocking when the output buffer is full")
+	subscribeCmd.Flags().String("save", "", "Path to write the received messages to as JSON lines (topic, payload)")
+	subscribeCmd.Flags().Bool("sort", false, "Sort the records of --save once the session ends")
+	subscribeCmd.Flags().Bool("unique", false, "Remove the duplicate records of --save once the session ends")
+	subscribeCmd.Flags().String("heartbeat", "", "Publish a timestamped liveness payload in the background as <topic>:<interval>, e.g. devices/d1/presence:30s")
+	subscribeCmd.Flags().String("retain-last", "", "Republish the last received payload to this topic as retained on SIGHUP or every --retain-interval")
+	subscribeCmd.Flags().Duration("retain-interval", 0, "Interval between --retain-last republishes, 0 to only republish on SIGHUP")
+
+	if err := subscribeCmd.MarkFlagRequired("env"); err != nil {
+		log.Fatalf("could not mark `env` as required: %s", err)
+	}
+}
@@ -0,0 +1,127 @@
+package iot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/eclipse/paho.golang/paho"
+)
+
+func TestDeduper(t *testing.T) {
+	type message struct {
+		topic   string
+		payload string
+	}
+	type result struct {
+		duplicate bool
+		repeats   int
+	}
+
+	// Table Driven Test
+	tests := []struct {
+		name     string
+		messages []message
+		want     []result
+	}{
+		{
+			name:     "distinct payloads case",
+			messages: []message{{"a", "1"}, {"a", "2"}},
+			want:     []result{{false, 0}, {false, 0}},
+		},
+		{
+			name:     "repeated payload case",
+			messages: []message{{"a", "1"}, {"a", "1"}, {"a", "1"}, {"a", "2"}},
+			want:     []result{{false, 0}, {true, 0}, {true, 0}, {false, 2}},
+		},
+		{
+			name:     "per topic case",
+			messages: []message{{"a", "1"}, {"b", "1"}, {"a", "1"}},
+			want:     []result{{false, 0}, {false, 0}, {true, 0}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newDeduper()
+			for i, m := range tt.messages {
+				duplicate, repeats := d.observe(m.topic, []byte(m.payload))
+				if got := (result{duplicate, repeats}); got != tt.want[i] {
+					t.Errorf("%s: message %d: observe(%q, %q) = %+v; want %+v", tt.name, i, m.topic, m.payload, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestMatchesRetain(t *testing.T) {
+	// Table Driven Test
+	tests := []struct {
+		name         string
+		retain       bool
+		onlyRetained bool
+		onlyLive     bool
+		want         bool
+	}{
+		{name: "no filter retained case", retain: true, want: true},
+		{name: "no filter live case", retain: false, want: true},
+		{name: "only retained with retained case", retain: true, onlyRetained: true, want: true},
+		{name: "only retained with live case", retain: false, onlyRetained: true, want: false},
+		{name: "only live with retained case", retain: true, onlyLive: true, want: false},
+		{name: "only live with live case", retain: false, onlyLive: true, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchesRetain(tt.retain, tt.onlyRetained, tt.onlyLive); got != tt.want {
+				t.Errorf("%s: matchesRetain(%t, %t, %t) = %t; want %t", tt.name, tt.retain, tt.onlyRetained, tt.onlyLive, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHexdumpMessage(t *testing.T) {
+	m := &paho.Publish{Topic: "sensors/raw", Payload: []byte{0x00, 0xff, 'h', 'i', '\n'}, Retain: true}
+	want := "received message on topic sensors/raw; 5 bytes (retain: true)\n" +
+		"00000000  00 ff 68 69 0a                                    |..hi.|\n"
+	if got := hexdumpMessage(m); got != want {
+		t.Errorf("hexdumpMessage() = %q; want %q", got, want)
+	}
+}
+
+func TestMessageJSON(t *testing.T) {
+	receivedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	// Table Driven Test
+	tests := []struct {
+		name   string
+		m      *paho.Publish
+		filter string
+		want   string
+	}{
+		{
+			name: "text payload case",
+			m:    &paho.Publish{Topic: "a/b", QoS: 1, Payload: []byte("hello")},
+			want: `{"topic":"a/b","payload":"hello","qos":1,"retain":false,"received_at":"2024-01-02T03:04:05Z"}`,
+		},
+		{
+			name: "binary payload case",
+			m:    &paho.Publish{Topic: "a/b", Retain: true, Payload: []byte{0xff, 0xfe}},
+			want: `{"topic":"a/b","payload_base64":"//4=","qos":0,"retain":true,"received_at":"2024-01-02T03:04:05Z"}`,
+		},
+		{
+			name:   "filter case",
+			m:      &paho.Publish{Topic: "a/b", Payload: []byte("hi")},
+			filter: "a/+",
+			want:   `{"topic":"a/b","payload":"hi","qos":0,"retain":false,"received_at":"2024-01-02T03:04:05Z","filter":"a/+"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := messageJSON(tt.m, tt.filter, receivedAt)
+			if err != nil {
+				t.Fatalf("%s: messageJSON() error = %v", tt.name, err)
+			}
+			if got != tt.want {
+				t.Errorf("%s: messageJSON() = %s; want %s", tt.name, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package iot
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/eclipse/paho.golang/paho"
+)
+
+// templateMessage holds the fields of a received message available to --format-template
+type templateMessage struct {
+	Topic      string
+	Payload    string
+	QoS        byte
+	Retain     bool
+	Properties map[string]string
+}
+
+// parseMessageTemplate parses a --format-template, missing properties render as empty strings
+func parseMessageTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("message").Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid format template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// renderMessage applies the template to the message
+func renderMessage(tmpl *template.Template, m *paho.Publish) (string, error) {
+	var b strings.Builder
+	err := tmpl.Execute(&b, templateMessage{
+		Topic:      m.Topic,
+		Payload:    string(m.Payload),
+		QoS:        m.QoS,
+		Retain:     m.Retain,
+		Properties: messageProperties(m.Properties),
+	})
+	if err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
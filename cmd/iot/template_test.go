@@ -0,0 +1,51 @@
+package iot
+
+import (
+	"testing"
+
+	"github.com/eclipse/paho.golang/paho"
+)
+
+func TestRenderMessage(t *testing.T) {
+	m := &paho.Publish{
+		Topic:   "sensors/1",
+		QoS:     1,
+		Retain:  true,
+		Payload: []byte(`{"t":21}`),
+		Properties: &paho.PublishProperties{
+			ContentType: "application/json",
+			User:        paho.UserProperties{{Key: "site", Value: "tokyo"}},
+		},
+	}
+
+	// Table Driven Test
+	tests := []struct {
+		name    string
+		text    string
+		want    string
+		wantErr bool
+	}{
+		{name: "fields case", text: "{{.Topic}} q{{.QoS}} r={{.Retain}} {{.Payload}}", want: `sensors/1 q1 r=true {"t":21}`},
+		{name: "properties case", text: "{{.Properties.site}} {{.Properties.content_type}}", want: "tokyo application/json"},
+		{name: "missing property case", text: "[{{.Properties.missing}}]", want: "[]"},
+		{name: "invalid template case", text: "{{.Topic", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := parseMessageTemplate(tt.text)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("%s: parseMessageTemplate(%q) error = %v; want error %t", tt.name, tt.text, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			got, err := renderMessage(tmpl, m)
+			if err != nil {
+				t.Fatalf("%s: renderMessage error = %v", tt.name, err)
+			}
+			if got != tt.want {
+				t.Errorf("%s: renderMessage(%q) = %q; want %q", tt.name, tt.text, got, tt.want)
+			}
+		})
+	}
+}
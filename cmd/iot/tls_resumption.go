@@ -0,0 +1,46 @@
+package iot
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+)
+
+const (
+	handshakeResumed = "resumed"
+	handshakeFull    = "full handshake"
+)
+
+// tlsSessionResumption shares a TLS session cache across the connections of a command and records
+// whether each handshake resumed a session, nil when session resumption is not tested
+var tlsSessionResumption *tlsResumption
+
+type tlsResumption struct {
+	cache      tls.ClientSessionCache
+	handshakes []string
+}
+
+func newTLSResumption() *tlsResumption {
+	return &tlsResumption{cache: tls.NewLRUClientSessionCache(0)}
+}
+
+// observe records and prints the kind of handshake of a new connection
+func (r *tlsResumption) observe(out io.Writer, state tls.ConnectionState) {
+	kind := handshakeFull
+	if state.DidResume {
+		kind = handshakeResumed
+	}
+	r.handshakes = append(r.handshakes, kind)
+	fmt.Fprintf(out, "TLS connection #%d (%s): %s\n", len(r.handshakes), tls.VersionName(state.Version), kind)
+}
+
+// notResumed returns the number of reconnections which did a full handshake, the first connection has no session to resume
+func (r *tlsResumption) notResumed() int {
+	n := 0
+	for i, kind := range r.handshakes {
+		if i > 0 && kind != handshakeResumed {
+			n++
+		}
+	}
+	return n
+}
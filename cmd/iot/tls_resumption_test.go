@@ -0,0 +1,36 @@
+package iot
+
+import (
+	"crypto/tls"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestTLSResumption(t *testing.T) {
+	// Table Driven Test
+	tests := []struct {
+		name           string
+		resumed        []bool
+		wantHandshakes []string
+		wantNotResumed int
+	}{
+		{name: "all resumed case", resumed: []bool{false, true, true}, wantHandshakes: []string{handshakeFull, handshakeResumed, handshakeResumed}},
+		{name: "not resumed case", resumed: []bool{false, false, true}, wantHandshakes: []string{handshakeFull, handshakeFull, handshakeResumed}, wantNotResumed: 1},
+		{name: "single connection case", resumed: []bool{false}, wantHandshakes: []string{handshakeFull}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTLSResumption()
+			for _, resumed := range tt.resumed {
+				r.observe(io.Discard, tls.ConnectionState{Version: tls.VersionTLS13, DidResume: resumed})
+			}
+			if !reflect.DeepEqual(r.handshakes, tt.wantHandshakes) {
+				t.Errorf("%s: handshakes = %v; want %v", tt.name, r.handshakes, tt.wantHandshakes)
+			}
+			if got := r.notResumed(); got != tt.wantNotResumed {
+				t.Errorf("%s: notResumed() = %d; want %d", tt.name, got, tt.wantNotResumed)
+			}
+		})
+	}
+}
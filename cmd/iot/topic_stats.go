@@ -0,0 +1,96 @@
+package iot
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/ks6088ts-labs/misctl/internal"
+)
+
+// topicCount is the traffic received on a single topic
+type topicCount struct {
+	Topic    string `json:"topic"`
+	Messages int    `json:"messages"`
+	Bytes    int    `json:"bytes"`
+}
+
+// topicTotals is the JSON representation of the per-topic counts
+type topicTotals struct {
+	Topics   []topicCount `json:"topics"`
+	Messages int          `json:"messages"`
+	Bytes    int          `json:"bytes"`
+}
+
+// topicStats counts the messages and payload bytes received per topic
+type topicStats struct {
+	mu     sync.Mutex
+	counts map[string]*topicCount
+}
+
+func newTopicStats() *topicStats {
+	return &topicStats{counts: map[string]*topicCount{}}
+}
+
+// observe records a message of size bytes received on topic
+func (s *topicStats) observe(topic string, size int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tc, ok := s.counts[topic]
+	if !ok {
+		tc = &topicCount{Topic: topic}
+		s.counts[topic] = tc
+	}
+	tc.Messages++
+	tc.Bytes += size
+}
+
+// sorted returns the per-topic counts sorted by volume, then by topic
+func (s *topicStats) sorted() []topicCount {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts := make([]topicCount, 0, len(s.counts))
+	for _, tc := range s.counts {
+		counts = append(counts, *tc)
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Bytes != counts[j].Bytes {
+			return counts[i].Bytes > counts[j].Bytes
+		}
+		return counts[i].Topic < counts[j].Topic
+	})
+	return counts
+}
+
+// totals returns the per-topic counts sorted by volume and their sums
+func (s *topicStats) totals() topicTotals {
+	totals := topicTotals{Topics: s.sorted()}
+	for _, tc := range totals.Topics {
+		totals.Messages += tc.Messages
+		totals.Bytes += tc.Bytes
+	}
+	return totals
+}
+
+// print writes the per-topic counts and the totals as a table, or as a JSON line with --json
+func (s *topicStats) print(out io.Writer) {
+	totals := s.totals()
+	if internal.JSONOutput() {
+		if err := internal.PrintJSON(out, totals, false); err != nil {
+			log.Printf("could not print topic counts: %s", err)
+		}
+		return
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TOPIC\tMESSAGES\tBYTES")
+	for _, tc := range totals.Topics {
+		fmt.Fprintf(w, "%s\t%d\t%d\n", tc.Topic, tc.Messages, tc.Bytes)
+	}
+	fmt.Fprintf(w, "TOTAL\t%d\t%d\n", totals.Messages, totals.Bytes)
+	w.Flush()
+}
@@ -0,0 +1,59 @@
+package iot
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTopicStats(t *testing.T) {
+	type message struct {
+		topic string
+		size  int
+	}
+
+	// Table Driven Test
+	tests := []struct {
+		name     string
+		messages []message
+		want     []topicCount
+	}{
+		{name: "empty case", messages: nil, want: []topicCount{}},
+		{
+			name:     "sorted by bytes case",
+			messages: []message{{"a", 1}, {"b", 10}, {"a", 2}},
+			want:     []topicCount{{Topic: "b", Messages: 1, Bytes: 10}, {Topic: "a", Messages: 2, Bytes: 3}},
+		},
+		{
+			name:     "tie broken by topic case",
+			messages: []message{{"b", 5}, {"a", 5}},
+			want:     []topicCount{{Topic: "a", Messages: 1, Bytes: 5}, {Topic: "b", Messages: 1, Bytes: 5}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTopicStats()
+			for _, m := range tt.messages {
+				s.observe(m.topic, m.size)
+			}
+			if got := s.sorted(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s: sorted() = %+v; want %+v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTopicStatsTotals(t *testing.T) {
+	s := newTopicStats()
+	s.observe("a", 1)
+	s.observe("b", 10)
+	s.observe("a", 2)
+
+	want := topicTotals{
+		Topics:   []topicCount{{Topic: "b", Messages: 1, Bytes: 10}, {Topic: "a", Messages: 2, Bytes: 3}},
+		Messages: 3,
+		Bytes:    13,
+	}
+	if got := s.totals(); !reflect.DeepEqual(got, want) {
+		t.Errorf("totals() = %+v; want %+v", got, want)
+	}
+}
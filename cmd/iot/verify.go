@@ -0,0 +1,182 @@
+/*
+Copyright © 2024 ks6088ts
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package iot
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/eclipse/paho.golang/paho"
+	"github.com/ks6088ts-labs/misctl/internal"
+	"github.com/spf13/cobra"
+)
+
+// verifyCmd represents the verify command
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Verify the broker traffic against a topic contract",
+	Long: `This command will subscribe to the topics of a YAML contract and check that the expected messages
+arrive within their timeouts with the required payload fields and properties, then print a pass/fail report.
+It exits with a non-zero code when any assertion fails. Example contract:
+
+  timeout: 10s
+  topics:
+    - topic: devices/+/telemetry
+      timeout: 5s
+      min_messages: 2
+      payload_pattern: temperature
+      fields:
+        temperature: number
+        ts: string
+      properties: [content_type]`,
+	Run: func(cmd *cobra.Command, args []string) {
+		out := cmd.OutOrStdout()
+		// Parse flags
+		env, err := cmd.Flags().GetString("env")
+		if err != nil {
+			log.Fatalf("could not get `env` flag: %s", err)
+		}
+		contractPath, err := cmd.Flags().GetString("contract")
+		if err != nil {
+			log.Fatalf("could not get `contract` flag: %s", err)
+		}
+		qos, err := cmd.Flags().GetUint8("qos")
+		if err != nil {
+			log.Fatalf("could not get `qos` flag: %s", err)
+		}
+		pretty, err := cmd.Flags().GetBool("pretty")
+		if err != nil {
+			log.Fatalf("could not get `pretty` flag: %s", err)
+		}
+		c, err := loadContract(contractPath)
+		if err != nil {
+			internal.Fatalf(internal.CodeConfig, "invalid contract %s: %s", contractPath, err)
+		}
+		cs := loadConnectionSettings(env)
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		// The timeouts of the contract include the connection, as queued messages may arrive right after it
+		checker := newContractChecker(c, time.Now())
+		received := make(chan struct{}, 1)
+		client, _, err := connect(ctx, out, cs, paho.ClientConfig{
+			Router: paho.NewSingleHandlerRouter(func(m *paho.Publish) {
+				checker.observe(m.Topic, m.Payload, messageProperties(m.Properties), time.Now())
+				select {
+				case received <- struct{}{}:
+				default:
+				}
+			}),
+		})
+		if err != nil {
+			internal.Fatalf(connectErrorCode(err), "%s", err)
+		}
+
+		subscriptions := make([]paho.SubscribeOptions, 0, len(c.Topics))
+		window := time.Duration(0)
+		for _, t := range c.Topics {
+			subscriptions = append(subscriptions, paho.SubscribeOptions{Topic: t.Topic, QoS: qos})
+			window = max(window, t.Timeout)
+		}
+		if _, err := client.Subscribe(ctx, &paho.Subscribe{Subscriptions: subscriptions}); err != nil {
+			internal.Fatalf(internal.CodeRuntime, "could not subscribe to topic: %s", err)
+		}
+
+		// Stop early once every topic got its messages, later messages could only add violations
+		deadline := time.After(window)
+	wait:
+		for !checker.satisfied() {
+			select {
+			case <-received:
+			case <-deadline:
+				break wait
+			case <-ctx.Done():
+				fmt.Fprintf(internal.Decorative(out), "%s - stopping\n", internal.DoneReason(ctx))
+				break wait
+			}
+		}
+		if err := client.Disconnect(&paho.Disconnect{ReasonCode: 0}); err != nil {
+			log.Printf("could not disconnect: %s", err)
+		}
+
+		results := checker.report()
+		failed := 0
+		for _, r := range results {
+			if !r.Passed {
+				failed++
+			}
+		}
+		if internal.JSONOutput() {
+			if err := internal.PrintJSON(out, results, pretty); err != nil {
+				internal.Fatalf(internal.CodeRuntime, "could not print report: %s", err)
+			}
+		} else {
+			printContractReport(out, results)
+		}
+		if failed > 0 {
+			internal.Fatalf(internal.CodeRuntime, "%d of %d topic contract(s) failed", failed, len(results))
+		}
+	},
+}
+
+// printContractReport prints one line per topic contract followed by its violations
+func printContractReport(out io.Writer, results []topicResult) {
+	passed := 0
+	for _, r := range results {
+		status := "FAIL"
+		if r.Passed {
+			status = "PASS"
+			passed++
+		}
+		first := "-"
+		if r.FirstMs != nil {
+			first = fmt.Sprintf("%.1fms", *r.FirstMs)
+		}
+		fmt.Fprintf(out, "%s %s: %d of %d message(s) received, first after %s, %d late\n", status, r.Topic, r.Received, r.MinMessages, first, r.Late)
+		if len(r.Violations) > 0 {
+			fmt.Fprintf(out, "  %s\n", strings.Join(r.Violations, "\n  "))
+		}
+	}
+	fmt.Fprintf(out, "%d of %d topic contract(s) passed\n", passed, len(results))
+}
+
+func init() {
+	iotCmd.AddCommand(verifyCmd)
+
+	verifyCmd.Flags().StringP("env", "e", "", "Path to .env file")
+	verifyCmd.Flags().String("contract", "", "Path to the YAML contract of the expected topics")
+	verifyCmd.Flags().Uint8P("qos", "q", 1, "QoS level of the subscriptions")
+
+	if err := verifyCmd.MarkFlagRequired("env"); err != nil {
+		log.Fatalf("could not mark `env` as required: %s", err)
+	}
+	if err := verifyCmd.MarkFlagRequired("contract"); err != nil {
+		log.Fatalf("could not mark `contract` as required: %s", err)
+	}
+}
@@ -0,0 +1,270 @@
+/*
+Copyright © 2024 ks6088ts
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package iot
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"github.com/eclipse/paho.golang/paho"
+	"github.com/ks6088ts-labs/misctl/internal"
+	"github.com/spf13/cobra"
+)
+
+// webhookFlushTimeout bounds the final flush once the command is interrupted
+const webhookFlushTimeout = 10 * time.Second
+
+// webhookMessage is the JSON representation of a message posted to the webhook.
+// Payloads which are not valid UTF-8 are sent base64 encoded.
+type webhookMessage struct {
+	Topic         string            `json:"topic"`
+	Payload       string            `json:"payload,omitempty"`
+	PayloadBase64 string            `json:"payload_base64,omitempty"`
+	QoS           byte              `json:"qos"`
+	Retain        bool              `json:"retain"`
+	Properties    map[string]string `json:"properties,omitempty"`
+	ReceivedAt    time.Time         `json:"received_at"`
+}
+
+// newWebhookMessage converts a received message, user properties are merged with the standard ones
+func newWebhookMessage(m *paho.Publish, receivedAt time.Time) webhookMessage {
+	wm := webhookMessage{
+		Topic:      m.Topic,
+		QoS:        m.QoS,
+		Retain:     m.Retain,
+		ReceivedAt: receivedAt,
+	}
+	if utf8.Valid(m.Payload) {
+		wm.Payload = string(m.Payload)
+	} else {
+		wm.PayloadBase64 = base64.StdEncoding.EncodeToString(m.Payload)
+	}
+
+	wm.Properties = messageProperties(m.Properties)
+	return wm
+}
+
+// messageProperties merges the user properties with the standard ones, nil when there is none
+func messageProperties(p *paho.PublishProperties) map[string]string {
+	if p == nil {
+		return nil
+	}
+	props := map[string]string{}
+	for _, u := range p.User {
+		props[u.Key] = u.Value
+	}
+	if p.ContentType != "" {
+		props["content_type"] = p.ContentType
+	}
+	if p.ResponseTopic != "" {
+		props["response_topic"] = p.ResponseTopic
+	}
+	if len(p.CorrelationData) > 0 {
+		props["correlation_data"] = base64.StdEncoding.EncodeToString(p.CorrelationData)
+	}
+	if len(props) == 0 {
+		return nil
+	}
+	return props
+}
+
+// webhookPost is the outcome of a POST to the webhook, printed as a JSON line with --json
+type webhookPost struct {
+	Time     time.Time `json:"time"`
+	Webhook  string    `json:"webhook"`
+	Messages int       `json:"messages"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// forwardMessages posts the received messages to the webhook in batches of batchSize.
+// A partial batch is posted after batchTimeout, and the remaining messages are posted once messages is closed.
+func forwardMessages(ctx context.Context, out io.Writer, messages <-chan webhookMessage, webhook string, batchSize int, batchTimeout time.Duration, retries int) {
+	pending := make([]webhookMessage, 0, batchSize)
+	flush := func(ctx context.Context) {
+		if len(pending) == 0 {
+			return
+		}
+		// A batch size of 1 posts the message itself rather than an array
+		var payload any = pending
+		if batchSize == 1 {
+			payload = pending[0]
+		}
+		post := webhookPost{Time: time.Now(), Webhook: webhook, Messages: len(pending)}
+		if err := internal.PostJSON(ctx, http.DefaultClient, webhook, payload, retries); err != nil {
+			log.Printf("could not forward %d message(s): %s", len(pending), err)
+			post.Error = err.Error()
+		} else {
+			fmt.Fprintf(internal.Decorative(out), "forwarded %d message(s) to %s\n", len(pending), webhook)
+		}
+		if internal.JSONOutput() {
+			if err := internal.PrintJSON(out, post, false); err != nil {
+				log.Printf("could not print webhook post: %s", err)
+			}
+		}
+		pending = make([]webhookMessage, 0, batchSize)
+	}
+
+	ticker := time.NewTicker(batchTimeout)
+	defer ticker.Stop()
+	for {
+		select {
+		case m, ok := <-messages:
+			if !ok {
+				flushCtx, cancel := context.WithTimeout(context.Background(), webhookFlushTimeout)
+				flush(flushCtx)
+				cancel()
+				return
+			}
+			pending = append(pending, m)
+			if len(pending) >= batchSize {
+				flush(ctx)
+			}
+		case <-ticker.C:
+			flush(ctx)
+		}
+	}
+}
+
+// webhookCmd represents the webhook command
+var webhookCmd = &cobra.Command{
+	Use:   "webhook",
+	Short: "Forward messages to a HTTP webhook",
+	Long: `This command will subscribe to the specified topic filters and POST each received message
+(topic, payload and properties) as JSON to the webhook, optionally grouped in batches.
+
+With --json, each POST is printed as a JSON line {"time", "webhook", "messages", "error"},
+where "messages" is the size of the batch and "error" is set when the POST failed after the retries.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		out := cmd.OutOrStdout()
+		// Progress text is dropped with --json
+		text := internal.Decorative(out)
+		// Parse flags
+		env, err := cmd.Flags().GetString("env")
+		if err != nil {
+			log.Fatalf("could not get `env` flag: %s", err)
+		}
+		topics, err := cmd.Flags().GetStringArray("topic")
+		if err != nil {
+			log.Fatalf("could not get `topic` flag: %s", err)
+		}
+		qos, err := cmd.Flags().GetUint8("qos")
+		if err != nil {
+			log.Fatalf("could not get `qos` flag: %s", err)
+		}
+		webhook, err := cmd.Flags().GetString("webhook")
+		if err != nil {
+			log.Fatalf("could not get `webhook` flag: %s", err)
+		}
+		batch, err := cmd.Flags().GetInt("batch")
+		if err != nil {
+			log.Fatalf("could not get `batch` flag: %s", err)
+		}
+		batchTimeout, err := cmd.Flags().GetDuration("batch-timeout")
+		if err != nil {
+			log.Fatalf("could not get `batch-timeout` flag: %s", err)
+		}
+		retries, err := cmd.Flags().GetInt("retries")
+		if err != nil {
+			log.Fatalf("could not get `retries` flag: %s", err)
+		}
+		if batch < 1 {
+			internal.Fatalf(internal.CodeUsage, "invalid `batch` %d, must be at least 1", batch)
+		}
+		if batchTimeout <= 0 {
+			internal.Fatalf(internal.CodeUsage, "invalid `batch-timeout` %s, must be positive", batchTimeout)
+		}
+		cs := loadConnectionSettings(env)
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		messages := make(chan webhookMessage, 1000)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			forwardMessages(ctx, out, messages, webhook, batch, batchTimeout, retries)
+		}()
+
+		c, _, err := connect(ctx, out, cs, paho.ClientConfig{
+			Router: paho.NewSingleHandlerRouter(func(m *paho.Publish) {
+				// Never block the router while the webhook is slow
+				select {
+				case messages <- newWebhookMessage(m, time.Now()):
+				default:
+					log.Printf("webhook backlog full, dropping message on topic %s", m.Topic)
+				}
+			}),
+			OnServerDisconnect: func(d *paho.Disconnect) {
+				fmt.Fprintf(text, "server requested disconnect; reason code: %d\n", d.ReasonCode)
+				stop()
+			},
+		})
+		if err != nil {
+			internal.Fatalf(connectErrorCode(err), "%s", err)
+		}
+
+		subscriptions := make([]paho.SubscribeOptions, 0, len(topics))
+		for _, topic := range topics {
+			subscriptions = append(subscriptions, paho.SubscribeOptions{Topic: topic, QoS: qos})
+		}
+		if _, err := c.Subscribe(ctx, &paho.Subscribe{Subscriptions: subscriptions}); err != nil {
+			internal.Fatalf(internal.CodeRuntime, "could not subscribe to topic: %s", err)
+		}
+
+		<-ctx.Done() // Wait for user to trigger exit
+		fmt.Fprintf(text, "%s - exiting\n", internal.DoneReason(ctx))
+		if err := c.Disconnect(&paho.Disconnect{ReasonCode: 0}); err != nil {
+			log.Printf("could not disconnect: %s", err)
+		}
+		// Post the messages still pending
+		close(messages)
+		<-done
+	},
+}
+
+func init() {
+	iotCmd.AddCommand(webhookCmd)
+
+	webhookCmd.Flags().StringP("env", "e", "", "Path to .env file")
+	webhookCmd.Flags().StringArrayP("topic", "t", []string{"#"}, "Topic filter to subscribe to")
+	webhookCmd.Flags().Uint8P("qos", "q", 1, "QoS level")
+	webhookCmd.Flags().String("webhook", "", "URL to POST the messages to")
+	webhookCmd.Flags().Int("batch", 1, "Number of messages posted together as a JSON array, 1 posts each message as an object")
+	webhookCmd.Flags().Duration("batch-timeout", 5*time.Second, "Maximum time a partial batch waits before being posted")
+	webhookCmd.Flags().Int("retries", 3, "Number of retries of a failed post")
+
+	if err := webhookCmd.MarkFlagRequired("env"); err != nil {
+		log.Fatalf("could not mark `env` as required: %s", err)
+	}
+	if err := webhookCmd.MarkFlagRequired("webhook"); err != nil {
+		log.Fatalf("could not mark `webhook` as required: %s", err)
+	}
+}
@@ -0,0 +1,104 @@
+package iot
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eclipse/paho.golang/paho"
+)
+
+func TestNewWebhookMessage(t *testing.T) {
+	now := time.Now()
+
+	// Table Driven Test
+	tests := []struct {
+		name string
+		m    *paho.Publish
+		want webhookMessage
+	}{
+		{
+			name: "text payload case",
+			m:    &paho.Publish{Topic: "a/b", QoS: 1, Payload: []byte("hello")},
+			want: webhookMessage{Topic: "a/b", QoS: 1, Payload: "hello", ReceivedAt: now},
+		},
+		{
+			name: "binary payload case",
+			m:    &paho.Publish{Topic: "a/b", Retain: true, Payload: []byte{0xff, 0xfe}},
+			want: webhookMessage{Topic: "a/b", Retain: true, PayloadBase64: "//4=", ReceivedAt: now},
+		},
+		{
+			name: "properties case",
+			m: &paho.Publish{Topic: "a/b", Payload: []byte("{}"), Properties: &paho.PublishProperties{
+				ContentType: "application/json",
+				User:        paho.UserProperties{{Key: "seq", Value: "1"}},
+			}},
+			want: webhookMessage{Topic: "a/b", Payload: "{}", ReceivedAt: now, Properties: map[string]string{
+				"content_type": "application/json",
+				"seq":          "1",
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newWebhookMessage(tt.m, now)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s: newWebhookMessage() = %+v; want %+v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestForwardMessages(t *testing.T) {
+	var mu sync.Mutex
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		bodies = append(bodies, string(body))
+	}))
+	defer server.Close()
+
+	// Table Driven Test
+	tests := []struct {
+		name      string
+		batch     int
+		messages  int
+		wantPosts []int
+	}{
+		{name: "single case", batch: 1, messages: 2, wantPosts: []int{-1, -1}},
+		{name: "batch case", batch: 2, messages: 3, wantPosts: []int{2, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bodies = nil
+			messages := make(chan webhookMessage, tt.messages)
+			for i := 0; i < tt.messages; i++ {
+				messages <- webhookMessage{Topic: "a/b"}
+			}
+			close(messages)
+			forwardMessages(context.Background(), io.Discard, messages, server.URL, tt.batch, time.Hour, 0)
+
+			// -1 stands for a single object rather than an array
+			got := []int{}
+			for _, body := range bodies {
+				var batch []webhookMessage
+				if err := json.Unmarshal([]byte(body), &batch); err != nil {
+					got = append(got, -1)
+					continue
+				}
+				got = append(got, len(batch))
+			}
+			if !reflect.DeepEqual(got, tt.wantPosts) {
+				t.Errorf("%s: forwardMessages posts = %v; want %v", tt.name, got, tt.wantPosts)
+			}
+		})
+	}
+}
@@ -22,18 +22,40 @@ THE SOFTWARE.
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"os"
+	"strings"
 
 	"github.com/ks6088ts-labs/misctl/cmd/http"
 	"github.com/ks6088ts-labs/misctl/cmd/iot"
+	"github.com/ks6088ts-labs/misctl/internal"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
 var cfgFile string
 
+// errorFormat is set by `--error-format`
+var errorFormat string
+
+// jsonOutput is set by `--json`
+var jsonOutput bool
+
+// outputHandle is the file of `--output-file`, nil when the output is not redirected
+var outputHandle *os.File
+
+// rawOutput is the output of the command before setRedaction wraps it, nil before the command runs
+var rawOutput io.Writer
+
+// cancelMaxRuntime releases the context created for `--max-runtime`
+var cancelMaxRuntime context.CancelFunc = func() {}
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "misctl",
@@ -43,18 +65,92 @@ examples and usage of using your application. For example:
 
 Cobra is a CLI library for Go that empowers applications.
 This application is a tool to generate the needed files
-to quickly create a Cobra application.`,
+to quickly create a Cobra application.
+
+With --json, the errors are printed to stderr as {"error": {"code", "exit_code", "message"}},
+and every command prints its results as JSON to stdout without the progress text:
+  version             {"version", "revision"}
+  doctor              an array of the checks {"name", "status", "detail", "critical"}
+  scrape              a line per url, as --stream-results
+  scrape bench        an array of {"engine", "url", "load_ms", "screenshot_ms", "error"}
+  iot init            nothing, it only writes the .env file
+  iot latency         an array of {"target", "received", "lost", "min_ms", "avg_ms", "max_ms", "p99_ms", "error"}
+  iot ping            {"connect_ms", "ping_ms", "total_ms", "tls_handshakes"}
+  iot snapshot        an array of the retained messages {"topic", "payload", "qos"}
+  iot verify          an array of the topic contract results
+  iot subscribe       a line per message, see its help
+  iot sandbox         a line per message, see its help
+  iot publish         a line per published message, see its help
+  iot sequence        a single object, see its help
+  iot seed            {"files", "published", "failed", "bytes", "failed_files"}
+  iot webhook         a line per POST to the webhook, see its help
+  http                the OpenTelemetry exporter records, a line each
+
+With --print-config, the effective configuration is printed to stderr as JSON lines at startup:
+the flags, the config file and the settings each command resolves, such as the MQTT settings
+of the .env file, with the source of each value and the secrets redacted.`,
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
 	// Run: func(cmd *cobra.Command, args []string) { },
+	// Errors are printed by Execute in the `--error-format`
+	SilenceErrors: true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// Errors are part of the machine output too
+		if jsonOutput {
+			errorFormat = internal.ErrorFormatJSON
+		}
+		internal.SetJSONOutput(jsonOutput)
+		if err := internal.SetErrorFormat(errorFormat); err != nil {
+			return internal.Errorf(internal.CodeUsage, "%w", err)
+		}
+		if err := setOutput(cmd); err != nil {
+			return err
+		}
+		if err := setRedaction(cmd); err != nil {
+			return err
+		}
+		if err := printEffectiveConfig(cmd); err != nil {
+			return err
+		}
+		return setMaxRuntime(cmd)
+	},
+	PersistentPostRunE: func(cmd *cobra.Command, args []string) error {
+		rawOutput = nil
+		if outputHandle == nil {
+			return nil
+		}
+		cmd.SetOut(nil)
+		if err := closeOutput(); err != nil {
+			return internal.Errorf(internal.CodeRuntime, "%w", err)
+		}
+		return nil
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
 	err := rootCmd.Execute()
+	cancelMaxRuntime()
+	// PersistentPostRunE does not run when the command fails
+	if closeErr := closeOutput(); closeErr != nil && err == nil {
+		err = internal.Errorf(internal.CodeRuntime, "%w", closeErr)
+	}
 	if err != nil {
-		os.Exit(1)
+		// Errors returned by cobra itself are flag and argument errors
+		var coded *internal.Error
+		if !errors.As(err, &coded) {
+			err = internal.Errorf(internal.CodeUsage, "%w", err)
+		}
+		// Flag parsing may fail before `--error-format` is validated
+		if jsonOutput {
+			errorFormat = internal.ErrorFormatJSON
+		}
+		if internal.SetErrorFormat(errorFormat) == nil && errorFormat == internal.ErrorFormatJSON {
+			internal.Exit(err)
+		}
+		fmt.Fprintln(os.Stderr, "Error:", internal.DefaultRedactor.Redact(err.Error()))
+		os.Exit(internal.ExitCode(err))
 	}
 }
 
@@ -66,12 +162,26 @@ func init() {
 	// will be global for your application.
 
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.misctl.yaml)")
+	rootCmd.PersistentFlags().Bool("pretty", false, "Indent JSON outputs for human reading")
+	rootCmd.PersistentFlags().String("output-file", "", "Write command output to this file instead of stdout")
+	rootCmd.PersistentFlags().StringVar(&errorFormat, "error-format", internal.ErrorFormatText, "Format of the error printed on failure: text or json")
+	rootCmd.PersistentFlags().BoolVar(&jsonOutput, "json", false, "Print the results and errors as JSON without progress text, see the help of misctl for the schemas")
+	rootCmd.PersistentFlags().Bool("show-secrets", false, "Do not redact passwords, SAS tokens and private keys in the output, logs and errors")
+	rootCmd.PersistentFlags().Bool("print-config", false, "Print the effective configuration to stderr as JSON lines, see the help of misctl")
+	rootCmd.PersistentFlags().Duration("max-runtime", 0, "Cancel the command after this duration, 0 for no limit")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
 	rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 
 	addSubCommands()
+
+	// internal.Fatalf exits without returning to Execute
+	internal.OnExit(func() {
+		if err := closeOutput(); err != nil {
+			log.Print(err)
+		}
+	})
 }
 
 // initConfig reads in config file and ENV variables if set.
@@ -98,6 +208,122 @@ func initConfig() {
 	}
 }
 
+// setOutput redirects the output of the command to the file given by `--output-file`.
+// Commands write through cmd.OutOrStdout() so that the output can be redirected or captured.
+func setOutput(cmd *cobra.Command) error {
+	outputFile, err := cmd.Flags().GetString("output-file")
+	if err != nil {
+		return fmt.Errorf("could not get `output-file` flag: %w", err)
+	}
+	if outputFile == "" {
+		return nil
+	}
+
+	f, err := os.Create(outputFile)
+	if err != nil {
+		return fmt.Errorf("could not create output file: %w", err)
+	}
+	outputHandle = f
+	cmd.SetOut(f)
+	return nil
+}
+
+// closeOutput closes the file of `--output-file`, if any
+func closeOutput() error {
+	if outputHandle == nil {
+		return nil
+	}
+	f := outputHandle
+	outputHandle = nil
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("could not close output file: %w", err)
+	}
+	return nil
+}
+
+// setRedaction masks the secrets in the command output and the logs, unless `--show-secrets` is set.
+// Commands register the secret values they load with internal.DefaultRedactor.
+func setRedaction(cmd *cobra.Command) error {
+	showSecrets, err := cmd.Flags().GetBool("show-secrets")
+	if err != nil {
+		return fmt.Errorf("could not get `show-secrets` flag: %w", err)
+	}
+	internal.DefaultRedactor.SetEnabled(!showSecrets)
+	rawOutput = cmd.OutOrStdout()
+	cmd.SetOut(internal.DefaultRedactor.Writer(cmd.OutOrStdout()))
+	cmd.SetErr(internal.DefaultRedactor.Writer(cmd.ErrOrStderr()))
+	log.SetOutput(internal.DefaultRedactor.Writer(log.Writer()))
+	return nil
+}
+
+// unredactedOutput returns the output of the command without the redaction, for binary output such as the PNG of `scrape --stdout`
+func unredactedOutput(cmd *cobra.Command) io.Writer {
+	if rawOutput == nil {
+		return cmd.OutOrStdout()
+	}
+	return rawOutput
+}
+
+// printEffectiveConfig prints the flags of the command and the config file settings to stderr when `--print-config` is set.
+// Commands print the sections they resolve later themselves, such as the MQTT settings of the .env file.
+func printEffectiveConfig(cmd *cobra.Command) error {
+	printConfig, err := cmd.Flags().GetBool("print-config")
+	if err != nil {
+		return fmt.Errorf("could not get `print-config` flag: %w", err)
+	}
+	pretty, err := cmd.Flags().GetBool("pretty")
+	if err != nil {
+		return fmt.Errorf("could not get `pretty` flag: %w", err)
+	}
+	internal.SetPrintConfig(printConfig, pretty)
+	if !printConfig {
+		return nil
+	}
+
+	flags := map[string]internal.ConfigValue{}
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		source := internal.ConfigSourceDefault
+		if f.Changed {
+			source = internal.ConfigSourceFlag
+		}
+		flags[f.Name] = internal.ConfigValue{Value: f.Value.String(), Source: source}
+	})
+	if err := internal.WriteConfig(cmd.ErrOrStderr(), internal.EffectiveConfig{Section: cmd.CommandPath(), Settings: flags}); err != nil {
+		return fmt.Errorf("could not print configuration: %w", err)
+	}
+
+	// AutomaticEnv makes the environment override the config file
+	settings := map[string]internal.ConfigValue{}
+	for _, key := range viper.AllKeys() {
+		source := internal.ConfigSourceFile
+		if _, ok := os.LookupEnv(strings.ToUpper(key)); ok {
+			source = internal.ConfigSourceEnv
+		}
+		settings[key] = internal.ConfigValue{Value: fmt.Sprint(viper.Get(key)), Source: source}
+	}
+	if err := internal.WriteConfig(cmd.ErrOrStderr(), internal.EffectiveConfig{Section: "config", Path: viper.ConfigFileUsed(), Settings: settings}); err != nil {
+		return fmt.Errorf("could not print configuration: %w", err)
+	}
+	return nil
+}
+
+// setMaxRuntime bounds the context of the command by `--max-runtime`.
+// Commands derive their context from cmd.Context() and shut down cleanly once it is done.
+func setMaxRuntime(cmd *cobra.Command) error {
+	maxRuntime, err := cmd.Flags().GetDuration("max-runtime")
+	if err != nil {
+		return fmt.Errorf("could not get `max-runtime` flag: %w", err)
+	}
+	if maxRuntime <= 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(cmd.Context(), maxRuntime)
+	cancelMaxRuntime = cancel
+	cmd.SetContext(ctx)
+	return nil
+}
+
 // addSubCommands registers sub commands
 func addSubCommands() {
 	rootCmd.AddCommand(iot.GetCommand())
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"bytes"
+	"log"
+	"testing"
+
+	"github.com/ks6088ts-labs/misctl/internal"
+	"github.com/spf13/cobra"
+)
+
+func TestSetRedactionUnredactedOutput(t *testing.T) {
+	defer log.SetOutput(log.Writer())
+	defer func() { rawOutput = nil }()
+	defer internal.DefaultRedactor.SetEnabled(internal.DefaultRedactor.Enabled())
+
+	var buf bytes.Buffer
+	cmd := &cobra.Command{}
+	cmd.Flags().Bool("show-secrets", false, "")
+	cmd.SetOut(&buf)
+	if err := setRedaction(cmd); err != nil {
+		t.Fatal(err)
+	}
+
+	data := "sig=secret"
+	if _, err := cmd.OutOrStdout().Write([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got == data {
+		t.Errorf("setRedaction() output = %q; want it redacted", got)
+	}
+
+	buf.Reset()
+	if _, err := unredactedOutput(cmd).Write([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != data {
+		t.Errorf("unredactedOutput() output = %q; want %q", got, data)
+	}
+}
@@ -22,19 +22,41 @@ THE SOFTWARE.
 package cmd
 
 import (
+	"context"
 	"crypto/md5"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net/http"
+	neturl "net/url"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/ks6088ts-labs/misctl/internal"
 
 	"github.com/playwright-community/playwright-go"
 	"github.com/spf13/cobra"
 )
 
+// timestampedDirLayout is the per-run subdirectory name used with `--timestamped`.
+// Colons are avoided so that the name stays valid on every platform.
+const timestampedDirLayout = "2006-01-02T15-04-05"
+
+// scrapeWebhookRetries is the number of retries of a failed change notification
+const scrapeWebhookRetries = 3
+
+// resourceSampleInterval is how often `--resource-stats` samples the memory, rare enough to keep the overhead low
+const resourceSampleInterval = 500 * time.Millisecond
+
 func assertErrorToNilf(message string, err error) {
 	if err != nil {
-		log.Fatalf(message, err)
+		internal.Fatalf(internal.CodeRuntime, message, err)
 	}
 }
 
@@ -44,55 +66,871 @@ var scrapeCmd = &cobra.Command{
 	Short: "Scrape urls",
 	Long:  `Scrape urls`,
 	Run: func(cmd *cobra.Command, args []string) {
+		out := cmd.OutOrStdout()
 		// Parse flags
 		urls, err := cmd.Flags().GetStringArray("url")
 		assertErrorToNilf("failed to parse `url`: %w", err)
+		jobsFile, err := cmd.Flags().GetString("jobs")
+		assertErrorToNilf("failed to parse `jobs`: %w", err)
 		dir, err := cmd.Flags().GetString("dir")
 		assertErrorToNilf("failed to parse `dir`: %w", err)
 		headless, err := cmd.Flags().GetBool("headless")
 		assertErrorToNilf("failed to parse `headless`: %w", err)
+		timestamped, err := cmd.Flags().GetBool("timestamped")
+		assertErrorToNilf("failed to parse `timestamped`: %w", err)
+		loadStorageState, err := cmd.Flags().GetString("load-storage-state")
+		assertErrorToNilf("failed to parse `load-storage-state`: %w", err)
+		saveStorageState, err := cmd.Flags().GetString("save-storage-state")
+		assertErrorToNilf("failed to parse `save-storage-state`: %w", err)
+		devtools, err := cmd.Flags().GetBool("devtools")
+		assertErrorToNilf("failed to parse `devtools`: %w", err)
+		cacheTTL, err := cmd.Flags().GetDuration("cache-ttl")
+		assertErrorToNilf("failed to parse `cache-ttl`: %w", err)
+		noCache, err := cmd.Flags().GetBool("no-cache")
+		assertErrorToNilf("failed to parse `no-cache`: %w", err)
+		conditional, err := cmd.Flags().GetBool("conditional")
+		assertErrorToNilf("failed to parse `conditional`: %w", err)
+		insecure, err := cmd.Flags().GetBool("insecure")
+		assertErrorToNilf("failed to parse `insecure`: %w", err)
+		dismissBanners, err := cmd.Flags().GetBool("dismiss-banners")
+		assertErrorToNilf("failed to parse `dismiss-banners`: %w", err)
+		bannerSelectorsFile, err := cmd.Flags().GetString("banner-selectors")
+		assertErrorToNilf("failed to parse `banner-selectors`: %w", err)
+		repeat, err := cmd.Flags().GetDuration("repeat")
+		assertErrorToNilf("failed to parse `repeat`: %w", err)
+		if repeat < 0 {
+			internal.Fatalf(internal.CodeUsage, "invalid `repeat` %s, must not be negative", repeat)
+		}
+		notifyWebhook, err := cmd.Flags().GetString("notify-webhook")
+		assertErrorToNilf("failed to parse `notify-webhook`: %w", err)
+		diffThreshold, err := cmd.Flags().GetFloat64("diff-threshold")
+		assertErrorToNilf("failed to parse `diff-threshold`: %w", err)
+		captureRequests, err := cmd.Flags().GetBool("capture-requests")
+		assertErrorToNilf("failed to parse `capture-requests`: %w", err)
+		exportCookies, err := cmd.Flags().GetBool("export-cookies")
+		assertErrorToNilf("failed to parse `export-cookies`: %w", err)
+		streamResults, err := cmd.Flags().GetBool("stream-results")
+		assertErrorToNilf("failed to parse `stream-results`: %w", err)
+		waitFonts, err := cmd.Flags().GetBool("wait-for-fonts")
+		assertErrorToNilf("failed to parse `wait-for-fonts`: %w", err)
+		trace, err := cmd.Flags().GetString("trace")
+		assertErrorToNilf("failed to parse `trace`: %w", err)
+		scale, err := cmd.Flags().GetFloat64("scale")
+		assertErrorToNilf("failed to parse `scale`: %w", err)
+		resourceStats, err := cmd.Flags().GetBool("resource-stats")
+		assertErrorToNilf("failed to parse `resource-stats`: %w", err)
+		layout, err := cmd.Flags().GetString("layout")
+		assertErrorToNilf("failed to parse `layout`: %w", err)
+		userAgent, err := cmd.Flags().GetString("user-agent")
+		assertErrorToNilf("failed to parse `user-agent`: %w", err)
+		uaPreset, err := cmd.Flags().GetString("ua-preset")
+		assertErrorToNilf("failed to parse `ua-preset`: %w", err)
+		abortSelectors, err := cmd.Flags().GetStringArray("abort-on-selector")
+		assertErrorToNilf("failed to parse `abort-on-selector`: %w", err)
+		followRedirects, err := cmd.Flags().GetBool("follow-redirects-render")
+		assertErrorToNilf("failed to parse `follow-redirects-render`: %w", err)
+		maxRedirects, err := cmd.Flags().GetInt("max-redirects")
+		assertErrorToNilf("failed to parse `max-redirects`: %w", err)
+		referer, err := cmd.Flags().GetString("referer")
+		assertErrorToNilf("failed to parse `referer`: %w", err)
+		concurrency, err := cmd.Flags().GetInt("concurrency")
+		assertErrorToNilf("failed to parse `concurrency`: %w", err)
+		isolation, err := cmd.Flags().GetString("context-isolation")
+		assertErrorToNilf("failed to parse `context-isolation`: %w", err)
+		maxOpenFiles, err := cmd.Flags().GetInt("max-open-files")
+		assertErrorToNilf("failed to parse `max-open-files`: %w", err)
+		compress, err := cmd.Flags().GetBool("compress")
+		assertErrorToNilf("failed to parse `compress`: %w", err)
+		clean, err := cmd.Flags().GetBool("clean")
+		assertErrorToNilf("failed to parse `clean`: %w", err)
+		force, err := cmd.Flags().GetBool("force")
+		assertErrorToNilf("failed to parse `force`: %w", err)
+		install, err := cmd.Flags().GetBool("install-browsers")
+		assertErrorToNilf("failed to parse `install-browsers`: %w", err)
+		toStdout, err := cmd.Flags().GetBool("stdout")
+		assertErrorToNilf("failed to parse `stdout`: %w", err)
+		// The streamed records are the JSON output of scrape
+		streamResults = streamResults || internal.JSONOutput()
+		// Keep stdout for the streamed records, everything else goes to stderr
+		var results io.Writer
+		if streamResults {
+			results = out
+			out = cmd.ErrOrStderr()
+		}
+		// The image is the output of a --stdout scrape, everything else goes to stderr.
+		// The redaction is for text, it would corrupt the PNG.
+		var image io.Writer
+		if toStdout {
+			if streamResults || repeat > 0 || compress || cacheTTL > 0 || conditional || captureRequests || exportCookies {
+				internal.Fatalf(internal.CodeUsage, "--stdout cannot be used with --stream-results, --json, --repeat, --compress, --cache-ttl, --conditional, --capture-requests or --export-cookies")
+			}
+			image = unredactedOutput(cmd)
+			out = cmd.ErrOrStderr()
+		}
+		if notifyWebhook != "" && repeat == 0 {
+			internal.Fatalf(internal.CodeUsage, "--notify-webhook requires --repeat")
+		}
+		if scale <= 0 {
+			internal.Fatalf(internal.CodeUsage, "invalid `scale` %g, must be positive", scale)
+		}
+		if layout != layoutFlat && layout != layoutNested {
+			internal.Fatalf(internal.CodeUsage, "invalid `layout` %q, must be one of %s or %s", layout, layoutFlat, layoutNested)
+		}
+		userAgent, err = resolveUserAgent(uaPreset, userAgent)
+		if err != nil {
+			internal.Fatalf(internal.CodeUsage, "%s", err)
+		}
+		// The cache and the change detection compare with the files of the previous runs
+		if compress && (cacheTTL > 0 || conditional || notifyWebhook != "") {
+			internal.Fatalf(internal.CodeUsage, "--compress cannot be used with --cache-ttl, --conditional or --notify-webhook")
+		}
+		if err := validateWorkers(scrapeWorkersOptions{
+			Concurrency:      concurrency,
+			Isolation:        isolation,
+			Devtools:         devtools,
+			Trace:            trace,
+			SaveStorageState: saveStorageState,
+		}); err != nil {
+			internal.Fatalf(internal.CodeUsage, "%s", err)
+		}
+		if maxOpenFiles < 0 {
+			internal.Fatalf(internal.CodeUsage, "invalid `max-open-files` %d, must not be negative", maxOpenFiles)
+		}
+		openFiles := uint64(maxOpenFiles)
+		if openFiles == 0 {
+			// The limit Chromium starts with, this process runs with a raised one
+			if limit, ok := internal.OpenFilesLimit(); ok {
+				openFiles = limit
+			}
+		}
+		if referer != "" {
+			if u, err := neturl.Parse(referer); err != nil || u.Scheme == "" || u.Host == "" {
+				internal.Fatalf(internal.CodeUsage, "invalid `referer` %q, must be an absolute URL", referer)
+			}
+		}
+		if followRedirects && maxRedirects < 1 {
+			internal.Fatalf(internal.CodeUsage, "invalid `max-redirects` %d, must be at least 1", maxRedirects)
+		}
+		if !followRedirects {
+			maxRedirects = 0
+		}
+		if devtools && headless {
+			internal.Fatalf(internal.CodeUsage, "--devtools is only valid with --headless=false")
+		}
+
+		// Build the jobs, urls given with --url use the default options
+		jobs := make([]scrapeJob, 0, len(urls))
+		for _, url := range urls {
+			jobs = append(jobs, scrapeJob{URL: url})
+		}
+		if jobsFile != "" {
+			fileJobs, err := loadJobs(jobsFile)
+			assertErrorToNilf("invalid jobs file: %w", err)
+			jobs = append(jobs, fileJobs...)
+		}
+		err = checkDuplicateJobs(jobs)
+		if err != nil {
+			internal.Fatalf(internal.CodeUsage, "%s", err)
+		}
+		if install {
+			err = installBrowsers(out)
+			assertErrorToNilf("could not install browsers: %w", err)
+			// Installing alone is a valid invocation
+			if len(jobs) == 0 {
+				return
+			}
+		}
+		if len(jobs) == 0 {
+			internal.Fatalf(internal.CodeUsage, "at least one of --url or --jobs is required")
+		}
+		if toStdout && len(jobs) > 1 {
+			internal.Fatalf(internal.CodeUsage, "--stdout requires exactly one url, got %d", len(jobs))
+		}
+
+		var bannerSelectors []string
+		if dismissBanners {
+			bannerSelectors, err = loadBannerSelectors(bannerSelectorsFile)
+			assertErrorToNilf("could not load banner selectors: %w", err)
+		}
 
-		// Create output directory
 		cwd, err := os.Getwd()
 		assertErrorToNilf("could not get cwd: %w", err)
-		err = os.MkdirAll(filepath.Join(cwd, dir), os.ModePerm)
-		assertErrorToNilf("could not create output directory: %w", err)
+		// Every cycle of a repeated scrape gets its own timestamped directory
+		timestamped = timestamped || repeat > 0
+
+		if clean {
+			// Only the files named by scrape are removed, never the whole directory
+			outputs, err := findScrapeOutputs(filepath.Join(cwd, dir))
+			assertErrorToNilf("could not list previous outputs: %w", err)
+			if len(outputs) > 0 {
+				question := fmt.Sprintf("Remove %d file(s) of previous scrapes from %s?", len(outputs), dir)
+				if !force && !confirm(cmd.InOrStdin(), out, question) {
+					internal.Fatalf(internal.CodeUsage, "clean aborted, use --force to skip the confirmation")
+				}
+				err = removeScrapeOutputs(outputs)
+				assertErrorToNilf("could not clean output directory: %w", err)
+				fmt.Fprintf(out, "Removed %d file(s) of previous scrapes from %s\n", len(outputs), dir)
+			}
+		}
+
+		// The cache lives in the base directory so that it is shared across timestamped runs
+		var cache *scrapeCache
+		if cacheTTL > 0 || conditional {
+			cache, err = loadScrapeCache(filepath.Join(cwd, dir, scrapeCacheFileName))
+			assertErrorToNilf("could not load cache: %w", err)
+		}
+
+		// Stop scraping on SIGINT/SIGTERM while still cleaning up the browser
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		opts := scrapeOptions{
+			Jobs:             jobs,
+			Headless:         headless,
+			LoadStorageState: loadStorageState,
+			SaveStorageState: saveStorageState,
+			Devtools:         devtools,
+			Cache:            cache,
+			CacheTTL:         cacheTTL,
+			NoCache:          noCache,
+			Conditional:      conditional,
+			Insecure:         insecure,
+			BannerSelectors:  bannerSelectors,
+			Results:          results,
+			CaptureRequests:  captureRequests,
+			ExportCookies:    exportCookies,
+			WaitForFonts:     waitFonts,
+			Scale:            scale,
+			Layout:           layout,
+			UserAgent:        userAgent,
+			Concurrency:      concurrency,
+			Isolation:        isolation,
+			MaxOpenFiles:     openFiles,
+			Referer:          referer,
+			AbortSelectors:   abortSelectors,
+			MaxRedirects:     maxRedirects,
+			Image:            image,
+			Out:              out,
+		}
+		if notifyWebhook != "" {
+			// Compare each capture with the one of the previous cycle
+			previous := map[string]string{}
+			opts.OnCapture = func(result captureResult) {
+				prev, ok := previous[result.URL]
+				previous[result.URL] = result.Path
+				if !ok {
+					return
+				}
+				percent, diffPath, err := diffFiles(prev, result.Path)
+				if err != nil {
+					log.Printf("could not compare %s: %v", result.URL, err)
+					return
+				}
+				if percent <= diffThreshold {
+					return
+				}
+				fmt.Fprintf(out, "Changed %s by %.2f%%, diff written to %s\n", result.URL, percent, diffPath)
+				change := scrapeChange{URL: result.URL, ChangePercent: percent, DiffImage: diffPath}
+				if err := internal.PostJSON(ctx, http.DefaultClient, notifyWebhook, change, scrapeWebhookRetries); err != nil {
+					log.Printf("could not notify change of %s: %v", result.URL, err)
+				}
+			}
+		}
+		// Print the resource usage of the whole run, before any failure exits
+		printResourceStats := func() {}
+		if resourceStats {
+			monitor := internal.StartResourceMonitor(resourceSampleInterval)
+			printResourceStats = func() { monitor.Stop().Print(out) }
+		}
+		for cycle := 1; ; cycle++ {
+			// Create output directory
+			opts.OutputDir = filepath.Join(cwd, dir)
+			if timestamped {
+				opts.OutputDir = filepath.Join(opts.OutputDir, time.Now().Format(timestampedDirLayout))
+			}
+			if trace != "" {
+				opts.Trace = tracePath(trace, cycle, repeat > 0)
+			}
+			if image == nil {
+				err = os.MkdirAll(opts.OutputDir, os.ModePerm)
+				assertErrorToNilf("could not create output directory: %w", err)
+				fmt.Fprintf(out, "Writing outputs to %s\n", opts.OutputDir)
+			}
+
+			start := time.Now()
+			err = runScrape(ctx, opts)
+			if compress {
+				// Archive even the partial outputs of a failed run
+				summary, compressErr := compressOutputs(opts.OutputDir)
+				if compressErr != nil {
+					log.Printf("could not compress outputs: %v", compressErr)
+				} else {
+					fmt.Fprintln(out, summary)
+				}
+			}
+			if repeat == 0 {
+				printResourceStats()
+				assertErrorToNilf("could not scrape: %w", err)
+				return
+			}
+			// A failed cycle does not stop the monitoring
+			if err != nil {
+				log.Printf("cycle %d failed: %v", cycle, err)
+			}
+			fmt.Fprintf(out, "Cycle %d finished in %s, next in %s\n", cycle, time.Since(start), repeat)
+
+			select {
+			case <-time.After(repeat):
+			case <-ctx.Done():
+				fmt.Fprintf(out, "%s - exiting after %d cycle(s)\n", internal.DoneReason(ctx), cycle)
+				printResourceStats()
+				return
+			}
+		}
+	},
+}
 
-		// Scrape via Playwright
-		pw, err := playwright.Run()
-		assertErrorToNilf("could not launch playwright: %w", err)
+// scrapeOptions holds the options of a scrape run
+type scrapeOptions struct {
+	Jobs             []scrapeJob
+	OutputDir        string
+	Headless         bool
+	LoadStorageState string
+	SaveStorageState string
+	Devtools         bool
+	// Cache is nil when caching is disabled
+	Cache    *scrapeCache
+	CacheTTL time.Duration
+	// NoCache forces every URL to be captured, the cache is still updated
+	NoCache bool
+	// Conditional skips URLs whose ETag/Last-Modified match the cached ones
+	Conditional bool
+	// Insecure ignores HTTPS errors, the ignored TLS issues are reported per URL
+	Insecure bool
+	// CaptureRequests writes a network summary next to each screenshot
+	CaptureRequests bool
+	// ExportCookies writes the cookies of the browser context next to each screenshot
+	ExportCookies bool
+	// WaitForFonts waits for the web fonts to be loaded before each screenshot
+	WaitForFonts bool
+	// Scale is the device scale factor of the pages, 2 for retina-like screenshots
+	Scale float64
+	// UserAgent overrides the browser user agent, empty to keep the default
+	UserAgent string
+	// Layout is how the artifacts are organized in OutputDir, layoutFlat or layoutNested
+	Layout string
+	// Trace is the path of the Playwright trace of the run, empty to disable tracing
+	Trace string
+	// Results receives a JSON line per URL as soon as it is done, nil to disable
+	Results io.Writer
+	// OnCapture is called after each successful capture, if set
+	OnCapture func(captureResult)
+	// BannerSelectors are tried before each screenshot to dismiss cookie banners, nil to disable
+	BannerSelectors []string
+	// Referer is sent when navigating to every URL, empty for none
+	Referer string
+	// AbortSelectors fail the capture of the pages matching any of them, e.g. captchas
+	AbortSelectors []string
+	// MaxRedirects is the number of client-side redirects followed before capturing, 0 to capture the first rendered page
+	MaxRedirects int
+	// Image receives the screenshot of the single URL instead of a file in OutputDir, nil to write files
+	Image io.Writer
+	// Concurrency is the number of workers, each with its own browser context and page
+	Concurrency int
+	// MaxOpenFiles caps the workers started by their estimated file descriptors, 0 for no cap
+	MaxOpenFiles uint64
+	// Isolation is isolationSharedBrowser or isolationPerWorkerBrowser
+	Isolation string
+	Out       io.Writer
+}
+
+// runScrape captures every URL into the output directory.
+// The browser and Playwright are always cleaned up, even when ctx is cancelled mid-run,
+// and the URLs captured so far are reported.
+func runScrape(ctx context.Context, opts scrapeOptions) (err error) {
+	pw, err := playwright.Run()
+	if err != nil {
+		return withInstallHint(fmt.Errorf("could not launch playwright: %w", err))
+	}
+	defer func() {
+		if stopErr := pw.Stop(); stopErr != nil {
+			err = errors.Join(err, fmt.Errorf("could not stop playwright: %w", stopErr))
+		}
+	}()
+
+	// Every browser launched is closed, the per-worker-browser isolation launches one per worker
+	browsers := []playwright.Browser{}
+	defer func() {
+		for _, browser := range browsers {
+			if closeErr := browser.Close(); closeErr != nil {
+				err = errors.Join(err, fmt.Errorf("could not close browser: %w", closeErr))
+			}
+		}
+	}()
+	launch := func() (playwright.Browser, error) {
 		browser, err := pw.Chromium.Launch(playwright.BrowserTypeLaunchOptions{
-			Headless: playwright.Bool(headless),
+			Headless: playwright.Bool(opts.Headless),
+			Devtools: playwright.Bool(opts.Devtools),
 		})
-		assertErrorToNilf("could not launch Chromium: %w", err)
-		context, err := browser.NewContext()
-		assertErrorToNilf("could not create context: %w", err)
-		page, err := context.NewPage()
-		assertErrorToNilf("could not create page: %w", err)
+		if err != nil {
+			return nil, withInstallHint(fmt.Errorf("could not launch Chromium: %w", err))
+		}
+		browsers = append(browsers, browser)
+		return browser, nil
+	}
+	browser, err := launch()
+	if err != nil {
+		return err
+	}
 
-		// TODO: parallelize
-		for _, url := range urls {
-			fmt.Printf("Scraping %s\n", url)
-			_, err := page.Goto(url, playwright.PageGotoOptions{
-				WaitUntil: playwright.WaitUntilStateDomcontentloaded,
-			})
-			assertErrorToNilf("could not goto: %w", err)
-
-			fileName, err := getFileName(url)
-			assertErrorToNilf("could not get file name: %w", err)
-
-			_, err = page.Screenshot(playwright.PageScreenshotOptions{
-				Path: playwright.String(filepath.Join(cwd, dir, fileName)),
-			})
-			assertErrorToNilf("could not take screenshot: %w", err)
-		}
-
-		// Close browser
-		err = browser.Close()
-		assertErrorToNilf("could not close browser: %w", err)
-		err = pw.Stop()
-		assertErrorToNilf("could not stop playwright: %w", err)
-	},
+	contextOptions := playwright.BrowserNewContextOptions{
+		IgnoreHttpsErrors: playwright.Bool(opts.Insecure),
+		DeviceScaleFactor: playwright.Float(opts.Scale),
+	}
+	if opts.UserAgent != "" {
+		contextOptions.UserAgent = playwright.String(opts.UserAgent)
+	}
+	if opts.LoadStorageState != "" {
+		contextOptions.StorageStatePath = playwright.String(opts.LoadStorageState)
+	}
+	browserContext, err := browser.NewContext(contextOptions)
+	if err != nil {
+		return fmt.Errorf("could not create context: %w", err)
+	}
+	if opts.Trace != "" {
+		if err := browserContext.Tracing().Start(playwright.TracingStartOptions{
+			Screenshots: playwright.Bool(true),
+			Snapshots:   playwright.Bool(true),
+		}); err != nil {
+			return fmt.Errorf("could not start tracing: %w", err)
+		}
+		// Stopped before the browser is closed, even when the run fails
+		defer func() {
+			if stopErr := browserContext.Tracing().Stop(opts.Trace); stopErr != nil {
+				err = errors.Join(err, fmt.Errorf("could not save trace: %w", stopErr))
+				return
+			}
+			fmt.Fprintf(opts.Out, "Saved trace to %s, open it with `playwright show-trace`\n", opts.Trace)
+		}()
+	}
+	page, err := browserContext.NewPage()
+	if err != nil {
+		return fmt.Errorf("could not create page: %w", err)
+	}
+	// The cookies every page starts from, those of --load-storage-state
+	var baseCookies []playwright.OptionalCookie
+	if opts.ExportCookies {
+		cookies, err := browserContext.Cookies()
+		if err != nil {
+			return fmt.Errorf("could not get cookies: %w", err)
+		}
+		baseCookies = optionalCookies(cookies)
+	}
+
+	// Each worker has its own context, so cookies and storage are never shared between workers.
+	// The context and page of a worker are reused for all its urls.
+	newWorkerPage := func() (playwright.Page, error) {
+		workerBrowser := browser
+		if opts.Isolation == isolationPerWorkerBrowser {
+			if workerBrowser, err = launch(); err != nil {
+				return nil, err
+			}
+		}
+		workerContext, err := workerBrowser.NewContext(contextOptions)
+		if err != nil {
+			return nil, fmt.Errorf("could not create context: %w", err)
+		}
+		workerPage, err := workerContext.NewPage()
+		if err != nil {
+			return nil, fmt.Errorf("could not create page: %w", err)
+		}
+		return workerPage, nil
+	}
+	workers := maxWorkersForOpenFiles(opts.Concurrency, opts.Isolation, opts.MaxOpenFiles)
+	if workers < opts.Concurrency {
+		fmt.Fprintf(opts.Out, "Limiting to %d worker(s) to stay under %d open files\n", workers, opts.MaxOpenFiles)
+	}
+	pages := []playwright.Page{page}
+	for i := 1; i < workers; i++ {
+		workerPage, err := newWorkerPage()
+		if isTooManyOpenFiles(err) {
+			// Go on with the workers started so far rather than failing the run
+			log.Printf("too many open files, going on with %d of %d worker(s): %v", len(pages), workers, err)
+			break
+		}
+		if err != nil {
+			return err
+		}
+		pages = append(pages, workerPage)
+	}
+
+	run := &scrapeRun{opts: opts, aborted: map[string]int{}, baseCookies: baseCookies}
+	jobs := make(chan scrapeJob)
+	var wg sync.WaitGroup
+	for _, page := range pages {
+		wg.Add(1)
+		go func(page playwright.Page) {
+			defer wg.Done()
+			for job := range jobs {
+				run.scrape(ctx, page, job)
+			}
+		}(page)
+	}
+	for _, job := range opts.Jobs {
+		if ctx.Err() != nil {
+			fmt.Fprintf(opts.Out, "%s - stopping\n", internal.DoneReason(ctx))
+			break
+		}
+		jobs <- job
+	}
+	close(jobs)
+	wg.Wait()
+
+	fmt.Fprintf(opts.Out, "Scraped %d of %d url(s), %d failed, %d cached\n", run.scraped, len(opts.Jobs), run.failed, run.cached)
+	printAborted(opts.Out, run.aborted)
+	if opts.Insecure {
+		fmt.Fprintf(opts.Out, "%d url(s) with TLS issues ignored\n", run.tlsIssues)
+	}
+
+	if opts.Cache != nil {
+		if err := opts.Cache.save(); err != nil {
+			return err
+		}
+	}
+
+	// Persist cookies and localStorage even if some URLs failed
+	if opts.SaveStorageState != "" {
+		if _, err := browserContext.StorageState(opts.SaveStorageState); err != nil {
+			return fmt.Errorf("could not save storage state: %w", err)
+		}
+		fmt.Fprintf(opts.Out, "Saved storage state to %s\n", opts.SaveStorageState)
+	}
+
+	if run.failed > 0 {
+		return fmt.Errorf("%d of %d url(s) failed", run.failed, len(opts.Jobs))
+	}
+	return nil
+}
+
+// scrapeRun holds the counters of a run, shared by its workers
+type scrapeRun struct {
+	opts scrapeOptions
+	// mu guards the counters, the streamed records and the OnCapture callback
+	mu                                 sync.Mutex
+	scraped, failed, cached, tlsIssues int
+	// aborted counts the urls failed by each `--abort-on-selector` selector
+	aborted map[string]int
+	// baseCookies are restored before each page when the cookies are exported
+	baseCookies []playwright.OptionalCookie
+}
+
+// count adds to one of the counters of the run
+func (r *scrapeRun) count(counter *int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	*counter++
+}
+
+// emit streams the record, one worker at a time
+func (r *scrapeRun) emit(record scrapeRecord) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.opts.emit(record)
+}
+
+// scrape captures a single URL with the page of a worker
+func (r *scrapeRun) scrape(ctx context.Context, page playwright.Page, job scrapeJob) {
+	opts := r.opts
+	url := job.URL
+	if ctx.Err() != nil {
+		return
+	}
+	if opts.Cache != nil && !opts.NoCache && opts.CacheTTL > 0 && opts.Cache.fresh(url, opts.CacheTTL, time.Now()) {
+		fmt.Fprintf(opts.Out, "Cached %s\n", url)
+		r.emit(scrapeRecord{Time: time.Now(), URL: url, Status: scrapeStatusCached})
+		r.count(&r.cached)
+		return
+	}
+	// Any failure of the HEAD request falls back to a full render
+	validators := httpValidators{}
+	if opts.Conditional {
+		v, err := headValidators(ctx, http.DefaultClient, url)
+		if err != nil {
+			log.Printf("could not check %s, rendering it: %v", url, err)
+		} else {
+			validators = v
+			if !opts.NoCache && opts.Cache.unchanged(url, validators) {
+				fmt.Fprintf(opts.Out, "Unchanged %s\n", url)
+				r.emit(scrapeRecord{Time: time.Now(), URL: url, Status: scrapeStatusUnchanged})
+				r.count(&r.cached)
+				return
+			}
+		}
+	}
+	fmt.Fprintf(opts.Out, "Scraping %s\n", url)
+	if opts.ExportCookies {
+		// The context of a worker is reused, so each export would also list the cookies of its previous pages
+		if err := resetCookies(page.Context(), r.baseCookies); err != nil {
+			log.Printf("could not reset cookies before %s: %v", url, err)
+		}
+	}
+	tlsIssue := ""
+	if opts.Insecure {
+		// The browser does not expose why a certificate was accepted, so verify it separately
+		issue, err := checkTLS(ctx, url)
+		if err != nil {
+			// The capture reports why the host cannot be reached
+			log.Printf("could not check TLS of %s: %v", url, err)
+		} else if issue != nil {
+			fmt.Fprintf(opts.Out, "TLS issue ignored for %s: %v\n", url, issue)
+			tlsIssue = issue.Error()
+			r.count(&r.tlsIssues)
+		}
+	}
+	var result captureResult
+	var err error
+	for attempt := 0; ; attempt++ {
+		result, err = capture(opts.Out, page, job, captureOptions{
+			OutputDir:       opts.OutputDir,
+			Layout:          opts.Layout,
+			Referer:         opts.Referer,
+			AbortSelectors:  opts.AbortSelectors,
+			MaxRedirects:    opts.MaxRedirects,
+			Image:           opts.Image,
+			BannerSelectors: opts.BannerSelectors,
+			CaptureRequests: opts.CaptureRequests,
+			ExportCookies:   opts.ExportCookies,
+			WaitForFonts:    opts.WaitForFonts,
+		})
+		if !isTooManyOpenFiles(err) || attempt == openFilesRetries {
+			break
+		}
+		// Back off until the other workers release descriptors
+		delay := openFilesBackoff << attempt
+		log.Printf("too many open files scraping %s, retrying in %s", url, delay)
+		select {
+		case <-time.After(delay):
+			continue
+		case <-ctx.Done():
+		}
+		break
+	}
+	record := newScrapeRecord(result, err, time.Now())
+	record.TLSIssue = tlsIssue
+	r.emit(record)
+	if err != nil {
+		log.Printf("could not scrape %s: %v", url, err)
+		r.count(&r.failed)
+		if record.AbortedOn != "" {
+			r.mu.Lock()
+			r.aborted[record.AbortedOn]++
+			r.mu.Unlock()
+		}
+	} else {
+		if opts.Cache != nil {
+			opts.Cache.record(url, result.Path, time.Now(), validators)
+		}
+		if opts.OnCapture != nil {
+			r.mu.Lock()
+			opts.OnCapture(result)
+			r.mu.Unlock()
+		}
+	}
+	r.count(&r.scraped)
+	if opts.Devtools {
+		// Resume from the Playwright inspector to continue with the next URL
+		fmt.Fprintf(opts.Out, "Paused on %s\n", url)
+		if err := page.Pause(); err != nil {
+			log.Printf("could not pause on %s: %v", url, err)
+		}
+	}
+}
+
+// emit streams the record to opts.Results, if set
+func (opts scrapeOptions) emit(r scrapeRecord) {
+	if opts.Results == nil {
+		return
+	}
+	if err := writeRecord(opts.Results, r); err != nil {
+		log.Printf("could not stream result of %s: %v", r.URL, err)
+	}
+}
+
+// captureResult holds the outcome of capturing a single URL
+type captureResult struct {
+	URL            string
+	Path           string
+	LoadTime       time.Duration
+	ScreenshotTime time.Duration
+	// FinalURL is the URL captured after the client-side redirects, set when they are followed
+	FinalURL  string
+	Redirects int
+}
+
+// captureOptions holds the options shared by every capture of a run
+type captureOptions struct {
+	OutputDir string
+	// Layout is layoutFlat or layoutNested
+	Layout string
+	// Referer is passed to the navigation, empty for none
+	Referer string
+	// AbortSelectors are looked for before the screenshot, a match fails the capture with an abortError
+	AbortSelectors []string
+	// MaxRedirects is the number of JavaScript and meta refresh redirects followed, 0 not to wait for any
+	MaxRedirects int
+	// Image receives the screenshot bytes instead of a file, nil to write the file
+	Image io.Writer
+	// BannerSelectors are tried in order, the first visible match is clicked before the screenshot
+	BannerSelectors []string
+	// CaptureRequests writes a `<name>.network.json` summary of the page requests
+	CaptureRequests bool
+	// ExportCookies writes the cookies of the browser context to `<name>.cookies.json`
+	ExportCookies bool
+	// WaitForFonts waits for document.fonts.ready before the screenshot
+	WaitForFonts bool
+}
+
+// capture navigates the page to the job URL and takes a screenshot into the output directory
+func capture(out io.Writer, page playwright.Page, job scrapeJob, opts captureOptions) (captureResult, error) {
+	result := captureResult{URL: job.URL}
+
+	var recorder *requestRecorder
+	if opts.CaptureRequests {
+		recorder = recordRequests(page)
+		defer recorder.stop()
+	}
+
+	start := time.Now()
+	gotoOptions := playwright.PageGotoOptions{
+		WaitUntil: playwright.WaitUntilStateDomcontentloaded,
+	}
+	if opts.Referer != "" {
+		gotoOptions.Referer = playwright.String(opts.Referer)
+	}
+	_, err := page.Goto(job.URL, gotoOptions)
+	if err != nil {
+		return result, fmt.Errorf("could not goto: %w", err)
+	}
+	if opts.MaxRedirects > 0 {
+		// Capture the page the redirects end on rather than an intermediate one
+		chain, err := followRenderRedirects(page, opts.MaxRedirects)
+		result.FinalURL, result.Redirects = chain.final(), chain.redirects()
+		if err != nil {
+			return result, err
+		}
+		if result.Redirects > 0 {
+			fmt.Fprintf(out, "Followed %d redirect(s) from %s to %s\n", result.Redirects, job.URL, result.FinalURL)
+		}
+	}
+	if job.WaitFor != "" {
+		if err := page.Locator(job.WaitFor).First().WaitFor(); err != nil {
+			return result, fmt.Errorf("could not wait for %q: %w", job.WaitFor, err)
+		}
+	}
+	if opts.WaitForFonts {
+		// Capture with the fallback fonts rather than failing
+		loaded, err := waitForFonts(page, fontsWaitTimeout)
+		if err != nil {
+			return result, err
+		}
+		if !loaded {
+			log.Printf("fonts of %s not loaded after %s, capturing anyway", job.URL, fontsWaitTimeout)
+		}
+	}
+	result.LoadTime = time.Since(start)
+
+	if len(opts.AbortSelectors) > 0 {
+		selector, err := findAbortSelector(page, opts.AbortSelectors)
+		if err != nil {
+			return result, err
+		}
+		if selector != "" {
+			return result, &abortError{Selector: selector}
+		}
+	}
+
+	if len(opts.BannerSelectors) > 0 {
+		// A banner that cannot be dismissed should not fail the capture
+		selector, err := dismissBanner(page, opts.BannerSelectors)
+		if err != nil {
+			log.Printf("could not dismiss banner on %s: %v", job.URL, err)
+		} else if selector != "" {
+			fmt.Fprintf(out, "Dismissed banner %s on %s\n", selector, job.URL)
+		}
+	}
+
+	// Without a path the screenshot is only returned
+	var screenshotPath *string
+	if opts.Image == nil {
+		result.Path, err = artifactPath(opts.OutputDir, opts.Layout, job.URL)
+		if err != nil {
+			return result, fmt.Errorf("could not get file name: %w", err)
+		}
+		if err := os.MkdirAll(filepath.Dir(result.Path), os.ModePerm); err != nil {
+			return result, fmt.Errorf("could not create artifacts directory: %w", err)
+		}
+		screenshotPath = playwright.String(result.Path)
+	}
+
+	start = time.Now()
+	var screenshot []byte
+	if job.Selector != "" {
+		screenshot, err = page.Locator(job.Selector).First().Screenshot(playwright.LocatorScreenshotOptions{
+			Path: screenshotPath,
+		})
+	} else {
+		screenshot, err = page.Screenshot(playwright.PageScreenshotOptions{
+			Path:     screenshotPath,
+			FullPage: playwright.Bool(job.FullPage),
+		})
+	}
+	if err != nil {
+		return result, fmt.Errorf("could not take screenshot: %w", err)
+	}
+	result.ScreenshotTime = time.Since(start)
+	if opts.Image != nil {
+		if _, err := opts.Image.Write(screenshot); err != nil {
+			return result, fmt.Errorf("could not write screenshot: %w", err)
+		}
+	}
+
+	if recorder != nil {
+		// A missing summary should not fail the capture
+		recorder.stop()
+		summaryPath, err := writeNetworkSummary(result.Path, summarizeRequests(job.URL, recorder.requests()))
+		if err != nil {
+			log.Printf("could not summarize requests of %s: %v", job.URL, err)
+		} else {
+			fmt.Fprintf(out, "Wrote network summary to %s\n", summaryPath)
+		}
+	}
+
+	if opts.ExportCookies {
+		// Missing cookies should not fail the capture either
+		cookies, err := page.Context().Cookies()
+		if err != nil {
+			log.Printf("could not get cookies of %s: %v", job.URL, err)
+		} else if cookiesPath, err := writeCookieExport(result.Path, newCookieExport(page.URL(), cookies)); err != nil {
+			log.Printf("could not export cookies of %s: %v", job.URL, err)
+		} else {
+			fmt.Fprintf(out, "Wrote cookies to %s\n", cookiesPath)
+		}
+	}
+
+	return result, nil
+}
+
+// browserType returns the Playwright browser type of the given engine
+func browserType(pw *playwright.Playwright, engine string) (playwright.BrowserType, error) {
+	switch engine {
+	case "chromium":
+		return pw.Chromium, nil
+	case "firefox":
+		return pw.Firefox, nil
+	case "webkit":
+		return pw.WebKit, nil
+	}
+	return nil, fmt.Errorf("unknown engine %q, must be one of chromium, firefox or webkit", engine)
 }
 
 func getFileName(url string) (string, error) {
@@ -108,8 +946,42 @@ func init() {
 	rootCmd.AddCommand(scrapeCmd)
 
 	scrapeCmd.Flags().StringArrayP("url", "u", []string{}, "URL to scrape")
+	scrapeCmd.Flags().String("jobs", "", "Path to a JSON Lines file of jobs with per-url options (url, selector, wait_for, full_page), a url can only be given once")
 	scrapeCmd.Flags().StringP("dir", "d", "artifacts", "Output directory")
+	scrapeCmd.Flags().Bool("stdout", false, "Write the PNG of the single url to stdout instead of a file, the progress goes to stderr")
 	scrapeCmd.Flags().BoolP("headless", "m", true, "Headless mode")
-
-	assertErrorToNilf("could not mark `url` as required: %w", scrapeCmd.MarkFlagRequired("url"))
+	scrapeCmd.Flags().Bool("timestamped", false, "Write outputs to a per-run timestamped subdirectory")
+	scrapeCmd.Flags().String("load-storage-state", "", "Path to a storage state file (cookies and localStorage) to load before scraping")
+	scrapeCmd.Flags().Bool("devtools", false, "Open devtools and pause on each page in the Playwright inspector (requires --headless=false)")
+	scrapeCmd.Flags().Duration("cache-ttl", 0, "Skip urls captured within this duration, 0 to disable the cache")
+	scrapeCmd.Flags().Bool("no-cache", false, "Capture every url even if it is cached")
+	scrapeCmd.Flags().Bool("conditional", false, "Skip urls whose ETag/Last-Modified did not change since the last capture")
+	scrapeCmd.Flags().Bool("insecure", false, "Ignore HTTPS errors and report the urls whose TLS verification failed")
+	scrapeCmd.Flags().Bool("dismiss-banners", false, "Click the first matching cookie/consent banner button before each screenshot")
+	scrapeCmd.Flags().String("banner-selectors", "", "Path to a file of banner selectors, one per line, overriding the built-in list used by --dismiss-banners")
+	scrapeCmd.Flags().Duration("repeat", 0, "Re-run the scrape at this interval until interrupted, writing each cycle to a timestamped directory, 0 to run once")
+	scrapeCmd.Flags().String("notify-webhook", "", "POST a JSON payload (url, change_percent, diff_image) to this URL when a page changed since the previous --repeat cycle")
+	scrapeCmd.Flags().Float64("diff-threshold", 1, "Percentage of changed pixels above which --notify-webhook is called")
+	scrapeCmd.Flags().Bool("export-cookies", false, "Write a <name>.cookies.json of the cookies set by each page, flagging the session and third-party ones; every page starts from the --load-storage-state cookies")
+	scrapeCmd.Flags().Bool("capture-requests", false, "Write a <name>.network.json summary of the requests of each page (count by resource type, transfer size, slowest requests)")
+	scrapeCmd.Flags().Bool("stream-results", false, "Write a JSON line per url to stdout as soon as it is done, other output goes to stderr")
+	scrapeCmd.Flags().Bool("wait-for-fonts", false, "Wait for the web fonts to be loaded (document.fonts.ready) before each screenshot, up to 30s")
+	scrapeCmd.Flags().String("trace", "", "Record a Playwright trace (screenshots and DOM snapshots) of the run to this zip file, suffixed with the cycle number with --repeat")
+	scrapeCmd.Flags().Float64("scale", 1, "Device scale factor of the pages, e.g. 2 for sharper high-DPI screenshots")
+	scrapeCmd.Flags().Bool("resource-stats", false, "Print the peak memory and the CPU time used by the run, including the browser driver, at the end")
+	scrapeCmd.Flags().String("layout", layoutFlat, "Layout of the output directory, flat or nested to keep the artifacts of each url in its own <hash> subdirectory")
+	scrapeCmd.Flags().String("user-agent", "", "User agent of the browser, overrides --ua-preset")
+	scrapeCmd.Flags().String("ua-preset", "", "User agent preset: "+strings.Join(userAgentPresetNames(), ", "))
+	scrapeCmd.Flags().Bool("follow-redirects-render", false, "Wait for JavaScript and meta refresh redirects to settle and capture the final page, recording its URL")
+	scrapeCmd.Flags().Int("max-redirects", 10, "Maximum number of redirects followed by --follow-redirects-render before failing the url")
+	scrapeCmd.Flags().StringArray("abort-on-selector", []string{}, "Fail the url instead of capturing it when the page matches this selector, e.g. a captcha or a 404 template, repeatable")
+	scrapeCmd.Flags().String("referer", "", "Referer sent when navigating to every url, to simulate a visit from that page")
+	scrapeCmd.Flags().Int("concurrency", 1, "Number of urls captured in parallel, each worker with its own browser context")
+	scrapeCmd.Flags().Int("max-open-files", 0, "Soft cap of open files limiting the workers started by --concurrency, 0 for the ulimit the browser inherits")
+	scrapeCmd.Flags().String("context-isolation", isolationSharedBrowser, "Isolation of the --concurrency workers: shared-browser (one Chromium, a context per worker, lighter) or per-worker-browser (a Chromium per worker, isolated from crashes but heavier)")
+	scrapeCmd.Flags().Bool("compress", false, "Move the outputs of each run into a <dir>.zip archive and report the compressed size")
+	scrapeCmd.Flags().Bool("clean", false, "Remove the screenshots, diffs and network summaries of previous scrapes from the output directory first, after a confirmation")
+	scrapeCmd.Flags().Bool("force", false, "Do not ask for a confirmation before --clean")
+	scrapeCmd.Flags().Bool("install-browsers", false, "Install the Playwright driver and Chromium before scraping, can be used without --url")
+	scrapeCmd.Flags().String("save-storage-state", "", "Path to write the storage state (cookies and localStorage) to after scraping")
 }
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// abortError marks a capture aborted because a page matched one of the `--abort-on-selector` selectors,
+// e.g. a captcha or a soft 404 template
+type abortError struct {
+	Selector string
+}
+
+func (e *abortError) Error() string {
+	return fmt.Sprintf("aborted, page matches %q", e.Selector)
+}
+
+// findAbortSelector returns the first selector present in the page, empty if none is
+func findAbortSelector(page playwright.Page, selectors []string) (string, error) {
+	for _, selector := range selectors {
+		count, err := page.Locator(selector).Count()
+		if err != nil {
+			return "", fmt.Errorf("could not look for %q: %w", selector, err)
+		}
+		if count > 0 {
+			return selector, nil
+		}
+	}
+	return "", nil
+}
+
+// printAborted prints how many urls were aborted per selector, by selector
+func printAborted(out io.Writer, aborted map[string]int) {
+	selectors := make([]string, 0, len(aborted))
+	for selector := range aborted {
+		selectors = append(selectors, selector)
+	}
+	sort.Strings(selectors)
+	for _, selector := range selectors {
+		fmt.Fprintf(out, "%d url(s) aborted on %s\n", aborted[selector], selector)
+	}
+}
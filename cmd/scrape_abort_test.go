@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestPrintAborted(t *testing.T) {
+	// Table Driven Test
+	tests := []struct {
+		name    string
+		aborted map[string]int
+		want    string
+	}{
+		{name: "empty case", aborted: map[string]int{}, want: ""},
+		{name: "sorted case", aborted: map[string]int{"#captcha": 2, ".error-404": 1}, want: "2 url(s) aborted on #captcha\n1 url(s) aborted on .error-404\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			printAborted(&out, tt.aborted)
+			if got := out.String(); got != tt.want {
+				t.Errorf("%s: printAborted() = %q; want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewScrapeRecordAborted(t *testing.T) {
+	// Table Driven Test
+	tests := []struct {
+		name          string
+		err           error
+		wantStatus    string
+		wantAbortedOn string
+	}{
+		{name: "scraped case", err: nil, wantStatus: scrapeStatusScraped},
+		{name: "failed case", err: fmt.Errorf("could not goto"), wantStatus: scrapeStatusFailed},
+		{name: "aborted case", err: &abortError{Selector: "#captcha"}, wantStatus: scrapeStatusFailed, wantAbortedOn: "#captcha"},
+		{name: "wrapped aborted case", err: fmt.Errorf("capture: %w", &abortError{Selector: "#captcha"}), wantStatus: scrapeStatusFailed, wantAbortedOn: "#captcha"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newScrapeRecord(captureResult{URL: "https://example.com"}, tt.err, time.Now())
+			if r.Status != tt.wantStatus || r.AbortedOn != tt.wantAbortedOn {
+				t.Errorf("%s: newScrapeRecord() = (%s, %q); want (%s, %q)", tt.name, r.Status, r.AbortedOn, tt.wantStatus, tt.wantAbortedOn)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// bannerClickTimeout is the time in milliseconds allowed to click a banner button
+const bannerClickTimeout = 2000
+
+// defaultBannerSelectors matches the accept buttons of common cookie and consent banners
+var defaultBannerSelectors = []string{
+	"#onetrust-accept-btn-handler",
+	"#CybotCookiebotDialogBodyLevelButtonLevelOptinAllowAll",
+	"#CybotCookiebotDialogBodyButtonAccept",
+	"#didomi-notice-agree-button",
+	"#truste-consent-button",
+	".fc-cta-consent",
+	".cc-allow",
+	".cc-dismiss",
+	"button[data-cookiebanner='accept_button']",
+	"button#L2AGLb",
+	"[aria-label='Accept all']",
+	"[aria-label='Accept cookies']",
+}
+
+// parseBannerSelectors reads one selector per line, blank lines are skipped.
+// Comments are not supported since selectors may start with # or //.
+func parseBannerSelectors(r io.Reader) ([]string, error) {
+	selectors := []string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		selectors = append(selectors, line)
+	}
+	return selectors, scanner.Err()
+}
+
+// loadBannerSelectors returns the selectors of the file at path, or the built-in list if path is empty
+func loadBannerSelectors(path string) ([]string, error) {
+	if path == "" {
+		return defaultBannerSelectors, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open banner selectors file: %w", err)
+	}
+	defer f.Close()
+	return parseBannerSelectors(f)
+}
+
+// dismissBanner clicks the first visible element matching one of the selectors.
+// It returns the selector that was clicked, or an empty string if none matched.
+func dismissBanner(page playwright.Page, selectors []string) (string, error) {
+	for _, selector := range selectors {
+		locator := page.Locator(selector).First()
+		visible, err := locator.IsVisible()
+		if err != nil || !visible {
+			continue
+		}
+		if err := locator.Click(playwright.LocatorClickOptions{
+			Timeout: playwright.Float(bannerClickTimeout),
+		}); err != nil {
+			return "", fmt.Errorf("could not click %q: %w", selector, err)
+		}
+		return selector, nil
+	}
+	return "", nil
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseBannerSelectors(t *testing.T) {
+	// Table Driven Test
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{name: "empty case", input: "", want: []string{}},
+		{name: "nominal case", input: "#accept\n.cookie button\n", want: []string{"#accept", ".cookie button"}},
+		{name: "blank lines case", input: "\n\n  #accept  \n\n//button[text()='OK']\n", want: []string{"#accept", "//button[text()='OK']"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseBannerSelectors(strings.NewReader(tt.input))
+			if err != nil {
+				t.Errorf("%s: parseBannerSelectors returned error: %v", tt.name, err)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s: parseBannerSelectors = %q; want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// scrapeCacheFileName is the name of the cache file kept in the scrape output directory
+const scrapeCacheFileName = ".scrape-cache.json"
+
+// httpValidators are the response headers used to tell whether a page changed
+type httpValidators struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// scrapeCacheEntry records when a URL was last captured
+type scrapeCacheEntry struct {
+	CapturedAt time.Time `json:"captured_at"`
+	Path       string    `json:"path"`
+	httpValidators
+}
+
+// scrapeCache is a small on-disk cache of captured URLs, safe for concurrent use
+type scrapeCache struct {
+	mu      sync.Mutex
+	path    string
+	Entries map[string]scrapeCacheEntry `json:"entries"`
+}
+
+// loadScrapeCache reads the cache at path, a missing file yields an empty cache
+func loadScrapeCache(path string) (*scrapeCache, error) {
+	c := &scrapeCache{path: path, Entries: map[string]scrapeCacheEntry{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read cache: %w", err)
+	}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("could not parse cache %s: %w", path, err)
+	}
+	if c.Entries == nil {
+		c.Entries = map[string]scrapeCacheEntry{}
+	}
+	return c, nil
+}
+
+// fresh reports whether url was captured within ttl and its capture still exists
+func (c *scrapeCache) fresh(url string, ttl time.Duration, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.Entries[url]
+	if !ok || now.Sub(entry.CapturedAt) > ttl {
+		return false
+	}
+	_, err := os.Stat(entry.Path)
+	return err == nil
+}
+
+// unchanged reports whether the validators match the ones recorded for url and its capture still exists.
+// The ETag is preferred, Last-Modified is only compared when the server sent no ETag.
+func (c *scrapeCache) unchanged(url string, v httpValidators) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.Entries[url]
+	if !ok {
+		return false
+	}
+	switch {
+	case v.ETag != "":
+		if v.ETag != entry.ETag {
+			return false
+		}
+	case v.LastModified != "":
+		if v.LastModified != entry.LastModified {
+			return false
+		}
+	default:
+		return false
+	}
+	_, err := os.Stat(entry.Path)
+	return err == nil
+}
+
+// record stores the capture of url along with the validators of the page
+func (c *scrapeCache) record(url string, path string, now time.Time, v httpValidators) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Entries[url] = scrapeCacheEntry{CapturedAt: now, Path: path, httpValidators: v}
+}
+
+// headValidators issues a HEAD request to url and returns the validators of the response
+func headValidators(ctx context.Context, client *http.Client, url string) (httpValidators, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return httpValidators{}, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return httpValidators{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return httpValidators{}, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return httpValidators{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}, nil
+}
+
+// save writes the cache back to disk
+func (c *scrapeCache) save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	data, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("could not marshal cache: %w", err)
+	}
+	if err := os.WriteFile(c.path, data, 0644); err != nil {
+		return fmt.Errorf("could not write cache: %w", err)
+	}
+	return nil
+}
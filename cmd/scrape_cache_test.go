@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestScrapeCache(t *testing.T) {
+	dir := t.TempDir()
+	capturePath := filepath.Join(dir, "capture.png")
+	if err := os.WriteFile(capturePath, []byte{}, 0644); err != nil {
+		t.Fatalf("could not write capture: %v", err)
+	}
+	now := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+
+	c, err := loadScrapeCache(filepath.Join(dir, scrapeCacheFileName))
+	if err != nil {
+		t.Fatalf("loadScrapeCache returned error: %v", err)
+	}
+	c.record("https://example.com", capturePath, now, httpValidators{ETag: `"v1"`})
+	c.record("https://example.org", filepath.Join(dir, "missing.png"), now, httpValidators{})
+	if err := c.save(); err != nil {
+		t.Fatalf("save returned error: %v", err)
+	}
+	c, err = loadScrapeCache(filepath.Join(dir, scrapeCacheFileName))
+	if err != nil {
+		t.Fatalf("loadScrapeCache returned error: %v", err)
+	}
+
+	// Table Driven Test
+	tests := []struct {
+		name string
+		url  string
+		ttl  time.Duration
+		now  time.Time
+		want bool
+	}{
+		{name: "within ttl case", url: "https://example.com", ttl: time.Hour, now: now.Add(time.Minute), want: true},
+		{name: "expired case", url: "https://example.com", ttl: time.Hour, now: now.Add(2 * time.Hour), want: false},
+		{name: "missing capture case", url: "https://example.org", ttl: time.Hour, now: now, want: false},
+		{name: "unknown url case", url: "https://example.net", ttl: time.Hour, now: now, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.fresh(tt.url, tt.ttl, tt.now); got != tt.want {
+				t.Errorf("%s: fresh(%q) = %t; want %t", tt.name, tt.url, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScrapeCacheUnchanged(t *testing.T) {
+	dir := t.TempDir()
+	capturePath := filepath.Join(dir, "capture.png")
+	if err := os.WriteFile(capturePath, []byte{}, 0644); err != nil {
+		t.Fatalf("could not write capture: %v", err)
+	}
+	c, err := loadScrapeCache(filepath.Join(dir, scrapeCacheFileName))
+	if err != nil {
+		t.Fatalf("loadScrapeCache returned error: %v", err)
+	}
+	lastModified := "Tue, 02 Jan 2024 15:04:05 GMT"
+	c.record("https://example.com", capturePath, time.Now(), httpValidators{ETag: `"v1"`, LastModified: lastModified})
+
+	// Table Driven Test
+	tests := []struct {
+		name       string
+		validators httpValidators
+		want       bool
+	}{
+		{name: "same etag case", validators: httpValidators{ETag: `"v1"`}, want: true},
+		{name: "changed etag case", validators: httpValidators{ETag: `"v2"`, LastModified: lastModified}, want: false},
+		{name: "same last modified case", validators: httpValidators{LastModified: lastModified}, want: true},
+		{name: "no validators case", validators: httpValidators{}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.unchanged("https://example.com", tt.validators); got != tt.want {
+				t.Errorf("%s: unchanged(%+v) = %t; want %t", tt.name, tt.validators, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHeadValidators(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			t.Errorf("method = %s; want HEAD", r.Method)
+		}
+		w.Header().Set("ETag", `"v1"`)
+	}))
+	defer srv.Close()
+
+	got, err := headValidators(context.Background(), srv.Client(), srv.URL)
+	if err != nil {
+		t.Fatalf("headValidators returned error: %v", err)
+	}
+	if want := (httpValidators{ETag: `"v1"`}); got != want {
+		t.Errorf("headValidators = %+v; want %+v", got, want)
+	}
+}
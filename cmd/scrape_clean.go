@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var (
+	// scrapeOutputPattern matches the flat layout artifacts, e.g. <hash>.png, <hash>.network.json or <hash>.cookies.json
+	scrapeOutputPattern = regexp.MustCompile(`^[0-9a-f]{32}(\.png|\.diff\.png|\.network\.json|\.cookies\.json)$`)
+	// scrapeNestedDirPattern matches the per-url directories of the nested layout
+	scrapeNestedDirPattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+	// scrapeNestedOutputPattern matches the artifacts inside a nested layout directory
+	scrapeNestedOutputPattern = regexp.MustCompile(`^screenshot(\.png|\.diff\.png|\.network\.json|\.cookies\.json)$`)
+)
+
+// findScrapeOutputs lists the files written by previous scrapes directly in dir.
+// Only names generated by scrape are matched so that unrelated files are never listed.
+func findScrapeOutputs(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	outputs := []string{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.Type().IsRegular() && scrapeOutputPattern.MatchString(name) {
+			outputs = append(outputs, filepath.Join(dir, name))
+			continue
+		}
+		if !entry.IsDir() || !scrapeNestedDirPattern.MatchString(name) {
+			continue
+		}
+		nested, err := os.ReadDir(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range nested {
+			if n.Type().IsRegular() && scrapeNestedOutputPattern.MatchString(n.Name()) {
+				outputs = append(outputs, filepath.Join(dir, name, n.Name()))
+			}
+		}
+	}
+	return outputs, nil
+}
+
+// removeScrapeOutputs removes the files and the nested layout directories left empty
+func removeScrapeOutputs(outputs []string) error {
+	for _, path := range outputs {
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		// A directory still holding unrelated files is kept
+		if parent := filepath.Dir(path); scrapeNestedDirPattern.MatchString(filepath.Base(parent)) {
+			if entries, err := os.ReadDir(parent); err == nil && len(entries) == 0 {
+				if err := os.Remove(parent); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// confirm asks a yes/no question, anything but y or yes is a no
+func confirm(in io.Reader, out io.Writer, question string) bool {
+	fmt.Fprintf(out, "%s [y/N] ", question)
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && answer == "" {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
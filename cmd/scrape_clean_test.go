@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestCleanScrapeOutputs(t *testing.T) {
+	const hash = "c984d06aafbecf6bc55569f964148ea3"
+	// Table Driven Test
+	tests := []struct {
+		name      string
+		files     []string
+		wantFound []string
+		wantLeft  []string
+	}{
+		{
+			name:      "flat case",
+			files:     []string{hash + ".png", hash + ".diff.png", hash + ".network.json", "notes.png", ".scrape-cache.json"},
+			wantFound: []string{hash + ".diff.png", hash + ".network.json", hash + ".png"},
+			wantLeft:  []string{".scrape-cache.json", "notes.png"},
+		},
+		{
+			name:      "nested case",
+			files:     []string{hash + "/screenshot.png", hash + "/screenshot.network.json"},
+			wantFound: []string{hash + "/screenshot.network.json", hash + "/screenshot.png"},
+			wantLeft:  []string{},
+		},
+		{
+			name:      "cookies case",
+			files:     []string{hash + ".png", hash + ".cookies.json", hash + "/screenshot.cookies.json"},
+			wantFound: []string{hash + ".cookies.json", hash + ".png", hash + "/screenshot.cookies.json"},
+			wantLeft:  []string{},
+		},
+		{
+			name:      "nested with unrelated file case",
+			files:     []string{hash + "/screenshot.png", hash + "/keep.txt", "2024-01-01T00-00-00/" + hash + ".png"},
+			wantFound: []string{hash + "/screenshot.png"},
+			wantLeft:  []string{"2024-01-01T00-00-00", hash},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, f := range tt.files {
+				path := filepath.Join(dir, f)
+				if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, nil, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			outputs, err := findScrapeOutputs(dir)
+			if err != nil {
+				t.Fatalf("%s: findScrapeOutputs() error = %v", tt.name, err)
+			}
+			found := []string{}
+			for _, o := range outputs {
+				rel, _ := filepath.Rel(dir, o)
+				found = append(found, filepath.ToSlash(rel))
+			}
+			sort.Strings(found)
+			if !reflect.DeepEqual(found, tt.wantFound) {
+				t.Errorf("%s: findScrapeOutputs() = %v; want %v", tt.name, found, tt.wantFound)
+			}
+			if err := removeScrapeOutputs(outputs); err != nil {
+				t.Fatalf("%s: removeScrapeOutputs() error = %v", tt.name, err)
+			}
+			entries, _ := os.ReadDir(dir)
+			left := []string{}
+			for _, e := range entries {
+				left = append(left, e.Name())
+			}
+			sort.Strings(left)
+			if !reflect.DeepEqual(left, tt.wantLeft) {
+				t.Errorf("%s: left %v; want %v", tt.name, left, tt.wantLeft)
+			}
+		})
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	// Table Driven Test
+	tests := []struct {
+		name  string
+		input string
+		want  bool
+	}{
+		{name: "yes case", input: "y\n", want: true},
+		{name: "full yes case", input: " Yes \n", want: true},
+		{name: "no case", input: "n\n", want: false},
+		{name: "empty case", input: "", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if got := confirm(strings.NewReader(tt.input), &out, "Remove?"); got != tt.want {
+				t.Errorf("%s: confirm(%q) = %t; want %t", tt.name, tt.input, got, tt.want)
+			}
+			if out.String() != "Remove? [y/N] " {
+				t.Errorf("%s: prompt = %q", tt.name, out.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// compressSummary describes an archive written by compressOutputs
+type compressSummary struct {
+	Archive string
+	Files   int
+	// Size and CompressedSize are the total size of the files before and after compression
+	Size           int64
+	CompressedSize int64
+}
+
+// String returns a one-line report of the compression
+func (s compressSummary) String() string {
+	ratio := 0.0
+	if s.Size > 0 {
+		ratio = 100 * float64(s.CompressedSize) / float64(s.Size)
+	}
+	return fmt.Sprintf("Compressed %d file(s) into %s, %d -> %d byte(s) (%.1f%%)", s.Files, s.Archive, s.Size, s.CompressedSize, ratio)
+}
+
+// compressOutputs moves the scrape outputs of dir into <dir>.zip.
+// Only the files named by scrape are archived and removed, the directory itself is removed if left empty.
+func compressOutputs(dir string) (compressSummary, error) {
+	summary := compressSummary{Archive: filepath.Clean(dir) + ".zip"}
+	outputs, err := findScrapeOutputs(dir)
+	if err != nil {
+		return summary, err
+	}
+	f, err := os.Create(summary.Archive)
+	if err != nil {
+		return summary, err
+	}
+	defer f.Close()
+	w := zip.NewWriter(f)
+	for _, path := range outputs {
+		if err := addToZip(w, dir, path); err != nil {
+			return summary, fmt.Errorf("could not archive %s: %w", path, err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		return summary, err
+	}
+	if err := f.Close(); err != nil {
+		return summary, err
+	}
+
+	// Read the sizes back from the archive once it is complete
+	r, err := zip.OpenReader(summary.Archive)
+	if err != nil {
+		return summary, err
+	}
+	defer r.Close()
+	for _, file := range r.File {
+		summary.Files++
+		summary.Size += int64(file.UncompressedSize64)
+		summary.CompressedSize += int64(file.CompressedSize64)
+	}
+
+	if err := removeScrapeOutputs(outputs); err != nil {
+		return summary, err
+	}
+	// Fails when the directory still holds other files, such as the cache, which is fine
+	_ = os.Remove(dir)
+	return summary, nil
+}
+
+// addToZip adds the file at path to the archive under its path relative to dir
+func addToZip(w *zip.Writer, dir, path string) error {
+	name, err := filepath.Rel(dir, path)
+	if err != nil {
+		return err
+	}
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := w.CreateHeader(&zip.FileHeader{Name: filepath.ToSlash(name), Method: zip.Deflate})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, src)
+	return err
+}
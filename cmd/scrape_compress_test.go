@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestCompressOutputs(t *testing.T) {
+	const hash = "c984d06aafbecf6bc55569f964148ea3"
+	// Table Driven Test
+	tests := []struct {
+		name        string
+		files       []string
+		wantEntries []string
+		wantDirLeft bool
+	}{
+		{name: "flat case", files: []string{hash + ".png", hash + ".network.json"}, wantEntries: []string{hash + ".network.json", hash + ".png"}},
+		{name: "nested case", files: []string{hash + "/screenshot.png"}, wantEntries: []string{hash + "/screenshot.png"}},
+		{name: "cookies case", files: []string{hash + ".png", hash + ".cookies.json"}, wantEntries: []string{hash + ".cookies.json", hash + ".png"}},
+		{name: "unrelated file case", files: []string{hash + ".png", ".scrape-cache.json"}, wantEntries: []string{hash + ".png"}, wantDirLeft: true},
+		{name: "empty case", files: nil, wantEntries: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "artifacts")
+			if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+				t.Fatal(err)
+			}
+			for _, f := range tt.files {
+				path := filepath.Join(dir, f)
+				if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, bytes.Repeat([]byte("a"), 1000), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			summary, err := compressOutputs(dir)
+			if err != nil {
+				t.Fatalf("%s: compressOutputs() error = %v", tt.name, err)
+			}
+			if summary.Archive != dir+".zip" || summary.Files != len(tt.wantEntries) {
+				t.Errorf("%s: compressOutputs() = %+v; want %d file(s) in %s.zip", tt.name, summary, len(tt.wantEntries), dir)
+			}
+			if summary.Size != int64(1000*len(tt.wantEntries)) || summary.CompressedSize > summary.Size {
+				t.Errorf("%s: sizes = %d -> %d; want %d -> less", tt.name, summary.Size, summary.CompressedSize, 1000*len(tt.wantEntries))
+			}
+			r, err := zip.OpenReader(summary.Archive)
+			if err != nil {
+				t.Fatalf("%s: could not open archive: %v", tt.name, err)
+			}
+			defer r.Close()
+			entries := []string{}
+			for _, f := range r.File {
+				entries = append(entries, f.Name)
+			}
+			sort.Strings(entries)
+			if len(entries) != len(tt.wantEntries) || (len(entries) > 0 && entries[0] != tt.wantEntries[0]) {
+				t.Errorf("%s: entries = %v; want %v", tt.name, entries, tt.wantEntries)
+			}
+			if _, err := os.Stat(dir); (err == nil) != tt.wantDirLeft {
+				t.Errorf("%s: directory left = %t; want %t", tt.name, err == nil, tt.wantDirLeft)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// exportedCookie is a cookie of a `<name>.cookies.json` file
+type exportedCookie struct {
+	playwright.Cookie
+	// Session cookies have no expiry and are dropped when the browser closes
+	Session bool `json:"session"`
+	// ThirdParty cookies are not sent to the page host, such as the ones of trackers embedded in the page
+	ThirdParty bool `json:"third_party"`
+}
+
+// cookieExport is the content of a `<name>.cookies.json` file
+type cookieExport struct {
+	URL     string           `json:"url"`
+	Cookies []exportedCookie `json:"cookies"`
+}
+
+// newCookieExport classifies the cookies of the browser context against the host of the page URL
+func newCookieExport(pageURL string, cookies []playwright.Cookie) cookieExport {
+	host := ""
+	if u, err := url.Parse(pageURL); err == nil {
+		host = u.Hostname()
+	}
+	e := cookieExport{URL: pageURL, Cookies: make([]exportedCookie, 0, len(cookies))}
+	for _, c := range cookies {
+		e.Cookies = append(e.Cookies, exportedCookie{
+			Cookie:     c,
+			Session:    c.Expires < 0,
+			ThirdParty: !cookieDomainMatch(host, c.Domain),
+		})
+	}
+	return e
+}
+
+// cookieDomainMatch reports whether a cookie of domain is sent to host, a leading dot matches the subdomains too
+func cookieDomainMatch(host, domain string) bool {
+	host = strings.ToLower(host)
+	domain = strings.ToLower(domain)
+	if trimmed, ok := strings.CutPrefix(domain, "."); ok {
+		return host == trimmed || strings.HasSuffix(host, domain)
+	}
+	return host == domain
+}
+
+// optionalCookies converts cookies read from a browser context to cookies that can be added to one
+func optionalCookies(cookies []playwright.Cookie) []playwright.OptionalCookie {
+	optional := make([]playwright.OptionalCookie, 0, len(cookies))
+	for _, c := range cookies {
+		optional = append(optional, playwright.OptionalCookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   playwright.String(c.Domain),
+			Path:     playwright.String(c.Path),
+			Expires:  playwright.Float(c.Expires),
+			HttpOnly: playwright.Bool(c.HttpOnly),
+			Secure:   playwright.Bool(c.Secure),
+			SameSite: c.SameSite,
+		})
+	}
+	return optional
+}
+
+// resetCookies replaces the cookies of the browser context with base
+func resetCookies(browserContext playwright.BrowserContext, base []playwright.OptionalCookie) error {
+	if err := browserContext.ClearCookies(); err != nil {
+		return err
+	}
+	if len(base) == 0 {
+		return nil
+	}
+	return browserContext.AddCookies(base)
+}
+
+// writeCookieExport writes the cookies next to the screenshot at path
+func writeCookieExport(path string, e cookieExport) (string, error) {
+	data, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("could not marshal cookies: %w", err)
+	}
+	cookiesPath := strings.TrimSuffix(path, ".png") + ".cookies.json"
+	if err := os.WriteFile(cookiesPath, data, 0644); err != nil {
+		return "", fmt.Errorf("could not write cookies: %w", err)
+	}
+	return cookiesPath, nil
+}
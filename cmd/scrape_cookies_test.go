@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+func TestCookieDomainMatch(t *testing.T) {
+	// Table Driven Test
+	tests := []struct {
+		name   string
+		host   string
+		domain string
+		want   bool
+	}{
+		{name: "host-only case", host: "example.com", domain: "example.com", want: true},
+		{name: "host-only subdomain case", host: "www.example.com", domain: "example.com", want: false},
+		{name: "domain case", host: "www.example.com", domain: ".example.com", want: true},
+		{name: "domain apex case", host: "example.com", domain: ".example.com", want: true},
+		{name: "case insensitive case", host: "WWW.Example.com", domain: ".example.COM", want: true},
+		{name: "suffix without dot case", host: "badexample.com", domain: ".example.com", want: false},
+		{name: "tracker case", host: "www.example.com", domain: ".tracker.net", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cookieDomainMatch(tt.host, tt.domain); got != tt.want {
+				t.Errorf("%s: cookieDomainMatch(%q, %q) = %t; want %t", tt.name, tt.host, tt.domain, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewCookieExport(t *testing.T) {
+	session := playwright.Cookie{Name: "sid", Value: "1", Domain: "www.example.com", Path: "/", Expires: -1}
+	tracker := playwright.Cookie{Name: "_ga", Value: "2", Domain: ".tracker.net", Path: "/", Expires: 1893456000}
+
+	// Table Driven Test
+	tests := []struct {
+		name    string
+		url     string
+		cookies []playwright.Cookie
+		want    cookieExport
+	}{
+		{name: "no cookies case", url: "https://www.example.com/", cookies: nil, want: cookieExport{URL: "https://www.example.com/", Cookies: []exportedCookie{}}},
+		{
+			name:    "nominal case",
+			url:     "https://www.example.com/",
+			cookies: []playwright.Cookie{session, tracker},
+			want: cookieExport{URL: "https://www.example.com/", Cookies: []exportedCookie{
+				{Cookie: session, Session: true, ThirdParty: false},
+				{Cookie: tracker, Session: false, ThirdParty: true},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newCookieExport(tt.url, tt.cookies); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s: newCookieExport() = %+v; want %+v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOptionalCookies(t *testing.T) {
+	lax := playwright.SameSiteAttributeLax
+	session := playwright.Cookie{Name: "sid", Value: "1", Domain: "www.example.com", Path: "/", Expires: -1, HttpOnly: true, Secure: true, SameSite: lax}
+
+	// Table Driven Test
+	tests := []struct {
+		name    string
+		cookies []playwright.Cookie
+		want    []playwright.OptionalCookie
+	}{
+		{name: "no cookies case", cookies: nil, want: []playwright.OptionalCookie{}},
+		{
+			name:    "nominal case",
+			cookies: []playwright.Cookie{session},
+			want: []playwright.OptionalCookie{{
+				Name:     "sid",
+				Value:    "1",
+				Domain:   playwright.String("www.example.com"),
+				Path:     playwright.String("/"),
+				Expires:  playwright.Float(-1),
+				HttpOnly: playwright.Bool(true),
+				Secure:   playwright.Bool(true),
+				SameSite: lax,
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := optionalCookies(tt.cookies); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s: optionalCookies() = %+v; want %+v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteCookieExport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "page.png")
+	e := newCookieExport("https://example.com/", []playwright.Cookie{{Name: "sid", Value: "1", Domain: "example.com", Path: "/", Expires: -1}})
+	got, err := writeCookieExport(path, e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(filepath.Dir(path), "page.cookies.json"); got != want {
+		t.Errorf("writeCookieExport() = %q; want %q", got, want)
+	}
+	data, err := os.ReadFile(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cookies struct {
+		Cookies []map[string]any `json:"cookies"`
+	}
+	if err := json.Unmarshal(data, &cookies); err != nil {
+		t.Fatal(err)
+	}
+	if len(cookies.Cookies) != 1 || cookies.Cookies[0]["name"] != "sid" || cookies.Cookies[0]["session"] != true {
+		t.Errorf("writeCookieExport() wrote %s; want the flattened sid session cookie", data)
+	}
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"strings"
+)
+
+// scrapeChange is the webhook payload sent when a page changed between two cycles
+type scrapeChange struct {
+	URL           string  `json:"url"`
+	ChangePercent float64 `json:"change_percent"`
+	DiffImage     string  `json:"diff_image"`
+}
+
+// diffImages returns the percentage of differing pixels over the union of both bounds,
+// and an image where the differing pixels are painted red over a faded copy of b.
+func diffImages(a, b image.Image) (float64, *image.RGBA) {
+	bounds := a.Bounds().Union(b.Bounds())
+	diff := image.NewRGBA(bounds)
+	if bounds.Empty() {
+		return 0, diff
+	}
+
+	changed := 0
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			p := image.Pt(x, y)
+			ca, cb := pixelAt(a, p), pixelAt(b, p)
+			if ca != cb {
+				changed++
+				diff.Set(x, y, color.RGBA{R: 255, A: 255})
+				continue
+			}
+			// Fade unchanged pixels so that the changes stand out
+			diff.Set(x, y, color.RGBA{R: cb.R/4 + 191, G: cb.G/4 + 191, B: cb.B/4 + 191, A: 255})
+		}
+	}
+	return 100 * float64(changed) / float64(bounds.Dx()*bounds.Dy()), diff
+}
+
+// pixelAt returns the color of img at p, transparent when p is outside its bounds
+func pixelAt(img image.Image, p image.Point) color.RGBA {
+	if !p.In(img.Bounds()) {
+		return color.RGBA{}
+	}
+	return color.RGBAModel.Convert(img.At(p.X, p.Y)).(color.RGBA)
+}
+
+// diffFiles compares two PNG files and writes the diff image next to current
+func diffFiles(previous, current string) (float64, string, error) {
+	a, err := readPNG(previous)
+	if err != nil {
+		return 0, "", err
+	}
+	b, err := readPNG(current)
+	if err != nil {
+		return 0, "", err
+	}
+	percent, diff := diffImages(a, b)
+
+	diffPath := strings.TrimSuffix(current, ".png") + ".diff.png"
+	f, err := os.Create(diffPath)
+	if err != nil {
+		return 0, "", fmt.Errorf("could not create diff image: %w", err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, diff); err != nil {
+		return 0, "", fmt.Errorf("could not encode diff image: %w", err)
+	}
+	return percent, diffPath, nil
+}
+
+func readPNG(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open image: %w", err)
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode %s: %w", path, err)
+	}
+	return img, nil
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// filledImage returns a w x h image of c with the first n pixels of the first row set to changed
+func filledImage(w, h int, c color.RGBA, n int, changed color.RGBA) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	for x := 0; x < n; x++ {
+		img.Set(x, 0, changed)
+	}
+	return img
+}
+
+func TestDiffImages(t *testing.T) {
+	white := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	black := color.RGBA{A: 255}
+
+	// Table Driven Test
+	tests := []struct {
+		name string
+		a    image.Image
+		b    image.Image
+		want float64
+	}{
+		{name: "identical case", a: filledImage(10, 10, white, 0, black), b: filledImage(10, 10, white, 0, black), want: 0},
+		{name: "partial case", a: filledImage(10, 10, white, 0, black), b: filledImage(10, 10, white, 5, black), want: 5},
+		{name: "different size case", a: filledImage(10, 5, white, 0, black), b: filledImage(10, 10, white, 0, black), want: 50},
+		{name: "empty case", a: image.NewRGBA(image.Rect(0, 0, 0, 0)), b: image.NewRGBA(image.Rect(0, 0, 0, 0)), want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, diff := diffImages(tt.a, tt.b)
+			if got != tt.want {
+				t.Errorf("%s: diffImages() = %v; want %v", tt.name, got, tt.want)
+			}
+			if diff.Bounds() != tt.a.Bounds().Union(tt.b.Bounds()) {
+				t.Errorf("%s: diff bounds = %v; want %v", tt.name, diff.Bounds(), tt.a.Bounds().Union(tt.b.Bounds()))
+			}
+		})
+	}
+}
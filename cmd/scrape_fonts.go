@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// fontsWaitTimeout bounds waitForFonts, it matches the default timeout of the Playwright waits
+const fontsWaitTimeout = 30 * time.Second
+
+// waitFontsScript resolves to true once document.fonts.ready resolves, or to false after the given milliseconds
+const waitFontsScript = `timeout => Promise.race([
+	document.fonts.ready.then(() => true),
+	new Promise(resolve => setTimeout(() => resolve(false), timeout)),
+])`
+
+// waitForFonts waits for the web fonts of the page to be loaded and reports whether they were loaded in time
+func waitForFonts(page playwright.Page, timeout time.Duration) (bool, error) {
+	loaded, err := page.Evaluate(waitFontsScript, timeout.Milliseconds())
+	if err != nil {
+		return false, fmt.Errorf("could not wait for fonts: %w", err)
+	}
+	ok, _ := loaded.(bool)
+	return ok, nil
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// installHint tells how to fix a missing Playwright driver or browser
+const installHint = "run `misctl scrape --install-browsers` to install the Playwright driver and Chromium"
+
+// missingInstallMarkers are the error messages Playwright gives when the driver or a browser is not installed
+var missingInstallMarkers = []string{
+	"please install the driver",
+	"Executable doesn't exist",
+}
+
+// withInstallHint adds installHint to errors caused by a missing driver or browser
+func withInstallHint(err error) error {
+	if err == nil {
+		return nil
+	}
+	for _, marker := range missingInstallMarkers {
+		if strings.Contains(err.Error(), marker) {
+			return fmt.Errorf("%w\n%s", err, installHint)
+		}
+	}
+	return err
+}
+
+// installBrowsers downloads the Playwright driver and Chromium, writing the progress to out
+func installBrowsers(out io.Writer) error {
+	return playwright.Install(&playwright.RunOptions{
+		Browsers: []string{"chromium"},
+		Stdout:   out,
+		Stderr:   out,
+	})
+}
@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestWithInstallHint(t *testing.T) {
+	// Table Driven Test
+	tests := []struct {
+		name     string
+		err      error
+		wantHint bool
+	}{
+		{name: "nil case", err: nil, wantHint: false},
+		{name: "missing driver case", err: errors.New("please install the driver (v1.41.2) and browsers first: <nil>"), wantHint: true},
+		{name: "missing browser case", err: errors.New("browserType.launch: Executable doesn't exist at /root/.cache/ms-playwright/chromium-1097/chrome-linux/chrome"), wantHint: true},
+		{name: "other error case", err: errors.New("net::ERR_NAME_NOT_RESOLVED"), wantHint: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := withInstallHint(tt.err)
+			if !errors.Is(got, tt.err) {
+				t.Errorf("%s: withInstallHint(%v) = %v; want it to wrap the error", tt.name, tt.err, got)
+			}
+			if hinted := got != nil && got.Error() != tt.err.Error(); hinted != tt.wantHint {
+				t.Errorf("%s: withInstallHint(%v) = %v; want hint %t", tt.name, tt.err, got, tt.wantHint)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+)
+
+// scrapeJob describes how a single URL is captured
+type scrapeJob struct {
+	URL string `json:"url"`
+	// Selector restricts the screenshot to the first element matching it
+	Selector string `json:"selector,omitempty"`
+	// WaitFor is a selector to wait for before capturing
+	WaitFor  string `json:"wait_for,omitempty"`
+	FullPage bool   `json:"full_page,omitempty"`
+}
+
+// validate checks that the job can be captured
+func (j scrapeJob) validate() error {
+	if j.URL == "" {
+		return errors.New("url is required")
+	}
+	u, err := url.Parse(j.URL)
+	if err != nil {
+		return fmt.Errorf("invalid url: %w", err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("invalid url %q: scheme and host are required", j.URL)
+	}
+	return nil
+}
+
+// parseJobs reads one JSON job per line, blank lines are skipped.
+// Every invalid line is reported with its line number.
+func parseJobs(r io.Reader) ([]scrapeJob, error) {
+	jobs := []scrapeJob{}
+	var errs []error
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		var job scrapeJob
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&job); err != nil {
+			errs = append(errs, fmt.Errorf("line %d: %w", line, err))
+			continue
+		}
+		if err := job.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("line %d: %w", line, err))
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+	if err := scanner.Err(); err != nil {
+		errs = append(errs, err)
+	}
+	return jobs, errors.Join(errs...)
+}
+
+// checkDuplicateJobs rejects the urls given more than once, the artifacts of a url are named after it
+// so the captures of its jobs would overwrite each other
+func checkDuplicateJobs(jobs []scrapeJob) error {
+	seen := map[string]bool{}
+	var errs []error
+	for _, job := range jobs {
+		if seen[job.URL] {
+			errs = append(errs, fmt.Errorf("duplicate url %q", job.URL))
+			continue
+		}
+		seen[job.URL] = true
+	}
+	return errors.Join(errs...)
+}
+
+// loadJobs reads the jobs file at path
+func loadJobs(path string) ([]scrapeJob, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open jobs file: %w", err)
+	}
+	defer f.Close()
+	return parseJobs(f)
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseJobs(t *testing.T) {
+	// Table Driven Test
+	tests := []struct {
+		name    string
+		input   string
+		want    []scrapeJob
+		wantErr string
+	}{
+		{
+			name:  "nominal case",
+			input: `{"url": "https://example.com", "selector": "#main", "wait_for": ".loaded", "full_page": true}`,
+			want:  []scrapeJob{{URL: "https://example.com", Selector: "#main", WaitFor: ".loaded", FullPage: true}},
+		},
+		{
+			name:  "blank lines case",
+			input: "\n{\"url\": \"https://example.com\"}\n\n{\"url\": \"https://example.org\"}\n",
+			want:  []scrapeJob{{URL: "https://example.com"}, {URL: "https://example.org"}},
+		},
+		{name: "invalid json case", input: "{\"url\": \"https://example.com\"}\n{\"url\":", wantErr: "line 2"},
+		{name: "unknown field case", input: `{"url": "https://example.com", "fullpage": true}`, wantErr: "line 1"},
+		{name: "missing url case", input: `{"selector": "#main"}`, wantErr: "line 1: url is required"},
+		{name: "relative url case", input: `{"url": "example.com"}`, wantErr: "line 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseJobs(strings.NewReader(tt.input))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("%s: parseJobs error = %v; want it to contain %q", tt.name, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("%s: parseJobs returned error: %v", tt.name, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s: parseJobs = %+v; want %+v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckDuplicateJobs(t *testing.T) {
+	// Table Driven Test
+	tests := []struct {
+		name    string
+		jobs    []scrapeJob
+		wantErr string
+	}{
+		{name: "no jobs case", jobs: nil},
+		{name: "distinct urls case", jobs: []scrapeJob{{URL: "https://example.com"}, {URL: "https://example.org"}}},
+		{name: "duplicate url case", jobs: []scrapeJob{{URL: "https://example.com"}, {URL: "https://example.com", Selector: "#main"}}, wantErr: `duplicate url "https://example.com"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkDuplicateJobs(tt.jobs)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("%s: checkDuplicateJobs() error = %v; want nil", tt.name, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: checkDuplicateJobs() error = %v; want it to contain %q", tt.name, err, tt.wantErr)
+			}
+		})
+	}
+}
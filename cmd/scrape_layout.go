@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// layoutFlat writes every artifact in the output directory, e.g. <hash>.png
+	layoutFlat = "flat"
+	// layoutNested writes the artifacts of each url in its own directory, e.g. <hash>/screenshot.png
+	layoutNested = "nested"
+)
+
+// artifactPath returns the screenshot path of the url in the output directory.
+// The other artifacts of the url are written next to it.
+func artifactPath(dir, layout, url string) (string, error) {
+	fileName, err := getFileName(url)
+	if err != nil {
+		return "", err
+	}
+	switch layout {
+	case layoutFlat:
+		return filepath.Join(dir, fileName), nil
+	case layoutNested:
+		return filepath.Join(dir, strings.TrimSuffix(fileName, ".png"), "screenshot.png"), nil
+	}
+	return "", fmt.Errorf("unknown layout %q, must be one of %s or %s", layout, layoutFlat, layoutNested)
+}
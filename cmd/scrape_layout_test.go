@@ -0,0 +1,28 @@
+package cmd
+
+import "testing"
+
+func TestArtifactPath(t *testing.T) {
+	// Table Driven Test
+	tests := []struct {
+		name    string
+		layout  string
+		want    string
+		wantErr bool
+	}{
+		{name: "flat case", layout: layoutFlat, want: "out/c984d06aafbecf6bc55569f964148ea3.png"},
+		{name: "nested case", layout: layoutNested, want: "out/c984d06aafbecf6bc55569f964148ea3/screenshot.png"},
+		{name: "unknown layout case", layout: "tree", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := artifactPath("out", tt.layout, "https://example.com")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("%s: artifactPath(%q) error = %v; want error %t", tt.name, tt.layout, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("%s: artifactPath(%q) = %s; want %s", tt.name, tt.layout, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// networkSlowestCount is the number of slowest requests kept in a network summary
+const networkSlowestCount = 5
+
+// networkRequest is a single request of a page
+type networkRequest struct {
+	URL          string  `json:"url"`
+	ResourceType string  `json:"resource_type"`
+	Bytes        int     `json:"bytes"`
+	DurationMs   float64 `json:"duration_ms"`
+	Failed       bool    `json:"failed,omitempty"`
+}
+
+// resourceTypeStats aggregates the requests of a resource type
+type resourceTypeStats struct {
+	Requests int `json:"requests"`
+	Bytes    int `json:"bytes"`
+}
+
+// networkSummary is the content of a `<name>.network.json` file
+type networkSummary struct {
+	URL        string                       `json:"url"`
+	Requests   int                          `json:"requests"`
+	Failed     int                          `json:"failed"`
+	TotalBytes int                          `json:"total_bytes"`
+	ByType     map[string]resourceTypeStats `json:"by_type"`
+	Slowest    []networkRequest             `json:"slowest"`
+}
+
+// summarizeRequests aggregates the requests by resource type and keeps the slowest ones
+func summarizeRequests(url string, requests []networkRequest) networkSummary {
+	s := networkSummary{URL: url, ByType: map[string]resourceTypeStats{}}
+	for _, r := range requests {
+		s.Requests++
+		if r.Failed {
+			s.Failed++
+		}
+		s.TotalBytes += r.Bytes
+		stats := s.ByType[r.ResourceType]
+		stats.Requests++
+		stats.Bytes += r.Bytes
+		s.ByType[r.ResourceType] = stats
+	}
+
+	slowest := make([]networkRequest, len(requests))
+	copy(slowest, requests)
+	sort.SliceStable(slowest, func(i, j int) bool { return slowest[i].DurationMs > slowest[j].DurationMs })
+	if len(slowest) > networkSlowestCount {
+		slowest = slowest[:networkSlowestCount]
+	}
+	s.Slowest = slowest
+	return s
+}
+
+// requestRecorder collects the requests of a page while it is captured.
+// The handlers only keep the requests, their sizes are fetched afterwards since
+// Playwright calls cannot be made from an event handler.
+type requestRecorder struct {
+	mu       sync.Mutex
+	page     playwright.Page
+	finished []playwright.Request
+	failed   []playwright.Request
+}
+
+func (r *requestRecorder) onFinished(req playwright.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.finished = append(r.finished, req)
+}
+
+func (r *requestRecorder) onFailed(req playwright.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.failed = append(r.failed, req)
+}
+
+// recordRequests starts recording the requests of page until stop is called
+func recordRequests(page playwright.Page) *requestRecorder {
+	r := &requestRecorder{page: page}
+	page.On("requestfinished", r.onFinished)
+	page.On("requestfailed", r.onFailed)
+	return r
+}
+
+// stop stops the recording, it can be called several times
+func (r *requestRecorder) stop() {
+	r.page.RemoveListener("requestfinished", r.onFinished)
+	r.page.RemoveListener("requestfailed", r.onFailed)
+}
+
+// requests returns the recorded requests along with their size and duration
+func (r *requestRecorder) requests() []networkRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	requests := make([]networkRequest, 0, len(r.finished)+len(r.failed))
+	for _, req := range r.finished {
+		nr := networkRequest{URL: req.URL(), ResourceType: req.ResourceType()}
+		if sizes, err := req.Sizes(); err == nil {
+			nr.Bytes = sizes.ResponseHeadersSize + sizes.ResponseBodySize
+		}
+		if timing := req.Timing(); timing != nil && timing.ResponseEnd > 0 {
+			nr.DurationMs = timing.ResponseEnd
+		}
+		requests = append(requests, nr)
+	}
+	for _, req := range r.failed {
+		requests = append(requests, networkRequest{URL: req.URL(), ResourceType: req.ResourceType(), Failed: true})
+	}
+	return requests
+}
+
+// writeNetworkSummary writes the summary next to the screenshot at path
+func writeNetworkSummary(path string, s networkSummary) (string, error) {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("could not marshal network summary: %w", err)
+	}
+	summaryPath := strings.TrimSuffix(path, ".png") + ".network.json"
+	if err := os.WriteFile(summaryPath, data, 0644); err != nil {
+		return "", fmt.Errorf("could not write network summary: %w", err)
+	}
+	return summaryPath, nil
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSummarizeRequests(t *testing.T) {
+	requests := []networkRequest{
+		{URL: "a", ResourceType: "document", Bytes: 100, DurationMs: 10},
+		{URL: "b", ResourceType: "script", Bytes: 300, DurationMs: 50},
+		{URL: "c", ResourceType: "script", Bytes: 200, DurationMs: 30},
+		{URL: "d", ResourceType: "image", Failed: true},
+		{URL: "e", ResourceType: "image", Bytes: 50, DurationMs: 5},
+		{URL: "f", ResourceType: "font", Bytes: 10, DurationMs: 40},
+	}
+
+	// Table Driven Test
+	tests := []struct {
+		name     string
+		requests []networkRequest
+		want     networkSummary
+	}{
+		{
+			name:     "empty case",
+			requests: nil,
+			want:     networkSummary{URL: "page", ByType: map[string]resourceTypeStats{}, Slowest: []networkRequest{}},
+		},
+		{
+			name:     "nominal case",
+			requests: requests,
+			want: networkSummary{
+				URL:        "page",
+				Requests:   6,
+				Failed:     1,
+				TotalBytes: 660,
+				ByType: map[string]resourceTypeStats{
+					"document": {Requests: 1, Bytes: 100},
+					"script":   {Requests: 2, Bytes: 500},
+					"image":    {Requests: 2, Bytes: 50},
+					"font":     {Requests: 1, Bytes: 10},
+				},
+				Slowest: []networkRequest{requests[1], requests[5], requests[2], requests[0], requests[4]},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := summarizeRequests("page", tt.requests)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s: summarizeRequests() = %+v; want %+v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// redirectSettleTimeout is how long a rendered page has to start a client-side redirect, e.g. a meta refresh,
+// before it is considered final
+const redirectSettleTimeout = 3 * time.Second
+
+// redirectChain tracks the client-side redirects of a page, failing on loops and past max redirects
+type redirectChain struct {
+	urls []string
+	max  int
+}
+
+func newRedirectChain(start string, max int) *redirectChain {
+	return &redirectChain{urls: []string{start}, max: max}
+}
+
+// follow records a redirect to url
+func (c *redirectChain) follow(url string) error {
+	for _, u := range c.urls {
+		if withoutFragment(u) == withoutFragment(url) {
+			return fmt.Errorf("redirect loop: %s -> %s", strings.Join(c.urls, " -> "), url)
+		}
+	}
+	c.urls = append(c.urls, url)
+	if c.redirects() > c.max {
+		return fmt.Errorf("more than %d redirects: %s", c.max, strings.Join(c.urls, " -> "))
+	}
+	return nil
+}
+
+// redirects returns the number of redirects followed
+func (c *redirectChain) redirects() int {
+	return len(c.urls) - 1
+}
+
+// final returns the URL the chain ended on
+func (c *redirectChain) final() string {
+	return c.urls[len(c.urls)-1]
+}
+
+// withoutFragment strips the fragment, which changes without navigating to another document
+func withoutFragment(url string) string {
+	url, _, _ = strings.Cut(url, "#")
+	return url
+}
+
+// followRenderRedirects waits for the JavaScript and meta refresh redirects of the loaded page to settle.
+// It returns the chain ending on the final URL, or an error on a loop or past max redirects.
+func followRenderRedirects(page playwright.Page, max int) (*redirectChain, error) {
+	chain := newRedirectChain(page.URL(), max)
+	for {
+		current := withoutFragment(page.URL())
+		err := page.WaitForURL(func(url string) bool { return withoutFragment(url) != current }, playwright.PageWaitForURLOptions{
+			Timeout:   playwright.Float(float64(redirectSettleTimeout.Milliseconds())),
+			WaitUntil: playwright.WaitUntilStateDomcontentloaded,
+		})
+		if errors.Is(err, playwright.ErrTimeout) {
+			// No further redirect, the page settled
+			return chain, nil
+		}
+		if err != nil {
+			return chain, fmt.Errorf("could not follow redirect from %s: %w", current, err)
+		}
+		if err := chain.follow(page.URL()); err != nil {
+			return chain, err
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import "testing"
+
+func TestRedirectChain(t *testing.T) {
+	// Table Driven Test
+	tests := []struct {
+		name          string
+		max           int
+		follow        []string
+		wantErr       bool
+		wantFinal     string
+		wantRedirects int
+	}{
+		{name: "no redirect case", max: 3, wantFinal: "https://a.example/"},
+		{name: "chain case", max: 3, follow: []string{"https://b.example/", "https://c.example/"}, wantFinal: "https://c.example/", wantRedirects: 2},
+		{name: "loop case", max: 10, follow: []string{"https://b.example/", "https://a.example/"}, wantErr: true},
+		{name: "fragment loop case", max: 10, follow: []string{"https://a.example/#top"}, wantErr: true},
+		{name: "cap case", max: 1, follow: []string{"https://b.example/", "https://c.example/"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newRedirectChain("https://a.example/", tt.max)
+			var err error
+			for _, url := range tt.follow {
+				if err = c.follow(url); err != nil {
+					break
+				}
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("%s: follow() error = %v; want error %t", tt.name, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if c.final() != tt.wantFinal || c.redirects() != tt.wantRedirects {
+				t.Errorf("%s: final() = %s, redirects() = %d; want %s, %d", tt.name, c.final(), c.redirects(), tt.wantFinal, tt.wantRedirects)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Statuses of a streamed scrape record
+const (
+	scrapeStatusScraped   = "scraped"
+	scrapeStatusFailed    = "failed"
+	scrapeStatusCached    = "cached"
+	scrapeStatusUnchanged = "unchanged"
+)
+
+// scrapeRecord is the result of a single URL streamed with `--stream-results`
+type scrapeRecord struct {
+	Time         time.Time `json:"time"`
+	URL          string    `json:"url"`
+	Status       string    `json:"status"`
+	Path         string    `json:"path,omitempty"`
+	LoadMs       float64   `json:"load_ms,omitempty"`
+	ScreenshotMs float64   `json:"screenshot_ms,omitempty"`
+	Error        string    `json:"error,omitempty"`
+	// TLSIssue is the TLS verification error ignored with `--insecure`
+	TLSIssue string `json:"tls_issue,omitempty"`
+	// AbortedOn is the `--abort-on-selector` selector found in the page
+	AbortedOn string `json:"aborted_on,omitempty"`
+	// FinalURL is the URL captured after the `--follow-redirects-render` redirects
+	FinalURL  string `json:"final_url,omitempty"`
+	Redirects int    `json:"redirects,omitempty"`
+}
+
+// newScrapeRecord builds the record of a capture, err is the capture error if any
+func newScrapeRecord(result captureResult, err error, now time.Time) scrapeRecord {
+	r := scrapeRecord{
+		Time:         now,
+		URL:          result.URL,
+		Status:       scrapeStatusScraped,
+		Path:         result.Path,
+		LoadMs:       durationMs(result.LoadTime),
+		ScreenshotMs: durationMs(result.ScreenshotTime),
+		FinalURL:     result.FinalURL,
+		Redirects:    result.Redirects,
+	}
+	if err != nil {
+		r.Status = scrapeStatusFailed
+		r.Error = err.Error()
+		var abort *abortError
+		if errors.As(err, &abort) {
+			r.AbortedOn = abort.Selector
+		}
+	}
+	return r
+}
+
+// writeRecord writes r as a single JSON line
+func writeRecord(w io.Writer, r scrapeRecord) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("could not marshal record: %w", err)
+	}
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
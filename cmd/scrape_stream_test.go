@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWriteRecord(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	result := captureResult{URL: "https://example.com", Path: "out/a.png", LoadTime: 1500 * time.Microsecond, ScreenshotTime: 2 * time.Millisecond}
+
+	// Table Driven Test
+	tests := []struct {
+		name   string
+		record scrapeRecord
+		want   string
+	}{
+		{
+			name:   "scraped case",
+			record: newScrapeRecord(result, nil, now),
+			want:   `{"time":"2024-01-02T03:04:05Z","url":"https://example.com","status":"scraped","path":"out/a.png","load_ms":1.5,"screenshot_ms":2}` + "\n",
+		},
+		{
+			name:   "failed case",
+			record: newScrapeRecord(captureResult{URL: "https://example.com"}, errors.New("could not goto"), now),
+			want:   `{"time":"2024-01-02T03:04:05Z","url":"https://example.com","status":"failed","error":"could not goto"}` + "\n",
+		},
+		{
+			name:   "cached case",
+			record: scrapeRecord{Time: now, URL: "https://example.com", Status: scrapeStatusCached},
+			want:   `{"time":"2024-01-02T03:04:05Z","url":"https://example.com","status":"cached"}` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeRecord(&buf, tt.record); err != nil {
+				t.Errorf("%s: writeRecord returned error: %v", tt.name, err)
+				return
+			}
+			if buf.String() != tt.want {
+				t.Errorf("%s: writeRecord = %s; want %s", tt.name, buf.String(), tt.want)
+			}
+		})
+	}
+}
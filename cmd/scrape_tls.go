@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+)
+
+// tlsCheckTimeout bounds the handshake made by checkTLS
+const tlsCheckTimeout = 10 * time.Second
+
+// checkTLS makes a verified TLS handshake with the host of rawURL and returns the certificate verification
+// error as issue, if any. Other failures, such as DNS errors, refused connections and timeouts, are returned
+// as err since they say nothing about the certificate. URLs that are not https are not checked.
+func checkTLS(ctx context.Context, rawURL string) (issue error, err error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url: %w", err)
+	}
+	if u.Scheme != "https" {
+		return nil, nil
+	}
+	port := u.Port()
+	if port == "" {
+		port = "443"
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, tlsCheckTimeout)
+	defer cancel()
+	dialer := &tls.Dialer{
+		NetDialer: &net.Dialer{},
+		Config:    &tls.Config{ServerName: u.Hostname(), MinVersion: tls.VersionTLS12},
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(u.Hostname(), port))
+	if err != nil {
+		if isCertificateError(err) {
+			return err, nil
+		}
+		return nil, err
+	}
+	return nil, conn.Close()
+}
+
+// isCertificateError reports whether err is a failed certificate verification
+func isCertificateError(err error) bool {
+	var verification *tls.CertificateVerificationError
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostname x509.HostnameError
+	var invalid x509.CertificateInvalidError
+	return errors.As(err, &verification) || errors.As(err, &unknownAuthority) ||
+		errors.As(err, &hostname) || errors.As(err, &invalid)
+}
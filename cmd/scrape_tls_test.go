@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"context"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckTLS(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	tlsServer := httptest.NewUnstartedServer(handler)
+	// The failed handshakes are expected, keep them out of the test output
+	tlsServer.Config.ErrorLog = log.New(io.Discard, "", 0)
+	tlsServer.StartTLS()
+	defer tlsServer.Close()
+	plainServer := httptest.NewServer(handler)
+	defer plainServer.Close()
+	// A port nothing listens on anymore refuses the connection
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unreachable := "https://" + ln.Addr().String()
+	ln.Close()
+
+	// Table Driven Test
+	tests := []struct {
+		name      string
+		url       string
+		wantIssue bool
+		wantErr   bool
+	}{
+		{name: "self-signed certificate case", url: tlsServer.URL, wantIssue: true, wantErr: false},
+		{name: "plain http case", url: plainServer.URL, wantIssue: false, wantErr: false},
+		{name: "unreachable host case", url: unreachable, wantIssue: false, wantErr: true},
+		{name: "invalid url case", url: "https://[::1", wantIssue: false, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issue, err := checkTLS(context.Background(), tt.url)
+			if (issue != nil) != tt.wantIssue || (err != nil) != tt.wantErr {
+				t.Errorf("%s: checkTLS(%s) = %v, %v; want issue %t and error %t", tt.name, tt.url, issue, err, tt.wantIssue, tt.wantErr)
+			}
+		})
+	}
+}
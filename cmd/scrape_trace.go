@@ -0,0 +1,17 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// tracePath returns the trace file of a run. Repeated runs get one trace per cycle,
+// e.g. trace.zip becomes trace-3.zip for the third cycle.
+func tracePath(path string, cycle int, repeated bool) string {
+	if !repeated {
+		return path
+	}
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s-%d%s", strings.TrimSuffix(path, ext), cycle, ext)
+}
@@ -0,0 +1,25 @@
+package cmd
+
+import "testing"
+
+func TestTracePath(t *testing.T) {
+	// Table Driven Test
+	tests := []struct {
+		name     string
+		path     string
+		cycle    int
+		repeated bool
+		want     string
+	}{
+		{name: "single run case", path: "out/trace.zip", cycle: 1, repeated: false, want: "out/trace.zip"},
+		{name: "repeated case", path: "out/trace.zip", cycle: 3, repeated: true, want: "out/trace-3.zip"},
+		{name: "no extension case", path: "trace", cycle: 2, repeated: true, want: "trace-2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tracePath(tt.path, tt.cycle, tt.repeated); got != tt.want {
+				t.Errorf("%s: tracePath(%q, %d, %t) = %s; want %s", tt.name, tt.path, tt.cycle, tt.repeated, got, tt.want)
+			}
+		})
+	}
+}
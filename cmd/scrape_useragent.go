@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// userAgentPresets maps the --ua-preset names to their user-agent strings
+var userAgentPresets = map[string]string{
+	"chrome":    "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+	"firefox":   "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:125.0) Gecko/20100101 Firefox/125.0",
+	"safari":    "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_4_1) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4.1 Safari/605.1.15",
+	"googlebot": "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+	"bingbot":   "Mozilla/5.0 (compatible; bingbot/2.0; +http://www.bing.com/bingbot.htm)",
+}
+
+// userAgentPresetNames returns the sorted preset names
+func userAgentPresetNames() []string {
+	names := make([]string, 0, len(userAgentPresets))
+	for name := range userAgentPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// resolveUserAgent returns the user agent of the context, an explicit user agent overrides the preset.
+// An empty result keeps the browser default. The preset is validated even when it is overridden.
+func resolveUserAgent(preset, userAgent string) (string, error) {
+	ua, ok := userAgentPresets[preset]
+	if preset != "" && !ok {
+		return "", fmt.Errorf("unknown user agent preset %q, must be one of %s", preset, strings.Join(userAgentPresetNames(), ", "))
+	}
+	if userAgent != "" {
+		return userAgent, nil
+	}
+	return ua, nil
+}
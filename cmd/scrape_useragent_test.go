@@ -0,0 +1,32 @@
+package cmd
+
+import "testing"
+
+func TestResolveUserAgent(t *testing.T) {
+	// Table Driven Test
+	tests := []struct {
+		name      string
+		preset    string
+		userAgent string
+		want      string
+		wantErr   bool
+	}{
+		{name: "default case", want: ""},
+		{name: "preset case", preset: "googlebot", want: userAgentPresets["googlebot"]},
+		{name: "override case", preset: "chrome", userAgent: "misctl/1.0", want: "misctl/1.0"},
+		{name: "unknown preset case", preset: "lynx", wantErr: true},
+		{name: "unknown preset override case", preset: "lynx", userAgent: "misctl/1.0", wantErr: true},
+		{name: "user agent only case", userAgent: "misctl/1.0", want: "misctl/1.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveUserAgent(tt.preset, tt.userAgent)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("%s: resolveUserAgent(%q, %q) error = %v; want error %t", tt.name, tt.preset, tt.userAgent, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("%s: resolveUserAgent(%q, %q) = %s; want %s", tt.name, tt.preset, tt.userAgent, got, tt.want)
+			}
+		})
+	}
+}
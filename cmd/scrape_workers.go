@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Isolation modes of the concurrent scrape workers.
+//
+// With a shared browser every worker gets a BrowserContext of a single Chromium process: contexts are
+// as isolated as incognito profiles (cookies, storage, cache) and cost a few MB each, while a browser
+// costs a process tree of 100+ MB. A crash or a hung renderer can however take every worker down.
+// A browser per worker isolates the workers at the process level, at the price of that memory per worker.
+const (
+	isolationSharedBrowser    = "shared-browser"
+	isolationPerWorkerBrowser = "per-worker-browser"
+)
+
+// scrapeWorkersOptions are the options constraining the number of scrape workers
+type scrapeWorkersOptions struct {
+	Concurrency      int
+	Isolation        string
+	Devtools         bool
+	Trace            string
+	SaveStorageState string
+}
+
+// validateWorkers checks the concurrency options. The inspector, the trace and the saved storage state
+// belong to a single context, so they require a single worker.
+func validateWorkers(opts scrapeWorkersOptions) error {
+	if opts.Concurrency < 1 {
+		return fmt.Errorf("invalid `concurrency` %d, must be at least 1", opts.Concurrency)
+	}
+	if opts.Isolation != isolationSharedBrowser && opts.Isolation != isolationPerWorkerBrowser {
+		return fmt.Errorf("invalid `context-isolation` %q, must be %s or %s", opts.Isolation, isolationSharedBrowser, isolationPerWorkerBrowser)
+	}
+	if opts.Concurrency > 1 && (opts.Devtools || opts.Trace != "" || opts.SaveStorageState != "") {
+		return errors.New("--devtools, --trace and --save-storage-state require --concurrency 1")
+	}
+	return nil
+}
+
+// Estimates of the file descriptors used by the workers, for the `--max-open-files` budget.
+// A Chromium process tree holds a few hundred descriptors (pipes, shared memory, fonts, caches),
+// each context and page adds its renderer and network connections.
+const (
+	openFilesReserve    = 64
+	openFilesPerBrowser = 256
+	openFilesPerWorker  = 32
+)
+
+// maxWorkersForOpenFiles returns the number of workers, at most concurrency and at least one,
+// whose estimated descriptors fit in maxOpenFiles. A maxOpenFiles of 0 does not limit the workers.
+func maxWorkersForOpenFiles(concurrency int, isolation string, maxOpenFiles uint64) int {
+	if maxOpenFiles == 0 {
+		return concurrency
+	}
+	perWorker := uint64(openFilesPerWorker)
+	fixed := uint64(openFilesReserve)
+	if isolation == isolationPerWorkerBrowser {
+		perWorker += openFilesPerBrowser
+	} else {
+		fixed += openFilesPerBrowser
+	}
+	if maxOpenFiles <= fixed+perWorker {
+		return 1
+	}
+	if workers := (maxOpenFiles - fixed) / perWorker; workers < uint64(concurrency) {
+		return int(workers)
+	}
+	return concurrency
+}
+
+// Retries of a capture failing with too many open files, the delay doubles from openFilesBackoff
+const (
+	openFilesRetries = 3
+	openFilesBackoff = time.Second
+)
+
+// isTooManyOpenFiles reports whether err is an EMFILE error, either of this process or reported by Playwright
+func isTooManyOpenFiles(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, syscall.EMFILE) {
+		return true
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "EMFILE") || strings.Contains(strings.ToLower(msg), "too many open files")
+}
@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"syscall"
+	"testing"
+)
+
+func TestValidateWorkers(t *testing.T) {
+	// Table Driven Test
+	tests := []struct {
+		name    string
+		opts    scrapeWorkersOptions
+		wantErr bool
+	}{
+		{name: "default case", opts: scrapeWorkersOptions{Concurrency: 1, Isolation: isolationSharedBrowser}},
+		{name: "concurrent case", opts: scrapeWorkersOptions{Concurrency: 4, Isolation: isolationPerWorkerBrowser}},
+		{name: "single worker trace case", opts: scrapeWorkersOptions{Concurrency: 1, Isolation: isolationSharedBrowser, Trace: "trace.zip"}},
+		{name: "zero concurrency case", opts: scrapeWorkersOptions{Concurrency: 0, Isolation: isolationSharedBrowser}, wantErr: true},
+		{name: "unknown isolation case", opts: scrapeWorkersOptions{Concurrency: 2, Isolation: "process"}, wantErr: true},
+		{name: "concurrent devtools case", opts: scrapeWorkersOptions{Concurrency: 2, Isolation: isolationSharedBrowser, Devtools: true}, wantErr: true},
+		{name: "concurrent storage state case", opts: scrapeWorkersOptions{Concurrency: 2, Isolation: isolationSharedBrowser, SaveStorageState: "state.json"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateWorkers(tt.opts); (err != nil) != tt.wantErr {
+				t.Errorf("%s: validateWorkers(%+v) error = %v; want error %t", tt.name, tt.opts, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMaxWorkersForOpenFiles(t *testing.T) {
+	// Table Driven Test
+	tests := []struct {
+		name         string
+		concurrency  int
+		isolation    string
+		maxOpenFiles uint64
+		want         int
+	}{
+		{name: "unlimited case", concurrency: 8, isolation: isolationSharedBrowser, want: 8},
+		{name: "large limit case", concurrency: 8, isolation: isolationSharedBrowser, maxOpenFiles: 1 << 20, want: 8},
+		{name: "shared browser case", concurrency: 16, isolation: isolationSharedBrowser, maxOpenFiles: 512, want: 6},
+		{name: "per-worker browser case", concurrency: 16, isolation: isolationPerWorkerBrowser, maxOpenFiles: 1024, want: 3},
+		{name: "tiny limit case", concurrency: 4, isolation: isolationPerWorkerBrowser, maxOpenFiles: 100, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := maxWorkersForOpenFiles(tt.concurrency, tt.isolation, tt.maxOpenFiles); got != tt.want {
+				t.Errorf("%s: maxWorkersForOpenFiles(%d, %s, %d) = %d; want %d", tt.name, tt.concurrency, tt.isolation, tt.maxOpenFiles, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsTooManyOpenFiles(t *testing.T) {
+	// Table Driven Test
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil case", err: nil, want: false},
+		{name: "errno case", err: fmt.Errorf("could not create artifacts directory: %w", syscall.EMFILE), want: true},
+		{name: "playwright case", err: errors.New("could not goto: net::ERR_INSUFFICIENT_RESOURCES: spawn EMFILE"), want: true},
+		{name: "message case", err: errors.New("open /tmp/a.png: Too many open files"), want: true},
+		{name: "other error case", err: errors.New("could not goto: net::ERR_NAME_NOT_RESOLVED"), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isTooManyOpenFiles(tt.err); got != tt.want {
+				t.Errorf("%s: isTooManyOpenFiles(%v) = %t; want %t", tt.name, tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+// BenchmarkScrapeRun captures pages of a local server with 1 to 8 workers of each isolation,
+// the speedup of the workers is the ratio of the ns/op. It requires the Playwright driver and Chromium,
+// e.g. `go test ./cmd -run '^$' -bench ScrapeRun -benchtime 3x`
+func BenchmarkScrapeRun(b *testing.B) {
+	if _, err := checkPlaywright(); err != nil {
+		b.Skipf("playwright is not available: %v", err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "<html><body><h1>%s</h1></body></html>", r.URL.Path)
+	}))
+	defer server.Close()
+
+	const pages = 16
+	jobs := make([]scrapeJob, 0, pages)
+	for i := 0; i < pages; i++ {
+		jobs = append(jobs, scrapeJob{URL: fmt.Sprintf("%s/page/%d", server.URL, i)})
+	}
+	for _, isolation := range []string{isolationSharedBrowser, isolationPerWorkerBrowser} {
+		for _, concurrency := range []int{1, 4, 8} {
+			b.Run(fmt.Sprintf("%s/%d", isolation, concurrency), func(b *testing.B) {
+				opts := scrapeOptions{
+					Jobs:        jobs,
+					OutputDir:   b.TempDir(),
+					Headless:    true,
+					Scale:       1,
+					Layout:      layoutFlat,
+					Concurrency: concurrency,
+					Isolation:   isolation,
+					Out:         io.Discard,
+				}
+				for i := 0; i < b.N; i++ {
+					if err := runScrape(context.Background(), opts); err != nil {
+						b.Fatalf("runScrape() error = %v", err)
+					}
+				}
+			})
+		}
+	}
+}
@@ -29,13 +29,26 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// versionInfo is the JSON output of the version command
+type versionInfo struct {
+	Version  string `json:"version"`
+	Revision string `json:"revision"`
+}
+
 // versionCmd represents the version command
 var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Print the version number of misctl",
 	Long:  `Print the version number of misctl`,
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Printf("Hello, world.\nversion=%s, revision=%s\n", internal.Version, internal.Revision)
+		if internal.JSONOutput() {
+			pretty, err := cmd.Flags().GetBool("pretty")
+			assertErrorToNilf("failed to parse `pretty`: %w", err)
+			err = internal.PrintJSON(cmd.OutOrStdout(), versionInfo{Version: internal.Version, Revision: internal.Revision}, pretty)
+			assertErrorToNilf("could not print version: %w", err)
+			return
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Hello, world.\nversion=%s, revision=%s\n", internal.Version, internal.Revision)
 	},
 }
 
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ks6088ts-labs/misctl/internal"
+)
+
+func TestVersionCommand(t *testing.T) {
+	internal.Version = "1.2.3"
+	internal.Revision = "abcdef"
+
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	rootCmd.SetArgs([]string{"version"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("version command returned error: %v", err)
+	}
+
+	want := "version=1.2.3, revision=abcdef"
+	if got := out.String(); !strings.Contains(got, want) {
+		t.Errorf("version command output = %q; want it to contain %q", got, want)
+	}
+}
+
+func TestVersionCommandOutputFile(t *testing.T) {
+	internal.Version = "1.2.3"
+	internal.Revision = "abcdef"
+
+	path := filepath.Join(t.TempDir(), "version.txt")
+	rootCmd.SetArgs([]string{"version", "--output-file", path})
+	t.Cleanup(func() {
+		if err := rootCmd.PersistentFlags().Set("output-file", ""); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("version command returned error: %v", err)
+	}
+	if outputHandle != nil {
+		t.Errorf("output file is still open after the command")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "version=1.2.3, revision=abcdef"
+	if got := string(data); !strings.Contains(got, want) {
+		t.Errorf("output file = %q; want it to contain %q", got, want)
+	}
+}
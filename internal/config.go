@@ -0,0 +1,66 @@
+package internal
+
+import (
+	"encoding/json"
+	"io"
+	"regexp"
+)
+
+// Sources of a configuration setting, from the highest precedence
+const (
+	ConfigSourceFlag    = "flag"
+	ConfigSourceEnv     = "env"
+	ConfigSourceFile    = "file"
+	ConfigSourceDefault = "default"
+)
+
+// secretSettingPattern matches the names of the settings whose value is a secret
+var secretSettingPattern = regexp.MustCompile(`(?i)(password|secret|token|auth[-_]data)$`)
+
+// printConfig and printConfigPretty are set by the root `--print-config` and `--pretty` flags
+var printConfig, printConfigPretty bool
+
+// SetPrintConfig makes the commands print the effective configuration they resolve
+func SetPrintConfig(enabled, pretty bool) {
+	printConfig, printConfigPretty = enabled, pretty
+}
+
+// PrintConfig reports whether the commands should print their effective configuration
+func PrintConfig() bool {
+	return printConfig
+}
+
+// ConfigValue is the value of a setting and the source it was resolved from
+type ConfigValue struct {
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// EffectiveConfig is a section of the resolved configuration, such as the flags of the command
+type EffectiveConfig struct {
+	Section string `json:"section"`
+	// Path is the file the section was read from, if any
+	Path     string                 `json:"path,omitempty"`
+	Settings map[string]ConfigValue `json:"settings"`
+}
+
+// WriteConfig prints the section as a JSON line. The values of the secret settings are masked unless
+// the redaction is disabled, other values go through DefaultRedactor.
+func WriteConfig(out io.Writer, c EffectiveConfig) error {
+	settings := make(map[string]ConfigValue, len(c.Settings))
+	for name, v := range c.Settings {
+		if DefaultRedactor.Enabled() && v.Value != "" && secretSettingPattern.MatchString(name) {
+			v.Value = redactedPlaceholder
+		}
+		v.Value = DefaultRedactor.Redact(v.Value)
+		settings[name] = v
+	}
+	c.Settings = settings
+	// Keep the placeholder readable rather than escaped
+	enc := json.NewEncoder(out)
+	enc.SetEscapeHTML(false)
+	if printConfigPretty {
+		enc.SetIndent("", "  ")
+	}
+	return enc.Encode(c)
+}
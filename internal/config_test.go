@@ -0,0 +1,53 @@
+package internal
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteConfig(t *testing.T) {
+	// Table Driven Test
+	tests := []struct {
+		name     string
+		redacted bool
+		settings map[string]ConfigValue
+		want     string
+	}{
+		{
+			name:     "redacted case",
+			redacted: true,
+			settings: map[string]ConfigValue{
+				"MQTT_PASSWORD": {Value: "hunter22", Source: ConfigSourceEnv},
+				"MQTT_USERNAME": {Value: "user", Source: ConfigSourceFile},
+				"auth-data":     {Value: "", Source: ConfigSourceDefault},
+				"show-secrets":  {Value: "false", Source: ConfigSourceDefault},
+			},
+			want: `{"section":"mqtt","path":".env","settings":{"MQTT_PASSWORD":{"value":"<redacted>","source":"env"},"MQTT_USERNAME":{"value":"user","source":"file"},"auth-data":{"value":"","source":"default"},"show-secrets":{"value":"false","source":"default"}}}` + "\n",
+		},
+		{
+			name:     "show secrets case",
+			redacted: false,
+			settings: map[string]ConfigValue{"MQTT_PASSWORD": {Value: "hunter22", Source: ConfigSourceEnv}},
+			want:     `{"section":"mqtt","path":".env","settings":{"MQTT_PASSWORD":{"value":"hunter22","source":"env"}}}` + "\n",
+		},
+		{
+			name:     "pattern case",
+			redacted: true,
+			settings: map[string]ConfigValue{"MQTT_CONNECTION_STRING": {Value: "HostName=h;SharedAccessKey=abc", Source: ConfigSourceFile}},
+			want:     `{"section":"mqtt","path":".env","settings":{"MQTT_CONNECTION_STRING":{"value":"HostName=h;SharedAccessKey=<redacted>","source":"file"}}}` + "\n",
+		},
+	}
+	defer DefaultRedactor.SetEnabled(DefaultRedactor.Enabled())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			DefaultRedactor.SetEnabled(tt.redacted)
+			var b bytes.Buffer
+			if err := WriteConfig(&b, EffectiveConfig{Section: "mqtt", Path: ".env", Settings: tt.settings}); err != nil {
+				t.Fatalf("%s: WriteConfig returned error: %v", tt.name, err)
+			}
+			if b.String() != tt.want {
+				t.Errorf("%s: WriteConfig() = %s; want %s", tt.name, b.String(), tt.want)
+			}
+		})
+	}
+}
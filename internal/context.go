@@ -0,0 +1,14 @@
+package internal
+
+import (
+	"context"
+	"errors"
+)
+
+// DoneReason describes why a command context is done, for the messages printed on exit
+func DoneReason(ctx context.Context) string {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return "max runtime reached"
+	}
+	return "signal caught"
+}
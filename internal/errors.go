@@ -0,0 +1,126 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+)
+
+// ErrorCode classifies a failure so that scripts can tell failure modes apart
+type ErrorCode string
+
+const (
+	CodeRuntime    ErrorCode = "runtime"
+	CodeUsage      ErrorCode = "usage"
+	CodeConfig     ErrorCode = "config"
+	CodeConnection ErrorCode = "connection"
+	CodeTimeout    ErrorCode = "timeout"
+)
+
+// exitCodes maps each error code to the exit code of the process
+var exitCodes = map[ErrorCode]int{
+	CodeRuntime:    1,
+	CodeUsage:      2,
+	CodeConfig:     3,
+	CodeConnection: 4,
+	CodeTimeout:    5,
+}
+
+// Error is an error with a code
+type Error struct {
+	Code    ErrorCode
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// ExitCode returns the exit code matching the error code
+func (e *Error) ExitCode() int {
+	if code, ok := exitCodes[e.Code]; ok {
+		return code
+	}
+	return 1
+}
+
+// Errorf formats an error with the given code, %w is supported
+func Errorf(code ErrorCode, format string, args ...any) *Error {
+	return &Error{Code: code, Message: fmt.Errorf(format, args...).Error()}
+}
+
+// asError returns err as an *Error with the code of the first coded error it wraps,
+// errors without a code are runtime errors
+func asError(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return &Error{Code: e.Code, Message: err.Error()}
+	}
+	return &Error{Code: CodeRuntime, Message: err.Error()}
+}
+
+// ExitCode returns the exit code matching the code of err
+func ExitCode(err error) int {
+	return asError(err).ExitCode()
+}
+
+// Error formats, set with `--error-format`
+const (
+	ErrorFormatText = "text"
+	ErrorFormatJSON = "json"
+)
+
+var errorFormat = ErrorFormatText
+
+// SetErrorFormat selects how Fatal prints errors
+func SetErrorFormat(format string) error {
+	switch format {
+	case ErrorFormatText, ErrorFormatJSON:
+		errorFormat = format
+		return nil
+	}
+	return fmt.Errorf("unknown error format %q, must be %s or %s", format, ErrorFormatText, ErrorFormatJSON)
+}
+
+// FormatErrorJSON returns the JSON representation of err
+func FormatErrorJSON(err error) []byte {
+	e := asError(err)
+	data, _ := json.Marshal(map[string]any{
+		"error": map[string]any{
+			"code":      e.Code,
+			"exit_code": e.ExitCode(),
+			"message":   e.Message,
+		},
+	})
+	return data
+}
+
+// exitHooks run before Exit terminates the process
+var exitHooks []func()
+
+// OnExit registers f to run before Exit terminates the process, e.g. to close an output file
+func OnExit(f func()) {
+	exitHooks = append(exitHooks, f)
+}
+
+// Exit prints err to stderr in the selected format and exits with its exit code
+func Exit(err error) {
+	e := asError(err)
+	e = &Error{Code: e.Code, Message: DefaultRedactor.Redact(e.Message)}
+	if errorFormat == ErrorFormatJSON {
+		fmt.Fprintf(os.Stderr, "%s\n", FormatErrorJSON(e))
+	} else {
+		log.Print(e.Message)
+	}
+	for _, hook := range exitHooks {
+		hook()
+	}
+	os.Exit(e.ExitCode())
+}
+
+// Fatalf is the coded equivalent of log.Fatalf
+func Fatalf(code ErrorCode, format string, args ...any) {
+	Exit(Errorf(code, format, args...))
+}
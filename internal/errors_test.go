@@ -0,0 +1,68 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestFormatErrorJSON(t *testing.T) {
+	// Table Driven Test
+	tests := []struct {
+		name         string
+		err          error
+		want         string
+		wantExitCode int
+	}{
+		{
+			name:         "usage case",
+			err:          Errorf(CodeUsage, "invalid `count` %d", 0),
+			want:         `{"error":{"code":"usage","exit_code":2,"message":"invalid ` + "`count`" + ` 0"}}`,
+			wantExitCode: 2,
+		},
+		{
+			name:         "wrapped case",
+			err:          fmt.Errorf("scrape: %w", Errorf(CodeConnection, "could not dial: %w", errors.New("refused"))),
+			want:         `{"error":{"code":"connection","exit_code":4,"message":"scrape: could not dial: refused"}}`,
+			wantExitCode: 4,
+		},
+		{
+			name:         "plain error case",
+			err:          errors.New("boom"),
+			want:         `{"error":{"code":"runtime","exit_code":1,"message":"boom"}}`,
+			wantExitCode: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(FormatErrorJSON(tt.err)); got != tt.want {
+				t.Errorf("%s: FormatErrorJSON() = %s; want %s", tt.name, got, tt.want)
+			}
+			if got := asError(tt.err).ExitCode(); got != tt.wantExitCode {
+				t.Errorf("%s: ExitCode() = %d; want %d", tt.name, got, tt.wantExitCode)
+			}
+		})
+	}
+}
+
+func TestSetErrorFormat(t *testing.T) {
+	defer SetErrorFormat(ErrorFormatText)
+
+	// Table Driven Test
+	tests := []struct {
+		name    string
+		format  string
+		wantErr bool
+	}{
+		{name: "text case", format: "text"},
+		{name: "json case", format: "json"},
+		{name: "unknown format case", format: "yaml", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := SetErrorFormat(tt.format); (err != nil) != tt.wantErr {
+				t.Errorf("%s: SetErrorFormat(%s) = %v; wantErr %t", tt.name, tt.format, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// MarshalJSON encodes v as compact JSON, or indented JSON when pretty is set
+func MarshalJSON(v any, pretty bool) ([]byte, error) {
+	if pretty {
+		return json.MarshalIndent(v, "", "  ")
+	}
+	return json.Marshal(v)
+}
+
+// jsonOutput is set by the root `--json` flag
+var jsonOutput bool
+
+// SetJSONOutput makes the commands emit machine readable JSON instead of text
+func SetJSONOutput(enabled bool) {
+	jsonOutput = enabled
+}
+
+// JSONOutput reports whether the commands should emit JSON
+func JSONOutput() bool {
+	return jsonOutput
+}
+
+// Decorative returns out for human readable progress text, which is discarded in JSON output mode
+func Decorative(out io.Writer) io.Writer {
+	if jsonOutput {
+		return io.Discard
+	}
+	return out
+}
+
+// PrintJSON writes v as a JSON line to out
+func PrintJSON(out io.Writer, v any, pretty bool) error {
+	data, err := MarshalJSON(v, pretty)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(out, string(data))
+	return err
+}
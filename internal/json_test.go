@@ -0,0 +1,56 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestMarshalJSON(t *testing.T) {
+	// Table Driven Test
+	tests := []struct {
+		name   string
+		v      any
+		pretty bool
+		want   string
+	}{
+		{name: "compact case", v: map[string]int{"a": 1}, pretty: false, want: `{"a":1}`},
+		{name: "pretty case", v: map[string]int{"a": 1}, pretty: true, want: "{\n  \"a\": 1\n}"},
+		{name: "empty slice case", v: []string{}, pretty: true, want: `[]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MarshalJSON(tt.v, tt.pretty)
+			if err != nil {
+				t.Fatalf("%s: MarshalJSON returned error: %v", tt.name, err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("%s: MarshalJSON(%v, %t) = %q; want %q", tt.name, tt.v, tt.pretty, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecorative(t *testing.T) {
+	defer SetJSONOutput(false)
+
+	// Table Driven Test
+	tests := []struct {
+		name       string
+		jsonOutput bool
+		want       string
+	}{
+		{name: "text case", jsonOutput: false, want: "Connecting\n"},
+		{name: "json case", jsonOutput: true, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetJSONOutput(tt.jsonOutput)
+			var buf bytes.Buffer
+			fmt.Fprintln(Decorative(&buf), "Connecting")
+			if buf.String() != tt.want {
+				t.Errorf("%s: Decorative output = %q; want %q", tt.name, buf.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package internal
+
+import (
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// redactedPlaceholder replaces every redacted value
+const redactedPlaceholder = "<redacted>"
+
+// minSecretLength avoids masking every occurrence of a trivially short value
+const minSecretLength = 4
+
+// secretPatterns match secrets whose value is not known in advance
+var secretPatterns = []struct {
+	re          *regexp.Regexp
+	replacement string
+}{
+	// SAS token signatures and the keys they are signed with
+	{regexp.MustCompile(`(sig=)[^&\s;"']+`), "${1}" + redactedPlaceholder},
+	{regexp.MustCompile(`(SharedAccessKey=)[^;\s"']+`), "${1}" + redactedPlaceholder},
+	// PEM encoded private keys
+	{regexp.MustCompile(`-----BEGIN ([A-Z ]*)PRIVATE KEY-----[\s\S]*?-----END ([A-Z ]*)PRIVATE KEY-----`), redactedPlaceholder},
+}
+
+// Redactor masks secrets in the output, such as passwords, SAS tokens and private keys
+type Redactor struct {
+	mu       sync.RWMutex
+	secrets  []string
+	disabled bool
+}
+
+// DefaultRedactor is applied to the command output, the logs and the errors
+var DefaultRedactor = &Redactor{}
+
+// Add registers secret values to mask, values shorter than 4 characters are ignored
+func (r *Redactor) Add(secrets ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, s := range secrets {
+		if len(s) >= minSecretLength {
+			r.secrets = append(r.secrets, s)
+		}
+	}
+}
+
+// SetEnabled turns the redaction on or off
+func (r *Redactor) SetEnabled(enabled bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.disabled = !enabled
+}
+
+// Enabled reports whether secrets are redacted
+func (r *Redactor) Enabled() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return !r.disabled
+}
+
+// Redact returns s with the registered secrets and the known secret patterns masked
+func (r *Redactor) Redact(s string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.disabled {
+		return s
+	}
+	for _, secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, redactedPlaceholder)
+	}
+	for _, p := range secretPatterns {
+		s = p.re.ReplaceAllString(s, p.replacement)
+	}
+	return s
+}
+
+// Writer returns a writer redacting every write to w. Secrets split across writes are not detected.
+func (r *Redactor) Writer(w io.Writer) io.Writer {
+	return &redactingWriter{r: r, w: w}
+}
+
+type redactingWriter struct {
+	r *Redactor
+	w io.Writer
+}
+
+func (w *redactingWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(w.w, w.r.Redact(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
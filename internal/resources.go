@@ -0,0 +1,112 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// ResourceStats summarizes the resources used while a ResourceMonitor was running.
+// CPU times and the max RSS are zero on platforms where they cannot be read.
+type ResourceStats struct {
+	Elapsed       time.Duration
+	PeakHeapBytes uint64
+	PeakSysBytes  uint64
+	// MaxRSSBytes is the peak resident set size of the process
+	MaxRSSBytes uint64
+	UserCPU     time.Duration
+	SystemCPU   time.Duration
+	// ChildUserCPU and ChildSystemCPU cover the child processes which exited, such as the browser driver
+	ChildUserCPU   time.Duration
+	ChildSystemCPU time.Duration
+}
+
+// ResourceMonitor samples the memory of the process until stopped
+type ResourceMonitor struct {
+	start    time.Time
+	startCPU cpuTimes
+	stop     chan struct{}
+	done     chan struct{}
+	mu       sync.Mutex
+	peakHeap uint64
+	peakSys  uint64
+}
+
+// StartResourceMonitor samples runtime.MemStats every interval until Stop is called
+func StartResourceMonitor(interval time.Duration) *ResourceMonitor {
+	m := &ResourceMonitor{
+		start:    time.Now(),
+		startCPU: readCPUTimes(),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	m.sample()
+	go func() {
+		defer close(m.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				m.sample()
+			case <-m.stop:
+				return
+			}
+		}
+	}()
+	return m
+}
+
+func (m *ResourceMonitor) sample() {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.peakHeap = max(m.peakHeap, ms.HeapAlloc)
+	m.peakSys = max(m.peakSys, ms.Sys)
+}
+
+// Stop stops the sampling and returns the stats of the monitored period
+func (m *ResourceMonitor) Stop() ResourceStats {
+	close(m.stop)
+	<-m.done
+	m.sample()
+
+	cpu := readCPUTimes()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return ResourceStats{
+		Elapsed:        time.Since(m.start),
+		PeakHeapBytes:  m.peakHeap,
+		PeakSysBytes:   m.peakSys,
+		MaxRSSBytes:    cpu.maxRSS,
+		UserCPU:        cpu.user - m.startCPU.user,
+		SystemCPU:      cpu.system - m.startCPU.system,
+		ChildUserCPU:   cpu.childUser - m.startCPU.childUser,
+		ChildSystemCPU: cpu.childSystem - m.startCPU.childSystem,
+	}
+}
+
+// Print writes a human readable summary of the stats
+func (s ResourceStats) Print(out io.Writer) {
+	fmt.Fprintf(out, "Elapsed: %s\n", s.Elapsed.Round(time.Millisecond))
+	fmt.Fprintf(out, "Peak memory: heap %s, from OS %s, max RSS %s\n", formatBytes(s.PeakHeapBytes), formatBytes(s.PeakSysBytes), formatBytes(s.MaxRSSBytes))
+	fmt.Fprintf(out, "CPU time: user %s, system %s\n", s.UserCPU.Round(time.Millisecond), s.SystemCPU.Round(time.Millisecond))
+	fmt.Fprintf(out, "Child CPU time: user %s, system %s\n", s.ChildUserCPU.Round(time.Millisecond), s.ChildSystemCPU.Round(time.Millisecond))
+}
+
+// formatBytes formats a byte count with a binary unit
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,22 @@
+//go:build !unix
+
+package internal
+
+import "time"
+
+// cpuTimes holds the CPU times of the process and of its waited-for children
+type cpuTimes struct {
+	user, system           time.Duration
+	childUser, childSystem time.Duration
+	maxRSS                 uint64
+}
+
+// readCPUTimes is not supported on this platform, the CPU times are reported as zero
+func readCPUTimes() cpuTimes {
+	return cpuTimes{}
+}
+
+// OpenFilesLimit is not supported on this platform, no limit is reported
+func OpenFilesLimit() (uint64, bool) {
+	return 0, false
+}
@@ -0,0 +1,47 @@
+package internal
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatBytes(t *testing.T) {
+	// Table Driven Test
+	tests := []struct {
+		name string
+		n    uint64
+		want string
+	}{
+		{name: "bytes case", n: 512, want: "512 B"},
+		{name: "kibibytes case", n: 1536, want: "1.5 KiB"},
+		{name: "mebibytes case", n: 3 << 20, want: "3.0 MiB"},
+		{name: "gibibytes case", n: 5 << 30, want: "5.0 GiB"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatBytes(tt.n); got != tt.want {
+				t.Errorf("%s: formatBytes(%d) = %s; want %s", tt.name, tt.n, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResourceMonitor(t *testing.T) {
+	m := StartResourceMonitor(time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	stats := m.Stop()
+	if stats.PeakHeapBytes == 0 || stats.PeakSysBytes < stats.PeakHeapBytes {
+		t.Errorf("ResourceMonitor.Stop() = %+v; want a non-zero heap below the OS memory", stats)
+	}
+	if stats.Elapsed < 5*time.Millisecond {
+		t.Errorf("ResourceMonitor.Stop().Elapsed = %s; want at least 5ms", stats.Elapsed)
+	}
+
+	var out bytes.Buffer
+	stats.Print(&out)
+	if !strings.Contains(out.String(), "Peak memory: heap ") {
+		t.Errorf("ResourceStats.Print() = %q; want the peak memory", out.String())
+	}
+}
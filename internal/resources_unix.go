@@ -0,0 +1,61 @@
+//go:build unix
+
+package internal
+
+import (
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// cpuTimes holds the CPU times of the process and of its waited-for children
+type cpuTimes struct {
+	user, system           time.Duration
+	childUser, childSystem time.Duration
+	maxRSS                 uint64
+}
+
+func readCPUTimes() cpuTimes {
+	var self, children syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &self); err != nil {
+		return cpuTimes{}
+	}
+	if err := syscall.Getrusage(syscall.RUSAGE_CHILDREN, &children); err != nil {
+		return cpuTimes{}
+	}
+	// Maxrss is in bytes on darwin and in kilobytes elsewhere
+	maxRSS := uint64(self.Maxrss)
+	if runtime.GOOS != "darwin" {
+		maxRSS *= 1024
+	}
+	return cpuTimes{
+		user:        time.Duration(self.Utime.Nano()),
+		system:      time.Duration(self.Stime.Nano()),
+		childUser:   time.Duration(children.Utime.Nano()),
+		childSystem: time.Duration(children.Stime.Nano()),
+		maxRSS:      maxRSS,
+	}
+}
+
+// OpenFilesLimit returns the soft limit of open files of the processes this one starts, such as the browser driver.
+// Since Go 1.21 the runtime raises its own soft limit to the hard limit at startup and os/exec restores the
+// original one in the child processes, so getrlimit would overstate it: the limit is read from a child shell.
+func OpenFilesLimit() (uint64, bool) {
+	out, err := exec.Command("sh", "-c", "ulimit -Sn").Output()
+	if err != nil {
+		return 0, false
+	}
+	return parseOpenFilesLimit(string(out))
+}
+
+// parseOpenFilesLimit parses the output of `ulimit -Sn`, "unlimited" reports no limit
+func parseOpenFilesLimit(s string) (uint64, bool) {
+	limit, err := strconv.ParseUint(strings.TrimSpace(s), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return limit, true
+}
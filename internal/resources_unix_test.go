@@ -0,0 +1,45 @@
+//go:build unix
+
+package internal
+
+import (
+	"syscall"
+	"testing"
+)
+
+func TestParseOpenFilesLimit(t *testing.T) {
+	// Table Driven Test
+	tests := []struct {
+		name   string
+		input  string
+		want   uint64
+		wantOk bool
+	}{
+		{name: "limit case", input: "1024\n", want: 1024, wantOk: true},
+		{name: "unlimited case", input: "unlimited\n", want: 0, wantOk: false},
+		{name: "empty case", input: "", want: 0, wantOk: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseOpenFilesLimit(tt.input)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("%s: parseOpenFilesLimit(%q) = %d, %t; want %d, %t", tt.name, tt.input, got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestOpenFilesLimit(t *testing.T) {
+	limit, ok := OpenFilesLimit()
+	if !ok {
+		t.Skip("the open files limit of the child processes is not available")
+	}
+	// The runtime raised the limit of this process, the children get at most that one
+	var self syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &self); err != nil {
+		t.Fatal(err)
+	}
+	if limit > self.Cur {
+		t.Errorf("OpenFilesLimit() = %d; want at most the limit of this process %d", limit, self.Cur)
+	}
+}
@@ -0,0 +1,187 @@
+package internal
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// TLSOptions holds the options used to build a TLS configuration
+type TLSOptions struct {
+	// CertFile and KeyFile are the PEM certificate and private key presented to the peer
+	CertFile        string
+	KeyFile         string
+	KeyFilePassword string
+	// CAFile is a PEM bundle used instead of the system roots to verify the peer
+	CAFile             string
+	InsecureSkipVerify bool
+	// MinVersion is the minimum TLS version, 0 keeps the crypto/tls default
+	MinVersion uint16
+	// AllowLegacyVersions permits a MinVersion below TLS 1.2
+	AllowLegacyVersions bool
+	// CipherSuites are IANA names of the TLS 1.0-1.2 cipher suites to enable, empty keeps the crypto/tls default
+	CipherSuites []string
+	// AllowInsecureCiphers permits cipher suites that crypto/tls considers insecure
+	AllowInsecureCiphers bool
+	// RequireOCSPStapling fails the handshake unless the peer staples a good OCSP response
+	RequireOCSPStapling bool
+}
+
+// tlsVersions maps the version names accepted on the command line to their crypto/tls value
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// ParseTLSVersion parses a TLS version such as 1.2
+func ParseTLSVersion(s string) (uint16, error) {
+	v, ok := tlsVersions[s]
+	if !ok {
+		return 0, fmt.Errorf("unknown TLS version %q, must be one of 1.0, 1.1, 1.2 or 1.3", s)
+	}
+	return v, nil
+}
+
+// cipherSuiteIDs resolves cipher suite names.
+// Insecure suites are rejected unless allowInsecure is set, in which case a warning lists them.
+func cipherSuiteIDs(names []string, allowInsecure bool) ([]uint16, error) {
+	secure := map[string]uint16{}
+	for _, s := range tls.CipherSuites() {
+		secure[s.Name] = s.ID
+	}
+	insecure := map[string]uint16{}
+	for _, s := range tls.InsecureCipherSuites() {
+		insecure[s.Name] = s.ID
+	}
+
+	ids := make([]uint16, 0, len(names))
+	var weak []string
+	for _, name := range names {
+		if id, ok := secure[name]; ok {
+			ids = append(ids, id)
+			continue
+		}
+		id, ok := insecure[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown cipher suite %q", name)
+		}
+		weak = append(weak, name)
+		ids = append(ids, id)
+	}
+
+	if len(weak) > 0 {
+		if !allowInsecure {
+			return nil, fmt.Errorf("insecure cipher suite(s) requested without explicit opt-in: %s", strings.Join(weak, ", "))
+		}
+		log.Printf("warning: using insecure cipher suite(s): %s", strings.Join(weak, ", "))
+	}
+	return ids, nil
+}
+
+// PrintTLSConnectionState prints the negotiated version and cipher suite and the peer certificate chain
+func PrintTLSConnectionState(out io.Writer, state tls.ConnectionState) {
+	fmt.Fprintf(out, "TLS version: %s\n", tls.VersionName(state.Version))
+	fmt.Fprintf(out, "Cipher suite: %s\n", tls.CipherSuiteName(state.CipherSuite))
+	for i, c := range state.PeerCertificates {
+		fmt.Fprintf(out, "Certificate #%d\n", i)
+		fmt.Fprintf(out, "  Subject: %s\n", c.Subject)
+		fmt.Fprintf(out, "  Issuer: %s\n", c.Issuer)
+		if len(c.DNSNames) > 0 {
+			fmt.Fprintf(out, "  DNS names: %s\n", strings.Join(c.DNSNames, ", "))
+		}
+		fmt.Fprintf(out, "  Not before: %s\n", c.NotBefore)
+		fmt.Fprintf(out, "  Not after: %s\n", c.NotAfter)
+	}
+}
+
+// verifyOCSPStaple checks the OCSP response stapled by the peer for its leaf certificate.
+// It fails when no response is stapled, when the response is invalid, or when the certificate is not good.
+func verifyOCSPStaple(state tls.ConnectionState) error {
+	if len(state.OCSPResponse) == 0 {
+		return errors.New("no OCSP response stapled by the peer")
+	}
+	// Prefer the verified chain, the peer chain is all there is with InsecureSkipVerify
+	chain := state.PeerCertificates
+	if len(state.VerifiedChains) > 0 {
+		chain = state.VerifiedChains[0]
+	}
+	if len(chain) < 2 {
+		return errors.New("could not check the stapled OCSP response: the issuer certificate is missing")
+	}
+	resp, err := ocsp.ParseResponseForCert(state.OCSPResponse, chain[0], chain[1])
+	if err != nil {
+		return fmt.Errorf("invalid stapled OCSP response: %w", err)
+	}
+	if !resp.NextUpdate.IsZero() && time.Now().After(resp.NextUpdate) {
+		return fmt.Errorf("stapled OCSP response expired at %s", resp.NextUpdate.Format(time.RFC3339))
+	}
+	switch resp.Status {
+	case ocsp.Good:
+		return nil
+	case ocsp.Revoked:
+		return fmt.Errorf("certificate %s revoked at %s according to the stapled OCSP response", chain[0].Subject, resp.RevokedAt.Format(time.RFC3339))
+	default:
+		return fmt.Errorf("certificate %s has an unknown status in the stapled OCSP response", chain[0].Subject)
+	}
+}
+
+// BuildTLSConfig returns a TLS configuration built from the options.
+// It is shared by the iot and http commands so that certificate handling lives in one place.
+func BuildTLSConfig(opts TLSOptions) (*tls.Config, error) {
+	if opts.MinVersion != 0 && opts.MinVersion < tls.VersionTLS12 && !opts.AllowLegacyVersions {
+		return nil, fmt.Errorf("minimum version %s is deprecated and requires explicit opt-in", tls.VersionName(opts.MinVersion))
+	}
+
+	cfg := &tls.Config{
+		InsecureSkipVerify: opts.InsecureSkipVerify,
+		MinVersion:         opts.MinVersion,
+	}
+
+	if len(opts.CipherSuites) > 0 {
+		ids, err := cipherSuiteIDs(opts.CipherSuites, opts.AllowInsecureCiphers)
+		if err != nil {
+			return nil, err
+		}
+		cfg.CipherSuites = ids
+	}
+
+	if opts.RequireOCSPStapling {
+		cfg.VerifyConnection = verifyOCSPStaple
+	}
+
+	if opts.CertFile != "" && opts.KeyFile != "" {
+		if opts.KeyFilePassword != "" {
+			return nil, errors.New("password protected key files are not supported at this time")
+		}
+
+		cert, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not load key pair: %w", err)
+		}
+
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	if opts.CAFile != "" {
+		ca, err := os.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read CA file: %w", err)
+		}
+
+		caCertPool := x509.NewCertPool()
+		caCertPool.AppendCertsFromPEM(ca)
+		cfg.RootCAs = caCertPool
+	}
+
+	return cfg, nil
+}
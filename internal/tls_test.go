@@ -0,0 +1,223 @@
+package internal
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// writeTestKeyPair writes a self-signed certificate and its key to dir and returns their paths
+func writeTestKeyPair(t *testing.T, dir string) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("could not create certificate: %v", err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("could not marshal key: %v", err)
+	}
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatalf("could not write certificate: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600); err != nil {
+		t.Fatalf("could not write key: %v", err)
+	}
+	return certFile, keyFile
+}
+
+func TestBuildTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeTestKeyPair(t, dir)
+
+	// Table Driven Test
+	tests := []struct {
+		name             string
+		opts             TLSOptions
+		wantErr          bool
+		wantCertificates int
+		wantRootCAs      bool
+	}{
+		{name: "empty case", opts: TLSOptions{}},
+		{name: "key pair case", opts: TLSOptions{CertFile: certFile, KeyFile: keyFile}, wantCertificates: 1},
+		{name: "ca case", opts: TLSOptions{CAFile: certFile}, wantRootCAs: true},
+		{name: "min version case", opts: TLSOptions{MinVersion: tls.VersionTLS13}},
+		{name: "legacy min version case", opts: TLSOptions{MinVersion: tls.VersionTLS10}, wantErr: true},
+		{name: "allowed legacy min version case", opts: TLSOptions{MinVersion: tls.VersionTLS11, AllowLegacyVersions: true}},
+		{name: "key password case", opts: TLSOptions{CertFile: certFile, KeyFile: keyFile, KeyFilePassword: "secret"}, wantErr: true},
+		{name: "mismatched key pair case", opts: TLSOptions{CertFile: certFile, KeyFile: certFile}, wantErr: true},
+		{name: "missing ca case", opts: TLSOptions{CAFile: filepath.Join(dir, "missing.pem")}, wantErr: true},
+		{name: "unknown cipher case", opts: TLSOptions{CipherSuites: []string{"TLS_UNKNOWN"}}, wantErr: true},
+		{name: "insecure cipher case", opts: TLSOptions{CipherSuites: []string{"TLS_RSA_WITH_RC4_128_SHA"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := BuildTLSConfig(tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("%s: BuildTLSConfig error = %v; wantErr %t", tt.name, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got.Certificates) != tt.wantCertificates {
+				t.Errorf("%s: len(Certificates) = %d; want %d", tt.name, len(got.Certificates), tt.wantCertificates)
+			}
+			if (got.RootCAs != nil) != tt.wantRootCAs {
+				t.Errorf("%s: RootCAs set = %t; want %t", tt.name, got.RootCAs != nil, tt.wantRootCAs)
+			}
+			if got.MinVersion != tt.opts.MinVersion {
+				t.Errorf("%s: MinVersion = %d; want %d", tt.name, got.MinVersion, tt.opts.MinVersion)
+			}
+		})
+	}
+}
+
+func TestParseTLSVersion(t *testing.T) {
+	// Table Driven Test
+	tests := []struct {
+		name    string
+		input   string
+		want    uint16
+		wantErr bool
+	}{
+		{name: "TLS 1.0 case", input: "1.0", want: tls.VersionTLS10},
+		{name: "TLS 1.2 case", input: "1.2", want: tls.VersionTLS12},
+		{name: "TLS 1.3 case", input: "1.3", want: tls.VersionTLS13},
+		{name: "prefixed case", input: "TLS1.2", wantErr: true},
+		{name: "empty case", input: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTLSVersion(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%s: ParseTLSVersion(%q) error = %v; wantErr %t", tt.name, tt.input, err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("%s: ParseTLSVersion(%q) = %d; want %d", tt.name, tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCipherSuiteIDs(t *testing.T) {
+	// Table Driven Test
+	tests := []struct {
+		name          string
+		names         []string
+		allowInsecure bool
+		want          []uint16
+		wantErr       bool
+	}{
+		{name: "secure case", names: []string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"}, want: []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256}},
+		{name: "insecure rejected case", names: []string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256", "TLS_RSA_WITH_RC4_128_SHA"}, wantErr: true},
+		{name: "insecure allowed case", names: []string{"TLS_RSA_WITH_RC4_128_SHA"}, allowInsecure: true, want: []uint16{tls.TLS_RSA_WITH_RC4_128_SHA}},
+		{name: "unknown case", names: []string{"TLS_UNKNOWN"}, allowInsecure: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := cipherSuiteIDs(tt.names, tt.allowInsecure)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%s: cipherSuiteIDs(%v, %t) error = %v; wantErr %t", tt.name, tt.names, tt.allowInsecure, err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s: cipherSuiteIDs(%v, %t) = %v; want %v", tt.name, tt.names, tt.allowInsecure, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyOCSPStaple(t *testing.T) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate key: %v", err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDer, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("could not create CA certificate: %v", err)
+	}
+	ca, _ := x509.ParseCertificate(caDer)
+	leafTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "broker"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	leafDer, err := x509.CreateCertificate(rand.Reader, leafTemplate, ca, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("could not create certificate: %v", err)
+	}
+	leaf, _ := x509.ParseCertificate(leafDer)
+	response := func(status int, nextUpdate time.Time) []byte {
+		der, err := ocsp.CreateResponse(ca, ca, ocsp.Response{
+			Status:       status,
+			SerialNumber: leaf.SerialNumber,
+			ThisUpdate:   time.Now().Add(-time.Minute),
+			NextUpdate:   nextUpdate,
+			RevokedAt:    time.Now().Add(-time.Minute),
+		}, caKey)
+		if err != nil {
+			t.Fatalf("could not create OCSP response: %v", err)
+		}
+		return der
+	}
+
+	// Table Driven Test
+	tests := []struct {
+		name    string
+		state   tls.ConnectionState
+		wantErr bool
+	}{
+		{name: "good case", state: tls.ConnectionState{PeerCertificates: []*x509.Certificate{leaf, ca}, OCSPResponse: response(ocsp.Good, time.Now().Add(time.Hour))}},
+		{name: "verified chain case", state: tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{leaf, ca}}, OCSPResponse: response(ocsp.Good, time.Time{})}},
+		{name: "revoked case", state: tls.ConnectionState{PeerCertificates: []*x509.Certificate{leaf, ca}, OCSPResponse: response(ocsp.Revoked, time.Now().Add(time.Hour))}, wantErr: true},
+		{name: "unknown case", state: tls.ConnectionState{PeerCertificates: []*x509.Certificate{leaf, ca}, OCSPResponse: response(ocsp.Unknown, time.Now().Add(time.Hour))}, wantErr: true},
+		{name: "expired case", state: tls.ConnectionState{PeerCertificates: []*x509.Certificate{leaf, ca}, OCSPResponse: response(ocsp.Good, time.Now().Add(-time.Second))}, wantErr: true},
+		{name: "not stapled case", state: tls.ConnectionState{PeerCertificates: []*x509.Certificate{leaf, ca}}, wantErr: true},
+		{name: "missing issuer case", state: tls.ConnectionState{PeerCertificates: []*x509.Certificate{leaf}, OCSPResponse: response(ocsp.Good, time.Now().Add(time.Hour))}, wantErr: true},
+		{name: "garbage case", state: tls.ConnectionState{PeerCertificates: []*x509.Certificate{leaf, ca}, OCSPResponse: []byte("garbage")}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := verifyOCSPStaple(tt.state); (err != nil) != tt.wantErr {
+				t.Errorf("%s: verifyOCSPStaple() error = %v; want error %t", tt.name, err, tt.wantErr)
+			}
+		})
+	}
+}
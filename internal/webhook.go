@@ -0,0 +1,56 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// WebhookRetryDelay is the delay before the first retry, doubled on every attempt
+var WebhookRetryDelay = time.Second
+
+// PostJSON posts v as JSON to url, retrying up to retries times on transport errors and non-2xx responses
+func PostJSON(ctx context.Context, client *http.Client, url string, v any, retries int) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("could not marshal webhook payload: %w", err)
+	}
+
+	delay := WebhookRetryDelay
+	for attempt := 0; ; attempt++ {
+		err = postOnce(ctx, client, url, body)
+		if err == nil || attempt >= retries {
+			break
+		}
+		select {
+		case <-time.After(delay):
+			delay *= 2
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("could not post to webhook after %d attempt(s): %w", retries+1, err)
+	}
+	return nil
+}
+
+func postOnce(ctx context.Context, client *http.Client, url string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPostJSON(t *testing.T) {
+	WebhookRetryDelay = time.Millisecond
+
+	// Table Driven Test
+	tests := []struct {
+		name         string
+		failures     int
+		retries      int
+		wantErr      bool
+		wantAttempts int
+	}{
+		{name: "success case", failures: 0, retries: 2, wantAttempts: 1},
+		{name: "retry case", failures: 2, retries: 2, wantAttempts: 3},
+		{name: "exhausted case", failures: 3, retries: 2, wantErr: true, wantAttempts: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempts++
+				var got map[string]string
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil || got["key"] != "value" {
+					t.Errorf("%s: unexpected body %v (%v)", tt.name, got, err)
+				}
+				if attempts <= tt.failures {
+					w.WriteHeader(http.StatusInternalServerError)
+				}
+			}))
+			err := PostJSON(context.Background(), server.Client(), server.URL, map[string]string{"key": "value"}, tt.retries)
+			server.Close()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%s: PostJSON error = %v; wantErr %t", tt.name, err, tt.wantErr)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("%s: attempts = %d; want %d", tt.name, attempts, tt.wantAttempts)
+			}
+		})
+	}
+}